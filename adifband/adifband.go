@@ -0,0 +1,38 @@
+// adifband: ADIF band enumeration
+// by Kenji Rikitake, JJ1BDX
+//
+// The bands are listed in the frequency order
+// as the Band enumeration of the ADIF specification.
+// Band names are in lowercase.
+
+package adifband
+
+import (
+	"strings"
+)
+
+// ADIF bands in the frequency order
+var Bands = []string{
+	"2190m", "630m", "560m", "160m", "80m", "60m", "40m", "30m",
+	"20m", "17m", "15m", "12m", "10m", "8m", "6m", "5m", "4m", "2m",
+	"1.25m", "70cm", "33cm", "23cm", "13cm", "9cm", "6cm", "3cm",
+	"1.25cm", "6mm", "4mm", "2.5mm", "2mm", "1mm", "submm",
+}
+
+var bandIndex = func() map[string]int {
+	m := make(map[string]int)
+	for i, band := range Bands {
+		m[band] = i
+	}
+	return m
+}()
+
+// Obtain the index of the band in Bands (case insensitive)
+// Returns -1 if the band is not an ADIF band
+func Index(band string) int {
+	i, exists := bandIndex[strings.ToLower(band)]
+	if !exists {
+		return -1
+	}
+	return i
+}
@@ -0,0 +1,44 @@
+package adifband
+
+import (
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	tests := []struct {
+		band string
+		want int
+	}{
+		{"2190m", 0},
+		{"160m", 3},
+		{"20M", 8},
+		{"8m", 13},
+		{"6m", 14},
+		{"70cm", 19},
+		{"submm", len(Bands) - 1},
+		{"", -1},
+		{"11m", -1},
+		{"20", -1},
+	}
+	for _, tt := range tests {
+		if got := Index(tt.band); got != tt.want {
+			t.Errorf("Index(%q) = %d, want %d", tt.band, got, tt.want)
+		}
+	}
+}
+
+func TestBandsOrder(t *testing.T) {
+	seen := make(map[string]bool)
+	for i, band := range Bands {
+		if seen[band] {
+			t.Errorf("duplicate band %s", band)
+		}
+		seen[band] = true
+		if Index(band) != i {
+			t.Errorf("Index(%q) = %d, want %d", band, Index(band), i)
+		}
+	}
+	if len(Bands) != 33 {
+		t.Errorf("len(Bands) = %d, want 33", len(Bands))
+	}
+}
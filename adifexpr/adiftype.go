@@ -0,0 +1,93 @@
+// adifexpr: ADIF data types of fields for typed comparison
+// by Kenji Rikitake, JJ1BDX
+//
+// Values of the fields with the data types listed in adifio
+// are parsed by the ADIF data type
+// for ordered comparison and range predicates:
+//  Number: decimal number
+//  Date: YYYYMMDD (YYYY-MM-DD is also accepted for literals)
+//  Time: HHMM or HHMMSS
+// The data types of user-defined fields are given to Parse
+// by the data type indicators of the USERDEF declarations.
+// Values of other fields are compared as strings,
+// or as numbers if both values are numbers.
+
+package adifexpr
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jj1bdx/goadiftools/adifio"
+)
+
+// ADIF data types
+const (
+	adifTypeString = iota
+	adifTypeNumber
+	adifTypeDate
+	adifTypeTime
+)
+
+var ErrInvalidValue = errors.New("invalid value")
+
+var adifTypeNames = map[int]string{
+	adifTypeString: "String",
+	adifTypeNumber: "Number",
+	adifTypeDate:   "Date",
+	adifTypeTime:   "Time",
+}
+
+// Obtain the ADIF data type of the data type indicator
+// (N: Number, D: Date, T: Time)
+func indicatorType(indicator string) int {
+	switch strings.ToUpper(indicator) {
+	case "N":
+		return adifTypeNumber
+	case "D":
+		return adifTypeDate
+	case "T":
+		return adifTypeTime
+	}
+	return adifTypeString
+}
+
+// Obtain the ADIF data type of a field
+// by the data type indicator of the user-defined field in types,
+// or by the ADIF field type in adifio
+func adifFieldType(field string, types map[string]string) int {
+	if adiftype := indicatorType(types[field]); adiftype != adifTypeString {
+		return adiftype
+	}
+	return indicatorType(adifio.FieldType(field))
+}
+
+// Parse a value of the ADIF data type into a number for comparison
+// Dates are converted to YYYYMMDD, and times to HHMMSS
+func parseTypedValue(adiftype int, value string) (float64, error) {
+	switch adiftype {
+	case adifTypeNumber:
+		return adifio.ParseNumber(value)
+	case adifTypeDate:
+		if len(value) != 8 {
+			return 0, ErrInvalidValue
+		}
+		if _, err := time.Parse("20060102", value); err != nil {
+			return 0, ErrInvalidValue
+		}
+		return adifio.ParseNumber(value)
+	case adifTypeTime:
+		if len(value) == 4 {
+			value = value + "00"
+		}
+		if len(value) != 6 {
+			return 0, ErrInvalidValue
+		}
+		if _, err := time.Parse("150405", value); err != nil {
+			return 0, ErrInvalidValue
+		}
+		return adifio.ParseNumber(value)
+	}
+	return 0, ErrInvalidValue
+}
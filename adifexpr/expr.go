@@ -0,0 +1,880 @@
+// adifexpr: query expression parser and evaluator for ADIF records
+// by Kenji Rikitake, JJ1BDX
+//
+// The expressions are used by goadifgrep, goadifdelf, and goadifset.
+//
+// Expression syntax:
+//
+//	expr    := or
+//	or      := and { "||" and }
+//	and     := unary { "&&" unary }
+//	unary   := "!" unary | primary
+//	primary := "(" expr ")"
+//	         | "has" "(" field ")"
+//	         | field op literal
+//	         | field "in" "(" literal { "," literal } ")"
+//	         | field "between" literal literal
+//	         | "any" "(" regex ")"
+//	         | "basecall" "(" field "," string ")"
+//	         | "mobile" "(" field "," string ")"
+//	op      := "==" | "!=" | "=~" | "!~" | "<" | "<=" | ">" | ">="
+//
+// Field names are case insensitive.
+// Literals are double-quoted strings or numbers.
+// In strings, \" and \\ are escaped, other backslashes are kept as is.
+// == and != compare strings case-insensitively.
+// =~ and !~ match with Go RE2 regex.
+// <, <=, >, >= compare numerically if both values are numbers,
+// otherwise compare as case-insensitive strings.
+// For the fields with Number, Date, and Time types in adifio/fields.go,
+// ==, !=, <, <=, >, >=, and between compare the values parsed by the type.
+// between includes the both ends.
+// any(regex) matches if any field value matches the regex.
+// basecall(field, "JA1ABC") matches if the base callsign of the field
+// is JA1ABC, e.g., JA1ABC/P, W1/JA1ABC, and JA1ABC/MM.
+// mobile(field, type) matches the maritime/aeronautical mobile type
+// of the callsign in the field, where type is one of:
+// "mm" (/MM), "am" (/AM), "any" (/MM or /AM), "none" (neither /MM nor /AM).
+// Note: /MM and /AM do not count for DXCC.
+// Unparseable values of typed fields do not match,
+// and are reported by Warnings of the node.
+// Date literals in YYYY-MM-DD are converted to ADIF YYYYMMDD.
+// Missing fields are treated as empty strings.
+
+package adifexpr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifio"
+	"github.com/jj1bdx/goadiftools/callsign"
+)
+
+var ErrExprSyntax = errors.New("expression syntax error")
+
+// Expression node evaluated for each record
+type Node interface {
+	Eval(record adifparser.ADIFRecord) (bool, error)
+	// Names of the fields in the record referred by the node
+	Fields(record adifparser.ADIFRecord) []string
+	// Warnings of the unparseable values of typed fields
+	// in the record referred by the node
+	Warnings(record adifparser.ADIFRecord) []string
+}
+
+type orNode struct {
+	left  Node
+	right Node
+}
+
+type andNode struct {
+	left  Node
+	right Node
+}
+
+type notNode struct {
+	operand Node
+}
+
+type hasNode struct {
+	field string
+}
+
+type compareNode struct {
+	field    string
+	op       string
+	value    string
+	pattern  *regexp.Regexp
+	adiftype int
+	number   float64
+}
+
+type betweenNode struct {
+	field    string
+	adiftype int
+	low      float64
+	high     float64
+}
+
+type inNode struct {
+	field  string
+	values []string
+}
+
+type anyNode struct {
+	pattern *regexp.Regexp
+}
+
+type basecallNode struct {
+	field string
+	base  string
+}
+
+type mobileNode struct {
+	field  string
+	mobile string
+}
+
+// Obtain a field value, or empty string if the field does not exist
+func fieldValue(record adifparser.ADIFRecord, field string) (string, error) {
+	value, err := record.GetValue(field)
+	if err == adifparser.ErrNoSuchField {
+		return "", nil
+	}
+	return value, err
+}
+
+// Names of the field if it exists in the record
+func existingField(record adifparser.ADIFRecord, field string) []string {
+	if _, err := record.GetValue(field); err != nil {
+		return nil
+	}
+	return []string{field}
+}
+
+// Concatenate field names without duplicates
+func mergeFields(a, b []string) []string {
+	for _, f := range b {
+		found := false
+		for _, g := range a {
+			if f == g {
+				found = true
+				break
+			}
+		}
+		if !found {
+			a = append(a, f)
+		}
+	}
+	return a
+}
+
+func (n orNode) Fields(record adifparser.ADIFRecord) []string {
+	return mergeFields(n.left.Fields(record), n.right.Fields(record))
+}
+
+func (n andNode) Fields(record adifparser.ADIFRecord) []string {
+	return mergeFields(n.left.Fields(record), n.right.Fields(record))
+}
+
+func (n notNode) Fields(record adifparser.ADIFRecord) []string {
+	return n.operand.Fields(record)
+}
+
+func (n hasNode) Fields(record adifparser.ADIFRecord) []string {
+	return existingField(record, n.field)
+}
+
+func (n compareNode) Fields(record adifparser.ADIFRecord) []string {
+	return existingField(record, n.field)
+}
+
+func (n betweenNode) Fields(record adifparser.ADIFRecord) []string {
+	return existingField(record, n.field)
+}
+
+func (n inNode) Fields(record adifparser.ADIFRecord) []string {
+	return existingField(record, n.field)
+}
+
+func (n orNode) Warnings(record adifparser.ADIFRecord) []string {
+	return append(n.left.Warnings(record), n.right.Warnings(record)...)
+}
+
+func (n andNode) Warnings(record adifparser.ADIFRecord) []string {
+	return append(n.left.Warnings(record), n.right.Warnings(record)...)
+}
+
+func (n notNode) Warnings(record adifparser.ADIFRecord) []string {
+	return n.operand.Warnings(record)
+}
+
+func (n hasNode) Warnings(record adifparser.ADIFRecord) []string {
+	return nil
+}
+
+func (n compareNode) Warnings(record adifparser.ADIFRecord) []string {
+	if n.adiftype == adifTypeString || n.pattern != nil {
+		return nil
+	}
+	return typedFieldWarnings(record, n.field, n.adiftype)
+}
+
+func (n betweenNode) Warnings(record adifparser.ADIFRecord) []string {
+	return typedFieldWarnings(record, n.field, n.adiftype)
+}
+
+func (n inNode) Warnings(record adifparser.ADIFRecord) []string {
+	return nil
+}
+
+func (n anyNode) Warnings(record adifparser.ADIFRecord) []string {
+	return nil
+}
+
+func (n basecallNode) Warnings(record adifparser.ADIFRecord) []string {
+	return nil
+}
+
+func (n mobileNode) Warnings(record adifparser.ADIFRecord) []string {
+	return nil
+}
+
+// Fields whose values match the regex
+func (n anyNode) Fields(record adifparser.ADIFRecord) []string {
+	matched := []string{}
+	for _, field := range record.GetFields() {
+		value, err := record.GetValue(field)
+		if err == nil && n.pattern.MatchString(value) {
+			matched = append(matched, field)
+		}
+	}
+	return matched
+}
+
+func (n basecallNode) Fields(record adifparser.ADIFRecord) []string {
+	return existingField(record, n.field)
+}
+
+func (n basecallNode) Eval(record adifparser.ADIFRecord) (bool, error) {
+	value, err := fieldValue(record, n.field)
+	if err != nil || value == "" {
+		return false, err
+	}
+	call, err := callsign.Parse(value)
+	if err != nil {
+		// Invalid callsigns do not match
+		return false, nil
+	}
+	return call.Base == n.base, nil
+}
+
+func (n mobileNode) Fields(record adifparser.ADIFRecord) []string {
+	return existingField(record, n.field)
+}
+
+func (n mobileNode) Eval(record adifparser.ADIFRecord) (bool, error) {
+	value, err := fieldValue(record, n.field)
+	if err != nil {
+		return false, err
+	}
+	call, err := callsign.Parse(value)
+	if err != nil {
+		// Invalid callsigns do not match
+		return false, nil
+	}
+	mm := call.IsMaritimeMobile()
+	am := call.IsAeronauticalMobile()
+	switch n.mobile {
+	case "mm":
+		return mm, nil
+	case "am":
+		return am, nil
+	case "any":
+		return mm || am, nil
+	}
+	return !mm && !am, nil
+}
+
+// Create a node matching the base callsign of the field
+// with validating the callsign
+func NewBasecallNode(field, call string) (Node, error) {
+	c, err := callsign.Parse(call)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", err, call)
+	}
+	return basecallNode{field, c.Base}, nil
+}
+
+// Create a node matching the mobile type of the callsign in the field
+// with validating the mobile type
+func NewMobileNode(field, mobile string) (Node, error) {
+	mobile = strings.ToLower(mobile)
+	switch mobile {
+	case "mm", "am", "any", "none":
+		return mobileNode{field, mobile}, nil
+	}
+	return nil, fmt.Errorf("%w: invalid mobile type %q",
+		ErrExprSyntax, mobile)
+}
+
+// Create a node matching the field value with the regex
+// (same as field =~ "regex")
+func NewMatchNode(field, regex string) (Node, error) {
+	pattern, err := regexp.Compile(regex)
+	if err != nil {
+		return nil, err
+	}
+	return compareNode{
+		field:   strings.ToLower(field),
+		op:      "=~",
+		value:   regex,
+		pattern: pattern,
+	}, nil
+}
+
+// Create a node matching any field value with the regex
+// (same as any("regex"))
+func NewAnyNode(regex string) (Node, error) {
+	pattern, err := regexp.Compile(regex)
+	if err != nil {
+		return nil, err
+	}
+	return anyNode{pattern}, nil
+}
+
+// Create a node of both nodes (same as left && right)
+func NewAndNode(left, right Node) Node {
+	return andNode{left, right}
+}
+
+func (n anyNode) Eval(record adifparser.ADIFRecord) (bool, error) {
+	for _, field := range record.GetFields() {
+		value, err := record.GetValue(field)
+		if err != nil {
+			return false, err
+		}
+		if n.pattern.MatchString(value) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (n orNode) Eval(record adifparser.ADIFRecord) (bool, error) {
+	left, err := n.left.Eval(record)
+	if err != nil || left {
+		return left, err
+	}
+	return n.right.Eval(record)
+}
+
+func (n andNode) Eval(record adifparser.ADIFRecord) (bool, error) {
+	left, err := n.left.Eval(record)
+	if err != nil || !left {
+		return false, err
+	}
+	return n.right.Eval(record)
+}
+
+func (n notNode) Eval(record adifparser.ADIFRecord) (bool, error) {
+	result, err := n.operand.Eval(record)
+	return !result, err
+}
+
+func (n hasNode) Eval(record adifparser.ADIFRecord) (bool, error) {
+	value, err := fieldValue(record, n.field)
+	return value != "", err
+}
+
+// Parse the field value of the record by the ADIF data type
+// Returns false if the value is empty or unparseable
+func typedFieldValue(record adifparser.ADIFRecord, field string,
+	adiftype int) (float64, bool, error) {
+	value, err := fieldValue(record, field)
+	if err != nil || value == "" {
+		return 0, false, err
+	}
+	number, err := parseTypedValue(adiftype, value)
+	if err != nil {
+		return 0, false, nil
+	}
+	return number, true, nil
+}
+
+// Warning of the field value of the record if unparseable
+// by the ADIF data type
+func typedFieldWarnings(record adifparser.ADIFRecord, field string,
+	adiftype int) []string {
+	value, err := fieldValue(record, field)
+	if err != nil || value == "" {
+		return nil
+	}
+	if _, err := parseTypedValue(adiftype, value); err == nil {
+		return nil
+	}
+	call, _ := fieldValue(record, "call")
+	return []string{fmt.Sprintf("%s value %q is not a valid %s (call: %s)",
+		field, value, adifTypeNames[adiftype], call)}
+}
+
+func (n betweenNode) Eval(record adifparser.ADIFRecord) (bool, error) {
+	number, ok, err := typedFieldValue(record, n.field, n.adiftype)
+	if !ok || err != nil {
+		return false, err
+	}
+	return n.low <= number && number <= n.high, nil
+}
+
+// Compare the values of typed fields
+func (n compareNode) evalTyped(record adifparser.ADIFRecord) (bool, error) {
+	number, ok, err := typedFieldValue(record, n.field, n.adiftype)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		// Empty or unparseable values only match !=
+		return n.op == "!=", nil
+	}
+	switch n.op {
+	case "==":
+		return number == n.number, nil
+	case "!=":
+		return number != n.number, nil
+	case "<":
+		return number < n.number, nil
+	case "<=":
+		return number <= n.number, nil
+	case ">":
+		return number > n.number, nil
+	case ">=":
+		return number >= n.number, nil
+	}
+	return false, ErrExprSyntax
+}
+
+func (n compareNode) Eval(record adifparser.ADIFRecord) (bool, error) {
+	if n.adiftype != adifTypeString && n.pattern == nil {
+		return n.evalTyped(record)
+	}
+	value, err := fieldValue(record, n.field)
+	if err != nil {
+		return false, err
+	}
+	switch n.op {
+	case "==":
+		return strings.EqualFold(value, n.value), nil
+	case "!=":
+		return !strings.EqualFold(value, n.value), nil
+	case "=~":
+		return n.pattern.MatchString(value), nil
+	case "!~":
+		return !n.pattern.MatchString(value), nil
+	}
+	// Ordered comparison
+	if value == "" {
+		return false, nil
+	}
+	var cmp int
+	x, errx := adifio.ParseNumber(value)
+	y, erry := adifio.ParseNumber(n.value)
+	if errx == nil && erry == nil {
+		switch {
+		case x < y:
+			cmp = -1
+		case x > y:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(strings.ToUpper(value), strings.ToUpper(n.value))
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	}
+	return false, ErrExprSyntax
+}
+
+func (n inNode) Eval(record adifparser.ADIFRecord) (bool, error) {
+	value, err := fieldValue(record, n.field)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range n.values {
+		if strings.EqualFold(value, v) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Token types
+const (
+	tokenEOF = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// Split an expression into tokens
+func tokenize(input string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(input)
+	i := 0
+	for i < len(runes) {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(runes) &&
+				(unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) ||
+					runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens,
+				token{tokenIdent, string(runes[start:i]), start})
+		case unicode.IsDigit(c) || c == '.' ||
+			(c == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) &&
+				(unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == '-') {
+				i++
+			}
+			tokens = append(tokens,
+				token{tokenNumber, string(runes[start:i]), start})
+		case c == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				// Only \" and \\ are escaped
+				// Other backslashes are kept for regex
+				if runes[i] == '\\' && i+1 < len(runes) &&
+					(runes[i+1] == '"' || runes[i+1] == '\\') {
+					i++
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("%w: unterminated string at %d",
+					ErrExprSyntax, start)
+			}
+			i++
+			tokens = append(tokens, token{tokenString, sb.String(), start})
+		default:
+			start := i
+			two := ""
+			if i+1 < len(runes) {
+				two = string(runes[i : i+2])
+			}
+			switch two {
+			case "&&", "||", "==", "!=", "=~", "!~", "<=", ">=":
+				tokens = append(tokens, token{tokenOp, two, start})
+				i += 2
+				continue
+			}
+			switch c {
+			case '!', '<', '>', '(', ')', ',':
+				tokens = append(tokens, token{tokenOp, string(c), start})
+				i++
+			default:
+				return nil, fmt.Errorf("%w: unexpected %q at %d",
+					ErrExprSyntax, c, start)
+			}
+		}
+	}
+	tokens = append(tokens, token{tokenEOF, "", len(runes)})
+	return tokens, nil
+}
+
+// Recursive descent parser state
+type exprParser struct {
+	tokens []token
+	pos    int
+	// Data type indicators of user-defined fields
+	types map[string]string
+}
+
+var regDateLiteral = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
+
+// Parse a query expression
+// types maps the lowercase names of user-defined fields
+// to the data type indicators of the USERDEF declarations (may be nil)
+func Parse(input string, types map[string]string) (Node, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens, types: types}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, p.errorf("unexpected %q", p.peek().text)
+	}
+	return node, nil
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s at %d", ErrExprSyntax,
+		fmt.Sprintf(format, args...), p.peek().pos)
+}
+
+// Return true and consume the token if the next token is the operator
+func (p *exprParser) acceptOp(op string) bool {
+	t := p.peek()
+	if t.kind == tokenOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expectOp(op string) error {
+	if !p.acceptOp(op) {
+		return p.errorf("%q expected", op)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (Node, error) {
+	if p.acceptOp("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+// Parse a literal value by the ADIF data type of the field
+func (p *exprParser) parseTypedLiteral(adiftype int) (float64, error) {
+	pos := p.peek().pos
+	value, err := p.parseLiteral()
+	if err != nil {
+		return 0, err
+	}
+	number, err := parseTypedValue(adiftype, value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a valid %s at %d",
+			ErrExprSyntax, value, adifTypeNames[adiftype], pos)
+	}
+	return number, nil
+}
+
+// Parse a literal value
+func (p *exprParser) parseLiteral() (string, error) {
+	t := p.peek()
+	if t.kind != tokenString && t.kind != tokenNumber {
+		return "", p.errorf("literal expected")
+	}
+	p.next()
+	// Convert YYYY-MM-DD to ADIF date YYYYMMDD
+	if match := regDateLiteral.FindStringSubmatch(t.text); match != nil {
+		return match[1] + match[2] + match[3], nil
+	}
+	if t.kind == tokenNumber {
+		if !adifio.IsNumber(t.text) {
+			return "", fmt.Errorf("%w: invalid number %q at %d",
+				ErrExprSyntax, t.text, t.pos)
+		}
+	}
+	return t.text, nil
+}
+
+func (p *exprParser) parsePrimary() (Node, error) {
+	if p.acceptOp("(") {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+
+	t := p.peek()
+	if t.kind != tokenIdent {
+		return nil, p.errorf("field name expected")
+	}
+	p.next()
+	name := strings.ToLower(t.text)
+
+	if name == "has" && p.acceptOp("(") {
+		f := p.next()
+		if f.kind != tokenIdent {
+			return nil, p.errorf("field name expected")
+		}
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+		return hasNode{strings.ToLower(f.text)}, nil
+	}
+
+	if (name == "basecall" || name == "mobile") && p.acceptOp("(") {
+		f := p.next()
+		if f.kind != tokenIdent {
+			return nil, p.errorf("field name expected")
+		}
+		if err := p.expectOp(","); err != nil {
+			return nil, err
+		}
+		t := p.peek()
+		if t.kind != tokenString {
+			return nil, p.errorf("string expected")
+		}
+		p.next()
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+		field := strings.ToLower(f.text)
+		if name == "mobile" {
+			return NewMobileNode(field, t.text)
+		}
+		return NewBasecallNode(field, t.text)
+	}
+
+	if name == "any" && p.acceptOp("(") {
+		t := p.peek()
+		if t.kind != tokenString {
+			return nil, p.errorf("regex string expected")
+		}
+		p.next()
+		pattern, err := regexp.Compile(t.text)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+		return anyNode{pattern}, nil
+	}
+
+	op := p.peek()
+	if op.kind == tokenIdent && strings.ToLower(op.text) == "in" {
+		p.next()
+		if err := p.expectOp("("); err != nil {
+			return nil, err
+		}
+		values := []string{}
+		for {
+			value, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			if !p.acceptOp(",") {
+				break
+			}
+		}
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+		return inNode{name, values}, nil
+	}
+
+	if op.kind == tokenIdent && strings.ToLower(op.text) == "between" {
+		p.next()
+		adiftype := adifFieldType(name, p.types)
+		if adiftype == adifTypeString {
+			// Untyped fields are compared as numbers
+			adiftype = adifTypeNumber
+		}
+		low, err := p.parseTypedLiteral(adiftype)
+		if err != nil {
+			return nil, err
+		}
+		high, err := p.parseTypedLiteral(adiftype)
+		if err != nil {
+			return nil, err
+		}
+		return betweenNode{name, adiftype, low, high}, nil
+	}
+
+	if op.kind != tokenOp {
+		return nil, p.errorf("operator expected")
+	}
+	switch op.text {
+	case "==", "!=", "=~", "!~", "<", "<=", ">", ">=":
+	default:
+		return nil, p.errorf("operator expected")
+	}
+	p.next()
+	node := compareNode{field: name, op: op.text}
+	if op.text == "=~" || op.text == "!~" {
+		t := p.peek()
+		if t.kind != tokenString {
+			return nil, p.errorf("regex string expected")
+		}
+		p.next()
+		pattern, err := regexp.Compile(t.text)
+		if err != nil {
+			return nil, err
+		}
+		node.value = t.text
+		node.pattern = pattern
+		return node, nil
+	}
+	node.adiftype = adifFieldType(name, p.types)
+	if node.adiftype != adifTypeString {
+		number, err := p.parseTypedLiteral(node.adiftype)
+		if err != nil {
+			return nil, err
+		}
+		node.number = number
+		return node, nil
+	}
+	value, err := p.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+	node.value = value
+	return node, nil
+}
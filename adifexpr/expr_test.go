@@ -0,0 +1,363 @@
+package adifexpr
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+// Expression and expected result for a record
+type evalTest struct {
+	expr string
+	want bool
+}
+
+func runEvalTests(t *testing.T, record adifparser.ADIFRecord, tests []evalTest) {
+	t.Helper()
+	for _, tt := range tests {
+		node, err := Parse(tt.expr, nil)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		got, err := node.Eval(record)
+		if err != nil {
+			t.Errorf("Eval(%q): %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Eval(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestBooleanExpressions(t *testing.T) {
+	record := testutil.ParseRecord(t,
+		"<call:5>A1AAA<band:3>20m<mode:3>FT8<cont:2>EU<qsl_rcvd:1>Y<notes:0><eor>")
+	runEvalTests(t, record, []evalTest{
+		{`band == "20m"`, true},
+		{`band == "20M"`, true},
+		{`BAND == "20m"`, true},
+		{`band != "20m"`, false},
+		{`band == "40m"`, false},
+		{`mode in ("FT8", "FT4")`, true},
+		{`mode in ("CW")`, false},
+		{`has(qsl_rcvd)`, true},
+		{`has(notes)`, false},
+		{`has(gridsquare)`, false},
+		{`!has(qsl_rcvd)`, false},
+		{`!!has(qsl_rcvd)`, true},
+		{`cont =~ "^E"`, true},
+		{`cont !~ "^E"`, false},
+		{`call =~ "(?i)a1a"`, true},
+		{`band == "20m" && mode == "CW"`, false},
+		{`band == "20m" || mode == "CW"`, true},
+		// && binds tighter than ||
+		{`band == "40m" && mode == "CW" || cont == "EU"`, true},
+		{`band == "40m" && (mode == "CW" || cont == "EU")`, false},
+		{`!(band == "40m" || mode == "CW")`, true},
+		{`gridsquare == ""`, true},
+		{`band == "20m" && mode in ("FT8","FT4") && !has(notes) && cont =~ "EU"`, true},
+	})
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`band`,
+		`band ==`,
+		`band = "20m"`,
+		`band == "20m" &&`,
+		`(band == "20m"`,
+		`band == "20m")`,
+		`has(band`,
+		`has("band")`,
+		`mode in ()`,
+		`mode in ("FT8"`,
+		`band == "20m" mode == "CW"`,
+		`"20m" == band`,
+		`band == "20m`,
+		`band =~ 20`,
+		`&& band == "20m"`,
+	} {
+		if _, err := Parse(expr, nil); !errors.Is(err, ErrExprSyntax) {
+			t.Errorf("Parse(%q) error = %v, want %v", expr, err, ErrExprSyntax)
+		}
+	}
+	// Invalid regex
+	if _, err := Parse(`call =~ "("`, nil); err == nil {
+		t.Errorf("Parse(invalid regex): no error")
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	record := testutil.ParseRecord(t, `<comment:12>say "hi" \ok<eor>`)
+	runEvalTests(t, record, []evalTest{
+		{`comment == "say \"hi\" \\ok"`, true},
+		{`comment =~ "\"hi\" \\\\ok$"`, true},
+		{`comment =~ "\s\\\\"`, true},
+	})
+}
+
+func TestTypedPredicates(t *testing.T) {
+	record := testutil.ParseRecord(t,
+		"<call:5>A1AAA<freq:6>14.074<tx_pwr:2>50<qso_date:8>20231125<time_on:4>0830"+
+			"<distance:4>9000<srx:3>010<rst_sent:3>599<app_test_n:3>010<eor>")
+	runEvalTests(t, record, []evalTest{
+		{`freq between 14.000 14.350`, true},
+		{`freq between 7.000 7.300`, false},
+		{`freq between 14.074 14.074`, true},
+		{`freq == 14.0740`, true},
+		{`freq > 14`, true},
+		{`freq < "14.1"`, true},
+		{`tx_pwr <= 5`, false},
+		{`tx_pwr >= 50`, true},
+		{`tx_pwr != 100`, true},
+		{`qso_date >= 20230101`, true},
+		{`qso_date >= 2023-01-01`, true},
+		{`qso_date >= "2023-01-01"`, true},
+		{`qso_date < 2023-11-25`, false},
+		{`qso_date between 2023-11-01 2023-11-30`, true},
+		{`qso_date == 2023-11-25`, true},
+		// HHMM and HHMMSS are compared as times
+		{`time_on == 083000`, true},
+		{`time_on > 0829`, true},
+		{`time_on between 0800 0900`, true},
+		{`time_on between 083001 0900`, false},
+		{`distance > 10000`, false},
+		// Integer fields are Number
+		{`srx == 10`, true},
+		// Untyped fields: ordered comparison as numbers if both are numbers
+		{`app_test_n > 9`, true},
+		{`app_test_n == 10`, false},
+		{`app_test_n == "010"`, true},
+		{`app_test_n between 5 15`, true},
+		{`rst_sent >= 599`, true},
+		// Otherwise strings compared case-insensitively
+		{`call > "A1AA"`, true},
+		{`call < "a1aab"`, true},
+		{`notes > ""`, false},
+	})
+}
+
+func TestTypedPredicatesInvalidValues(t *testing.T) {
+	record := testutil.ParseRecord(t,
+		"<freq:4>abcd<qso_date:8>20231340<time_on:4>2561<tx_pwr:0><eor>")
+	runEvalTests(t, record, []evalTest{
+		// Unparseable and empty values do not match except !=
+		{`freq > 0`, false},
+		{`freq between 0 100`, false},
+		{`freq != 14.074`, true},
+		{`qso_date >= 20230101`, false},
+		{`time_on < 2359`, false},
+		{`tx_pwr == 0`, false},
+		{`tx_pwr != 0`, true},
+		{`has(tx_pwr)`, false},
+	})
+}
+
+func TestWarnings(t *testing.T) {
+	record := testutil.ParseRecord(t,
+		"<call:5>A1AAA<freq:4>abcd<qso_date:8>20231340<tx_pwr:0><srx:2>+5<comment:3>xyz<eor>")
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{`freq > 0`, []string{`freq value "abcd" is not a valid Number (call: A1AAA)`}},
+		{`tx_pwr == 0 || comment == "xyz"`, nil},
+		{`freq =~ "^a"`, nil},
+		// Non-ADIF Numbers such as +5 are unparseable
+		{`!(srx between 0 10) && qso_date > 20230101`, []string{
+			`srx value "+5" is not a valid Number (call: A1AAA)`,
+			`qso_date value "20231340" is not a valid Date (call: A1AAA)`}},
+	}
+	for _, tt := range tests {
+		node, err := Parse(tt.expr, nil)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := node.Warnings(record); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Warnings(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	record := testutil.ParseRecord(t, "<srx:3>010<comment:3>1e3<eor>")
+	runEvalTests(t, record, []evalTest{
+		{`srx == 10`, true},
+		// Strings compared if not ADIF Numbers
+		{`comment > "1000"`, true},
+	})
+}
+
+func TestTypedLiteralErrors(t *testing.T) {
+	for _, expr := range []string{
+		`freq > "fourteen"`,
+		`freq between 14.0`,
+		`freq between 14.0 "x"`,
+		`qso_date >= 2023-13-01`,
+		`qso_date >= 202301`,
+		`time_on > 2500`,
+		`time_on > 08300`,
+		`app_test_n between "a" "b"`,
+		`srx > 1.2.3`,
+	} {
+		if _, err := Parse(expr, nil); !errors.Is(err, ErrExprSyntax) {
+			t.Errorf("Parse(%q) error = %v, want %v", expr, err, ErrExprSyntax)
+		}
+	}
+}
+
+func TestAnyAndFields(t *testing.T) {
+	record := testutil.ParseRecord(t,
+		"<call:5>A1AAA<band:3>20m<comment:8>Test QSO<notes:7>testing<eor>")
+	runEvalTests(t, record, []evalTest{
+		{`any("(?i)test")`, true},
+		{`any("^20m$")`, true},
+		{`any("xyz")`, false},
+		{`!any("xyz") && band == "20m"`, true},
+	})
+
+	tests := []struct {
+		expr   string
+		fields []string
+	}{
+		{`band == "20m"`, []string{"band"}},
+		{`band == "20m" && has(gridsquare)`, []string{"band"}},
+		{`band == "20m" || call =~ "A1" || BAND == "40m"`, []string{"band", "call"}},
+		{`!(mode in ("CW"))`, nil},
+		// Only the fields whose values matched
+		{`any("(?i)test")`, []string{"comment", "notes"}},
+		{`freq between 1 2`, nil},
+	}
+	for _, tt := range tests {
+		node, err := Parse(tt.expr, nil)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := node.Fields(record); !reflect.DeepEqual(got, tt.fields) &&
+			!(len(got) == 0 && len(tt.fields) == 0) {
+			t.Errorf("Fields(%q) = %v, want %v", tt.expr, got, tt.fields)
+		}
+	}
+}
+
+func TestNewNodes(t *testing.T) {
+	record := testutil.ParseRecord(t, "<call:5>A1AAA<band:3>20m<eor>")
+	match, err := NewMatchNode("BAND", "^20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	anynode, err := NewAnyNode("A1A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range []Node{match, anynode, NewAndNode(match, anynode)} {
+		if got, err := node.Eval(record); !got || err != nil {
+			t.Errorf("Eval(%+v) = %v, %v", node, got, err)
+		}
+	}
+	if _, err := NewMatchNode("band", "("); err == nil {
+		t.Errorf("NewMatchNode(invalid regex): no error")
+	}
+	if _, err := NewAnyNode("["); err == nil {
+		t.Errorf("NewAnyNode(invalid regex): no error")
+	}
+}
+
+func TestBasecallAndMobile(t *testing.T) {
+	tests := []struct {
+		call string
+		want []bool // basecall JA1ABC, mm, am, any, none
+	}{
+		{"JA1ABC", []bool{true, false, false, false, true}},
+		{"ja1abc/p", []bool{true, false, false, false, true}},
+		{"W1/JA1ABC", []bool{true, false, false, false, true}},
+		{"JA1ABC/MM", []bool{true, true, false, true, false}},
+		{"KH6/JA1ABC/AM", []bool{true, false, true, true, false}},
+		{"JA1ABD/MM", []bool{false, true, false, true, false}},
+		{"JA1AB", []bool{false, false, false, false, true}},
+		// Invalid callsigns match no mobile type
+		{"JA1-ABC", []bool{false, false, false, false, false}},
+		{"JA1ABC/JA1XYZ", []bool{false, false, false, false, false}},
+	}
+	exprs := []string{`basecall(call, "ja1abc/qrp")`, `mobile(call, "mm")`,
+		`mobile(call, "AM")`, `mobile(call, "any")`, `mobile(call, "none")`}
+	for _, tt := range tests {
+		record := testutil.ParseRecord(t, fmt.Sprintf("<call:%d>%s<eor>", len(tt.call), tt.call))
+		for i, expr := range exprs {
+			node, err := Parse(expr, nil)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", expr, err)
+			}
+			if got, err := node.Eval(record); got != tt.want[i] || err != nil {
+				t.Errorf("%s for %s = %v, %v, want %v", expr, tt.call, got, err, tt.want[i])
+			}
+		}
+	}
+
+	// No call field
+	record := testutil.ParseRecord(t, "<band:3>20m<eor>")
+	runEvalTests(t, record, []evalTest{
+		{`basecall(call, "JA1ABC")`, false},
+		{`mobile(call, "mm")`, false},
+	})
+
+	for _, expr := range []string{`basecall(call, "JA1/ABC/DEF/G/H")`,
+		`basecall(call, "")`, `mobile(call, "xm")`, `mobile("mm")`,
+		`basecall(call, JA1ABC)`, `mobile(call "mm")`} {
+		if _, err := Parse(expr, nil); err == nil {
+			t.Errorf("Parse(%q): no error", expr)
+		}
+	}
+	if _, err := NewBasecallNode("call", "JA1-ABC"); err == nil {
+		t.Errorf("NewBasecallNode(invalid callsign): no error")
+	}
+	if _, err := NewMobileNode("call", "boat"); !errors.Is(err, ErrExprSyntax) {
+		t.Errorf("NewMobileNode(invalid type) error = %v", err)
+	}
+}
+
+func TestUserdefTypes(t *testing.T) {
+	record := testutil.ParseRecord(t, "<epc:3>010<shipdate:8>20231125<shiptime:4>0830<size:2>10<eor>")
+	types := map[string]string{"epc": "N", "shipdate": "d", "shiptime": "T", "size": "S"}
+	tests := []struct {
+		expr  string
+		types map[string]string
+		want  bool
+	}{
+		{`epc == 10`, types, true},
+		{`epc == 10`, nil, false},
+		{`shipdate >= 2023-11-01`, types, true},
+		{`shiptime between 0800 0900`, types, true},
+		{`shiptime == 083000`, types, true},
+		{`shiptime == 083000`, nil, false},
+		// String types are compared as untyped fields
+		{`size > 9`, types, true},
+		{`size == "10"`, types, true},
+	}
+	for _, tt := range tests {
+		node, err := Parse(tt.expr, tt.types)
+		if err != nil {
+			t.Errorf("Parse(%q, %v): %v", tt.expr, tt.types, err)
+			continue
+		}
+		if got, err := node.Eval(record); got != tt.want || err != nil {
+			t.Errorf("Eval(%q) with %v = %v, %v, want %v", tt.expr, tt.types, got, err, tt.want)
+		}
+	}
+	// Literals are parsed by the types
+	if _, err := Parse(`shiptime < 2500`, types); !errors.Is(err, ErrExprSyntax) {
+		t.Errorf("Parse(invalid time) error = %v, want %v", err, ErrExprSyntax)
+	}
+	if _, err := Parse(`shiptime < 2500`, nil); err != nil {
+		t.Errorf("Parse(untyped): %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+// adifio: standard ADIF QSO fields
+// by Kenji Rikitake, JJ1BDX
+//
+// The QSO fields defined in ADIF 3.1.5,
+// including the import-only fields.
+// User-defined fields must not have the names of the standard fields.
+// The data types of the fields for typed comparison are also listed here
+// by the data type indicators.
+
+package adifio
+
+import (
+	"strings"
+)
+
+var standardFields = map[string]bool{
+	"address":                    true,
+	"address_intl":               true,
+	"age":                        true,
+	"altitude":                   true,
+	"a_index":                    true,
+	"ant_az":                     true,
+	"ant_el":                     true,
+	"ant_path":                   true,
+	"arrl_sect":                  true,
+	"award_granted":              true,
+	"award_submitted":            true,
+	"band":                       true,
+	"band_rx":                    true,
+	"call":                       true,
+	"check":                      true,
+	"class":                      true,
+	"clublog_qso_upload_date":    true,
+	"clublog_qso_upload_status":  true,
+	"cnty":                       true,
+	"cnty_alt":                   true,
+	"comment":                    true,
+	"comment_intl":               true,
+	"cont":                       true,
+	"contacted_op":               true,
+	"contest_id":                 true,
+	"country":                    true,
+	"country_intl":               true,
+	"cqz":                        true,
+	"credit_granted":             true,
+	"credit_submitted":           true,
+	"darc_dok":                   true,
+	"dcl_qslrdate":               true,
+	"dcl_qslsdate":               true,
+	"dcl_qsl_rcvd":               true,
+	"dcl_qsl_sent":               true,
+	"distance":                   true,
+	"dxcc":                       true,
+	"email":                      true,
+	"eq_call":                    true,
+	"eqsl_ag":                    true,
+	"eqsl_qslrdate":              true,
+	"eqsl_qslsdate":              true,
+	"eqsl_qsl_rcvd":              true,
+	"eqsl_qsl_sent":              true,
+	"fists":                      true,
+	"fists_cc":                   true,
+	"force_init":                 true,
+	"freq":                       true,
+	"freq_rx":                    true,
+	"gridsquare":                 true,
+	"gridsquare_ext":             true,
+	"guest_op":                   true,
+	"hamlogeu_qso_upload_date":   true,
+	"hamlogeu_qso_upload_status": true,
+	"hamqth_qso_upload_date":     true,
+	"hamqth_qso_upload_status":   true,
+	"hrdlog_qso_upload_date":     true,
+	"hrdlog_qso_upload_status":   true,
+	"iota":                       true,
+	"iota_island_id":             true,
+	"ituz":                       true,
+	"k_index":                    true,
+	"lat":                        true,
+	"lon":                        true,
+	"lotw_qslrdate":              true,
+	"lotw_qslsdate":              true,
+	"lotw_qsl_rcvd":              true,
+	"lotw_qsl_sent":              true,
+	"max_bursts":                 true,
+	"mode":                       true,
+	"morse_key_info":             true,
+	"morse_key_type":             true,
+	"ms_shower":                  true,
+	"my_altitude":                true,
+	"my_antenna":                 true,
+	"my_antenna_intl":            true,
+	"my_arrl_sect":               true,
+	"my_city":                    true,
+	"my_city_intl":               true,
+	"my_cnty":                    true,
+	"my_cnty_alt":                true,
+	"my_country":                 true,
+	"my_country_intl":            true,
+	"my_cq_zone":                 true,
+	"my_darc_dok":                true,
+	"my_dxcc":                    true,
+	"my_fists":                   true,
+	"my_gridsquare":              true,
+	"my_gridsquare_ext":          true,
+	"my_iota":                    true,
+	"my_iota_island_id":          true,
+	"my_itu_zone":                true,
+	"my_lat":                     true,
+	"my_lon":                     true,
+	"my_morse_key_info":          true,
+	"my_morse_key_type":          true,
+	"my_name":                    true,
+	"my_name_intl":               true,
+	"my_postal_code":             true,
+	"my_postal_code_intl":        true,
+	"my_pota_ref":                true,
+	"my_rig":                     true,
+	"my_rig_intl":                true,
+	"my_sig":                     true,
+	"my_sig_intl":                true,
+	"my_sig_info":                true,
+	"my_sig_info_intl":           true,
+	"my_sota_ref":                true,
+	"my_state":                   true,
+	"my_street":                  true,
+	"my_street_intl":             true,
+	"my_usaca_counties":          true,
+	"my_vucc_grids":              true,
+	"my_wwff_ref":                true,
+	"name":                       true,
+	"name_intl":                  true,
+	"notes":                      true,
+	"notes_intl":                 true,
+	"nr_bursts":                  true,
+	"nr_pings":                   true,
+	"operator":                   true,
+	"owner_callsign":             true,
+	"pfx":                        true,
+	"pota_ref":                   true,
+	"precedence":                 true,
+	"prop_mode":                  true,
+	"public_key":                 true,
+	"qrzcom_qso_download_date":   true,
+	"qrzcom_qso_download_status": true,
+	"qrzcom_qso_upload_date":     true,
+	"qrzcom_qso_upload_status":   true,
+	"qslmsg":                     true,
+	"qslmsg_intl":                true,
+	"qslmsg_rcvd":                true,
+	"qslrdate":                   true,
+	"qslsdate":                   true,
+	"qsl_rcvd":                   true,
+	"qsl_rcvd_via":               true,
+	"qsl_sent":                   true,
+	"qsl_sent_via":               true,
+	"qsl_via":                    true,
+	"qso_complete":               true,
+	"qso_date":                   true,
+	"qso_date_off":               true,
+	"qso_random":                 true,
+	"qth":                        true,
+	"qth_intl":                   true,
+	"region":                     true,
+	"rig":                        true,
+	"rig_intl":                   true,
+	"rst_rcvd":                   true,
+	"rst_sent":                   true,
+	"rx_pwr":                     true,
+	"sat_mode":                   true,
+	"sat_name":                   true,
+	"sfi":                        true,
+	"sig":                        true,
+	"sig_intl":                   true,
+	"sig_info":                   true,
+	"sig_info_intl":              true,
+	"silent_key":                 true,
+	"skcc":                       true,
+	"sota_ref":                   true,
+	"srx":                        true,
+	"srx_string":                 true,
+	"state":                      true,
+	"station_callsign":           true,
+	"stx":                        true,
+	"stx_string":                 true,
+	"submode":                    true,
+	"swl":                        true,
+	"ten_ten":                    true,
+	"time_off":                   true,
+	"time_on":                    true,
+	"tx_pwr":                     true,
+	"uksmg":                      true,
+	"usaca_counties":             true,
+	"ve_prov":                    true,
+	"vucc_grids":                 true,
+	"web":                        true,
+	"wwff_ref":                   true,
+}
+
+// Check if the field is a standard ADIF QSO field
+func IsStandardField(name string) bool {
+	return standardFields[strings.ToLower(name)]
+}
+
+// ADIF data type indicators of the fields
+// Number, Integer, and PositiveInteger types are all Number (N)
+// Date (D): YYYYMMDD, Time (T): HHMM or HHMMSS
+var fieldTypes = map[string]string{
+	"a_index":                  "N",
+	"age":                      "N",
+	"altitude":                 "N",
+	"ant_az":                   "N",
+	"ant_el":                   "N",
+	"cqz":                      "N",
+	"distance":                 "N",
+	"dxcc":                     "N",
+	"fists":                    "N",
+	"fists_cc":                 "N",
+	"freq":                     "N",
+	"freq_rx":                  "N",
+	"ituz":                     "N",
+	"k_index":                  "N",
+	"max_bursts":               "N",
+	"my_altitude":              "N",
+	"my_cq_zone":               "N",
+	"my_dxcc":                  "N",
+	"my_fists":                 "N",
+	"my_iota_island_id":        "N",
+	"my_itu_zone":              "N",
+	"nr_bursts":                "N",
+	"nr_pings":                 "N",
+	"rx_pwr":                   "N",
+	"sfi":                      "N",
+	"srx":                      "N",
+	"stx":                      "N",
+	"ten_ten":                  "N",
+	"tx_pwr":                   "N",
+	"uksmg":                    "N",
+	"clublog_qso_upload_date":  "D",
+	"dcl_qslrdate":             "D",
+	"dcl_qslsdate":             "D",
+	"eqsl_qslrdate":            "D",
+	"eqsl_qslsdate":            "D",
+	"hamlogeu_qso_upload_date": "D",
+	"hamqth_qso_upload_date":   "D",
+	"hrdlog_qso_upload_date":   "D",
+	"lotw_qslrdate":            "D",
+	"lotw_qslsdate":            "D",
+	"qrzcom_qso_download_date": "D",
+	"qrzcom_qso_upload_date":   "D",
+	"qslrdate":                 "D",
+	"qslsdate":                 "D",
+	"qso_date":                 "D",
+	"qso_date_off":             "D",
+	"time_off":                 "T",
+	"time_on":                  "T",
+}
+
+// Obtain the ADIF data type indicator of the field:
+// N (Number), D (Date), T (Time),
+// or empty string for the other fields
+func FieldType(name string) string {
+	return fieldTypes[strings.ToLower(name)]
+}
@@ -0,0 +1,194 @@
+// adifio: ADIF file header handling
+// by Kenji Rikitake, JJ1BDX
+//
+// An ADIF file has a header if the first character is not "<".
+// The header consists of the preamble text and the header fields,
+// such as ADIF_VER and PROGRAMID, and ends with <EOH>.
+// The header is read before the records are read by adifparser,
+// and the consumed bytes are replayed to adifparser
+// so that adifparser reads the whole input as usual.
+// If no <EOH> is found before the first record or within
+// maxHeaderLength bytes, the input is treated as having no header
+// (e.g., records after a BOM or blank lines).
+
+package adifio
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidHeader = errors.New("invalid header field")
+
+// Maximum length of the header to be buffered
+const maxHeaderLength = 1 << 20
+
+var errHeaderTooLong = errors.New("header too long")
+
+// UTF-8 byte order mark
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// Header field
+type HeaderField struct {
+	// Field name in lowercase
+	Name  string
+	Value string
+	// Data type indicator (empty if none)
+	Type string
+}
+
+// ADIF file header
+type Header struct {
+	// True if the file has a header
+	Exists bool
+	// Preamble text before the first header field
+	Preamble string
+	// Header fields in the order of appearance
+	Fields []HeaderField
+	// Raw header text including <eoh>
+	Raw string
+}
+
+// Obtain the value of the header field
+// Returns false if the field does not exist
+func (h *Header) GetValue(name string) (string, bool) {
+	name = strings.ToLower(name)
+	for _, f := range h.Fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// Reader recording the consumed bytes
+type recordingReader struct {
+	reader   *bufio.Reader
+	consumed bytes.Buffer
+}
+
+func (r *recordingReader) readByte() (byte, error) {
+	if r.consumed.Len() >= maxHeaderLength {
+		return 0, errHeaderTooLong
+	}
+	b, err := r.reader.ReadByte()
+	if err == nil {
+		r.consumed.WriteByte(b)
+	}
+	return b, err
+}
+
+// Read bytes until the delimiter, not including the delimiter
+func (r *recordingReader) readUntil(delimiter byte) (string, error) {
+	var s strings.Builder
+	for {
+		b, err := r.readByte()
+		if err != nil {
+			return s.String(), err
+		}
+		if b == delimiter {
+			return s.String(), nil
+		}
+		s.WriteByte(b)
+	}
+}
+
+// Parse a tag of name:length[:type]
+func parseTag(tag string) (string, int, string, error) {
+	parts := strings.Split(tag, ":")
+	name := strings.ToLower(strings.TrimSpace(parts[0]))
+	if len(parts) == 1 {
+		return name, 0, "", nil
+	}
+	if len(parts) > 3 {
+		return "", 0, "", fmt.Errorf("%w: <%s>", ErrInvalidHeader, tag)
+	}
+	length, err := strconv.Atoi(parts[1])
+	if err != nil || length < 0 {
+		return "", 0, "", fmt.Errorf("%w: <%s>", ErrInvalidHeader, tag)
+	}
+	datatype := ""
+	if len(parts) == 3 {
+		datatype = strings.ToUpper(parts[2])
+	}
+	return name, length, datatype, nil
+}
+
+// Read the header from the reader
+// Returns the header and the reader of the whole input
+// including the header for adifparser
+func ReadHeader(reader io.Reader) (*Header, io.Reader, error) {
+	r := &recordingReader{reader: bufio.NewReader(reader)}
+	header := &Header{}
+	replay := func() io.Reader {
+		return io.MultiReader(bytes.NewReader(r.consumed.Bytes()), r.reader)
+	}
+	// Fall back to no header if <eoh> is not found
+	// BOM and blank lines before the first record are skipped
+	noHeader := func(err error) (*Header, io.Reader, error) {
+		if err != io.EOF && err != errHeaderTooLong {
+			return header, replay(), err
+		}
+		consumed := r.consumed.Bytes()
+		if i := bytes.IndexByte(consumed, '<'); i >= 0 &&
+			len(bytes.TrimSpace(bytes.TrimPrefix(consumed[:i], utf8BOM))) == 0 {
+			consumed = consumed[i:]
+		}
+		return &Header{}, io.MultiReader(bytes.NewReader(consumed), r.reader), nil
+	}
+
+	first, err := r.reader.Peek(1)
+	if err == io.EOF {
+		return header, replay(), nil
+	} else if err != nil {
+		return header, replay(), err
+	}
+	if first[0] == '<' {
+		// No header
+		return header, replay(), nil
+	}
+	header.Exists = true
+
+	preamble, err := r.readUntil('<')
+	if err != nil {
+		return noHeader(err)
+	}
+	header.Preamble = preamble
+	for {
+		tag, err := r.readUntil('>')
+		if err != nil {
+			return noHeader(err)
+		}
+		name, length, datatype, err := parseTag(tag)
+		if err != nil {
+			return header, replay(), err
+		}
+		if name == "eoh" {
+			break
+		}
+		if name == "eor" {
+			// Records without header
+			return noHeader(io.EOF)
+		}
+		value := make([]byte, length)
+		for i := range value {
+			value[i], err = r.readByte()
+			if err != nil {
+				return noHeader(err)
+			}
+		}
+		header.Fields = append(header.Fields,
+			HeaderField{Name: name, Value: string(value), Type: datatype})
+		// Skip the text until the next tag
+		if _, err := r.readUntil('<'); err != nil {
+			return noHeader(err)
+		}
+	}
+	header.Raw = r.consumed.String()
+	return header, replay(), nil
+}
@@ -0,0 +1,106 @@
+package adifio
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadHeader(t *testing.T) {
+	input := "Exported log\r\nby a logger\n" +
+		"<ADIF_VER:5>3.1.4 <programid:6>Logger\n" +
+		"<USERDEF1:8:N>EPC_RANK <Created_Timestamp:15>20231125 123456\n" +
+		"<EOH>\n<call:4>A1AA<eor>\n"
+	header, reader, err := ReadHeader(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !header.Exists || header.Preamble != "Exported log\r\nby a logger\n" {
+		t.Errorf("header = %+v", header)
+	}
+	want := []HeaderField{
+		{Name: "adif_ver", Value: "3.1.4"},
+		{Name: "programid", Value: "Logger"},
+		{Name: "userdef1", Value: "EPC_RANK", Type: "N"},
+		{Name: "created_timestamp", Value: "20231125 123456"},
+	}
+	if !reflect.DeepEqual(header.Fields, want) {
+		t.Errorf("fields = %+v, want %+v", header.Fields, want)
+	}
+	if !strings.HasSuffix(header.Raw, "<EOH>") || !strings.HasPrefix(input, header.Raw) {
+		t.Errorf("raw = %q", header.Raw)
+	}
+	if value, ok := header.GetValue("PROGRAMID"); value != "Logger" || !ok {
+		t.Errorf("GetValue(PROGRAMID) = %q, %v", value, ok)
+	}
+	if _, ok := header.GetValue("programversion"); ok {
+		t.Errorf("GetValue(programversion) exists")
+	}
+	// The whole input is replayed
+	if replayed, _ := io.ReadAll(reader); string(replayed) != input {
+		t.Errorf("replayed = %q", replayed)
+	}
+}
+
+func TestReadHeaderNoHeader(t *testing.T) {
+	for _, input := range []string{"", "<call:4>A1AA<eor>\n"} {
+		header, reader, err := ReadHeader(strings.NewReader(input))
+		if err != nil || header.Exists || len(header.Fields) != 0 {
+			t.Errorf("ReadHeader(%q) = %+v, %v", input, header, err)
+		}
+		if replayed, _ := io.ReadAll(reader); string(replayed) != input {
+			t.Errorf("ReadHeader(%q): replayed = %q", input, replayed)
+		}
+	}
+}
+
+func TestReadHeaderInvalidTag(t *testing.T) {
+	for _, input := range []string{
+		"preamble <adif_ver:x>3.1.4 <eoh>",
+		"preamble <adif_ver:-1>3.1.4 <eoh>",
+		"preamble <adif_ver:5:S:x>3.1.4 <eoh>",
+	} {
+		if _, _, err := ReadHeader(strings.NewReader(input)); !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("ReadHeader(%q) error = %v, want %v", input, err, ErrInvalidHeader)
+		}
+	}
+}
+
+func TestReadHeaderFallback(t *testing.T) {
+	records := "<call:4>A1AA<eor>\n<call:4>A1AB<eor>\n"
+	tests := []struct {
+		input  string
+		replay string
+	}{
+		// BOM and blank lines before the records are skipped
+		{"\xef\xbb\xbf" + records, records},
+		{"\n\r\n" + records, records},
+		{"\xef\xbb\xbf\n" + records, records},
+		// No <eoh> until EOF
+		{"text without header", "text without header"},
+		{"preamble <adif_ver:5>3.1.4", "preamble <adif_ver:5>3.1.4"},
+		{"preamble " + records, "preamble " + records},
+	}
+	for _, tt := range tests {
+		header, reader, err := ReadHeader(strings.NewReader(tt.input))
+		if err != nil || header.Exists || len(header.Fields) != 0 {
+			t.Errorf("ReadHeader(%q) = %+v, %v", tt.input, header, err)
+		}
+		if replayed, _ := io.ReadAll(reader); string(replayed) != tt.replay {
+			t.Errorf("ReadHeader(%q): replayed = %q, want %q", tt.input, replayed, tt.replay)
+		}
+	}
+}
+
+func TestReadHeaderTooLong(t *testing.T) {
+	input := strings.Repeat("preamble\n", maxHeaderLength/9+1) + "<eoh>\n<call:4>A1AA<eor>\n"
+	header, reader, err := ReadHeader(strings.NewReader(input))
+	if err != nil || header.Exists {
+		t.Errorf("ReadHeader = %+v, %v", header.Exists, err)
+	}
+	if replayed, _ := io.ReadAll(reader); string(replayed) != input {
+		t.Errorf("replayed %d bytes, want %d", len(replayed), len(input))
+	}
+}
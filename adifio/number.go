@@ -0,0 +1,41 @@
+// adifio: ADIF Number and Integer data types
+// by Kenji Rikitake, JJ1BDX
+//
+// An ADIF Number is a sequence of one or more digits
+// optionally preceded by a minus sign and optionally including
+// a single decimal point (e.g., -12.5, 14.074, .5, 3.).
+// Plus signs, exponents, hexadecimal, NaN, and Inf are not Numbers,
+// though strconv.ParseFloat accepts them.
+// An ADIF Integer is a Number without a decimal point.
+
+package adifio
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+)
+
+var ErrInvalidNumber = errors.New("invalid ADIF Number")
+
+var regNumber = regexp.MustCompile(`^-?([0-9]+\.?[0-9]*|\.[0-9]+)$`)
+var regInteger = regexp.MustCompile(`^-?[0-9]+$`)
+
+// Check if the value is an ADIF Number
+func IsNumber(value string) bool {
+	return regNumber.MatchString(value)
+}
+
+// Check if the value is an ADIF Integer
+func IsInteger(value string) bool {
+	return regInteger.MatchString(value)
+}
+
+// Parse an ADIF Number
+// Returns ErrInvalidNumber if the value is not an ADIF Number
+func ParseNumber(value string) (float64, error) {
+	if !IsNumber(value) {
+		return 0, ErrInvalidNumber
+	}
+	return strconv.ParseFloat(value, 64)
+}
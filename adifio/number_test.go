@@ -0,0 +1,52 @@
+package adifio
+
+import (
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+	}{
+		{"0", 0},
+		{"14.074", 14.074},
+		{"-12.5", -12.5},
+		{".5", 0.5},
+		{"-.5", -0.5},
+		{"3.", 3},
+		{"007", 7},
+	}
+	for _, tt := range tests {
+		got, err := ParseNumber(tt.value)
+		if got != tt.want || err != nil {
+			t.Errorf("ParseNumber(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+		if !IsNumber(tt.value) {
+			t.Errorf("IsNumber(%q) = false", tt.value)
+		}
+	}
+	for _, value := range []string{"", "-", ".", "-.", "+1", "1e3", "1E3",
+		"0x10", "NaN", "nan", "Inf", "-Inf", "infinity", "1.2.3", " 1", "1 ",
+		"1,000", "1_000", "--1"} {
+		if got, err := ParseNumber(value); err != ErrInvalidNumber {
+			t.Errorf("ParseNumber(%q) = %v, %v, want %v", value, got, err, ErrInvalidNumber)
+		}
+		if IsNumber(value) {
+			t.Errorf("IsNumber(%q) = true", value)
+		}
+	}
+}
+
+func TestIsInteger(t *testing.T) {
+	for _, value := range []string{"0", "123", "-45", "007"} {
+		if !IsInteger(value) {
+			t.Errorf("IsInteger(%q) = false", value)
+		}
+	}
+	for _, value := range []string{"", "-", "+1", "1.", "1.5", "1e3", "0x10", " 1"} {
+		if IsInteger(value) {
+			t.Errorf("IsInteger(%q) = true", value)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// adifio: USERDEF field declarations and validation
+// by Kenji Rikitake, JJ1BDX
+//
+// User-defined fields are declared in the header as USERDEFn fields:
+//
+//	<USERDEF1:3:N>EPC
+//	<USERDEF2:19:E>SweaterSize,{S,M,L}
+//	<USERDEF3:15:N>ShoeSize,{5:20}
+//
+// The data type indicator is the type of the field values,
+// followed by an optional enumeration or range in braces.
+// The following types and restrictions are validated:
+//
+//	B: Boolean (Y or N)
+//	N: Number as decimal digits (within the range if specified)
+//	D: Date (YYYYMMDD)
+//	T: Time (HHMM or HHMMSS)
+//	E: Enumeration (one of the enumeration, case insensitive)
+//	L: Location (XDDD MM.MMM)
+//	other types: no validation except the enumeration
+//
+// The declarations are renumbered from 1 in the output header.
+
+package adifio
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidUserdef = errors.New("invalid USERDEF declaration")
+var ErrInvalidUserdefValue = errors.New("invalid USERDEF field value")
+
+var regUserdefName = regexp.MustCompile(`^userdef[0-9]+$`)
+var regLocation = regexp.MustCompile(`^[NSEWnsew][0-9]{3} [0-9]{2}\.[0-9]{3}$`)
+
+// USERDEF field declaration
+type Userdef struct {
+	// Field name in lowercase
+	Name string
+	// Data type indicator in uppercase (empty if none)
+	Type string
+	// Enumeration (empty if none)
+	Enum []string
+	// Range (valid if HasRange is true)
+	HasRange bool
+	Min      float64
+	Max      float64
+}
+
+// Parse a USERDEFn header field
+func ParseUserdef(f HeaderField) (Userdef, error) {
+	u := Userdef{Type: strings.ToUpper(f.Type)}
+	if !regUserdefName.MatchString(f.Name) {
+		return u, fmt.Errorf("%w: %s", ErrInvalidUserdef, f.Name)
+	}
+	name, restriction, found := strings.Cut(f.Value, ",")
+	u.Name = strings.ToLower(strings.TrimSpace(name))
+	if u.Name == "" || strings.ContainsAny(u.Name, "<>{}:, ") {
+		return u, fmt.Errorf("%w: %s: %q", ErrInvalidUserdef, f.Name, f.Value)
+	}
+	if !found {
+		return u, nil
+	}
+	restriction = strings.TrimSpace(restriction)
+	if !strings.HasPrefix(restriction, "{") || !strings.HasSuffix(restriction, "}") {
+		return u, fmt.Errorf("%w: %s: %q", ErrInvalidUserdef, f.Name, f.Value)
+	}
+	restriction = restriction[1 : len(restriction)-1]
+	if low, high, isrange := strings.Cut(restriction, ":"); isrange {
+		var errlow, errhigh error
+		u.Min, errlow = ParseNumber(strings.TrimSpace(low))
+		u.Max, errhigh = ParseNumber(strings.TrimSpace(high))
+		if errlow != nil || errhigh != nil || u.Min > u.Max {
+			return u, fmt.Errorf("%w: %s: %q", ErrInvalidUserdef, f.Name, f.Value)
+		}
+		u.HasRange = true
+		return u, nil
+	}
+	for _, item := range strings.Split(restriction, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			return u, fmt.Errorf("%w: %s: %q", ErrInvalidUserdef, f.Name, f.Value)
+		}
+		u.Enum = append(u.Enum, item)
+	}
+	return u, nil
+}
+
+// Return the value of the USERDEFn header field
+func (u Userdef) String() string {
+	if u.HasRange {
+		return fmt.Sprintf("%s,{%s:%s}", u.Name,
+			strconv.FormatFloat(u.Min, 'f', -1, 64),
+			strconv.FormatFloat(u.Max, 'f', -1, 64))
+	}
+	if len(u.Enum) > 0 {
+		return fmt.Sprintf("%s,{%s}", u.Name, strings.Join(u.Enum, ","))
+	}
+	return u.Name
+}
+
+// Validate a value of the field by the declaration
+// Empty values are valid
+func (u Userdef) Validate(value string) error {
+	if value == "" {
+		return nil
+	}
+	invalid := fmt.Errorf("%w: %s: %q", ErrInvalidUserdefValue, u.Name, value)
+	switch u.Type {
+	case "B":
+		if !strings.EqualFold(value, "Y") && !strings.EqualFold(value, "N") {
+			return invalid
+		}
+	case "N":
+		n, err := ParseNumber(value)
+		if err != nil || (u.HasRange && (n < u.Min || n > u.Max)) {
+			return invalid
+		}
+	case "D":
+		if _, err := time.Parse("20060102", value); err != nil || len(value) != 8 {
+			return invalid
+		}
+	case "T":
+		t := value
+		if len(t) == 4 {
+			t = t + "00"
+		}
+		if _, err := time.Parse("150405", t); err != nil || len(t) != 6 {
+			return invalid
+		}
+	case "L":
+		if !regLocation.MatchString(value) {
+			return invalid
+		}
+	}
+	if len(u.Enum) > 0 {
+		for _, item := range u.Enum {
+			if strings.EqualFold(item, value) {
+				return nil
+			}
+		}
+		return invalid
+	}
+	return nil
+}
+
+// Obtain the USERDEF declarations of the header
+// Invalid declarations are skipped and reported by the error
+func (h *Header) Userdefs() ([]Userdef, error) {
+	userdefs := []Userdef{}
+	var errs []error
+	for _, f := range h.Fields {
+		if !strings.HasPrefix(f.Name, "userdef") {
+			continue
+		}
+		u, err := ParseUserdef(f)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		userdefs = append(userdefs, u)
+	}
+	return userdefs, errors.Join(errs...)
+}
+
+// Obtain the USERDEF declaration of the field
+// Returns false if the field is not declared
+func (h *Header) Userdef(name string) (Userdef, bool) {
+	name = strings.ToLower(name)
+	userdefs, _ := h.Userdefs()
+	for _, u := range userdefs {
+		if u.Name == name {
+			return u, true
+		}
+	}
+	return Userdef{}, false
+}
+
+// Obtain the names of the user-defined fields in USERDEFn header fields
+// The names are in lowercase
+func (h *Header) UserdefNames() []string {
+	names := []string{}
+	userdefs, _ := h.Userdefs()
+	for _, u := range userdefs {
+		names = append(names, u.Name)
+	}
+	return names
+}
+
+// Obtain the data type indicators of the user-defined fields
+// by the lowercase field names (e.g., for adifexpr.Parse)
+func (h *Header) UserdefTypes() map[string]string {
+	types := make(map[string]string)
+	userdefs, _ := h.Userdefs()
+	for _, u := range userdefs {
+		types[u.Name] = u.Type
+	}
+	return types
+}
+
+// Renumber USERDEFn header fields from 1 in the order of appearance
+func (h *Header) renumberUserdefs() {
+	n := 0
+	for i, f := range h.Fields {
+		if regUserdefName.MatchString(f.Name) {
+			n++
+			h.Fields[i].Name = "userdef" + strconv.Itoa(n)
+		}
+	}
+}
+
+// Add a USERDEF declaration
+// An existing declaration of the same field name is replaced
+func (h *Header) AddUserdef(u Userdef) {
+	f := HeaderField{Value: u.String(), Type: u.Type}
+	for i, hf := range h.Fields {
+		if old, err := ParseUserdef(hf); err == nil && old.Name == u.Name {
+			f.Name = hf.Name
+			h.Fields[i] = f
+			return
+		}
+	}
+	f.Name = "userdef" + strconv.Itoa(len(h.Fields)+1)
+	h.Fields = append(h.Fields, f)
+	h.renumberUserdefs()
+}
+
+// Remove the USERDEF declaration of the field
+// Returns false if the field is not declared
+func (h *Header) RemoveUserdef(name string) bool {
+	name = strings.ToLower(name)
+	for i, f := range h.Fields {
+		if u, err := ParseUserdef(f); err == nil && u.Name == name {
+			h.Fields = append(h.Fields[:i], h.Fields[i+1:]...)
+			h.renumberUserdefs()
+			return true
+		}
+	}
+	return false
+}
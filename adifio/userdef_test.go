@@ -0,0 +1,206 @@
+package adifio
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseUserdef(t *testing.T) {
+	tests := []struct {
+		field HeaderField
+		want  Userdef
+		str   string
+	}{
+		{HeaderField{Name: "userdef1", Value: "EPC", Type: "n"},
+			Userdef{Name: "epc", Type: "N"}, "epc"},
+		{HeaderField{Name: "userdef2", Value: "SweaterSize,{S,M,L}", Type: "E"},
+			Userdef{Name: "sweatersize", Type: "E", Enum: []string{"S", "M", "L"}},
+			"sweatersize,{S,M,L}"},
+		{HeaderField{Name: "userdef3", Value: "ShoeSize, {5:20.5}", Type: "N"},
+			Userdef{Name: "shoesize", Type: "N", HasRange: true, Min: 5, Max: 20.5},
+			"shoesize,{5:20.5}"},
+		{HeaderField{Name: "userdef4", Value: "Notes2"},
+			Userdef{Name: "notes2"}, "notes2"},
+	}
+	for _, tt := range tests {
+		u, err := ParseUserdef(tt.field)
+		if err != nil {
+			t.Errorf("ParseUserdef(%+v): %v", tt.field, err)
+			continue
+		}
+		if !reflect.DeepEqual(u, tt.want) || u.String() != tt.str {
+			t.Errorf("ParseUserdef(%+v) = %+v, %q, want %+v, %q",
+				tt.field, u, u.String(), tt.want, tt.str)
+		}
+	}
+
+	for _, f := range []HeaderField{
+		{Name: "userdef", Value: "EPC", Type: "N"},
+		{Name: "userdef1", Value: "", Type: "N"},
+		{Name: "userdef1", Value: "EPC RANK", Type: "N"},
+		{Name: "userdef1", Value: "EPC,S,M", Type: "E"},
+		{Name: "userdef1", Value: "EPC,{S,,M}", Type: "E"},
+		{Name: "userdef1", Value: "EPC,{20:5}", Type: "N"},
+		{Name: "userdef1", Value: "EPC,{1e1:20}", Type: "N"},
+		{Name: "userdef1", Value: "EPC,{-Inf:Inf}", Type: "N"},
+	} {
+		if _, err := ParseUserdef(f); !errors.Is(err, ErrInvalidUserdef) {
+			t.Errorf("ParseUserdef(%+v) error = %v, want %v", f, err, ErrInvalidUserdef)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		field   HeaderField
+		valid   []string
+		invalid []string
+	}{
+		{HeaderField{Name: "userdef1", Value: "F", Type: "B"},
+			[]string{"", "Y", "n"}, []string{"T", "yes"}},
+		{HeaderField{Name: "userdef1", Value: "F", Type: "N"},
+			[]string{"", "12", "-3.5", ".5", "1."},
+			[]string{"1e3", "NaN", "Inf", "+1", "0x10", "1,000"}},
+		{HeaderField{Name: "userdef1", Value: "F,{5:20}", Type: "N"},
+			[]string{"5", "12.5", "20"}, []string{"4.99", "21", "NaN"}},
+		{HeaderField{Name: "userdef1", Value: "F", Type: "D"},
+			[]string{"20231125"}, []string{"2023-11-25", "20231131", "202311250"}},
+		{HeaderField{Name: "userdef1", Value: "F", Type: "T"},
+			[]string{"1230", "123045"}, []string{"2400", "12304", "1260"}},
+		{HeaderField{Name: "userdef1", Value: "F,{S,M,L}", Type: "E"},
+			[]string{"S", "m"}, []string{"XL"}},
+		{HeaderField{Name: "userdef1", Value: "F", Type: "L"},
+			[]string{"N035 40.500", "w139 45.000"}, []string{"N35 40.500", "X035 40.500"}},
+		{HeaderField{Name: "userdef1", Value: "F", Type: "S"},
+			[]string{"anything"}, nil},
+	}
+	for _, tt := range tests {
+		u, err := ParseUserdef(tt.field)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, value := range tt.valid {
+			if err := u.Validate(value); err != nil {
+				t.Errorf("%s: Validate(%q): %v", u.Type, value, err)
+			}
+		}
+		for _, value := range tt.invalid {
+			if err := u.Validate(value); !errors.Is(err, ErrInvalidUserdefValue) {
+				t.Errorf("%s: Validate(%q) error = %v, want %v",
+					u.Type, value, err, ErrInvalidUserdefValue)
+			}
+		}
+	}
+}
+
+func TestHeaderUserdefs(t *testing.T) {
+	header, _, err := ReadHeader(strings.NewReader("log <adif_ver:5>3.1.4" +
+		"<userdef1:3:N>EPC<userdef2:4:S>EPC2<userdef3:0:N><userdef4:10:D>ShipmentDt<eoh>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := header.Userdefs(); !errors.Is(err, ErrInvalidUserdef) {
+		t.Errorf("Userdefs() error = %v", err)
+	}
+	if got := header.UserdefNames(); !reflect.DeepEqual(got, []string{"epc", "epc2", "shipmentdt"}) {
+		t.Errorf("UserdefNames() = %q", got)
+	}
+	wanttypes := map[string]string{"epc": "N", "epc2": "S", "shipmentdt": "D"}
+	if got := header.UserdefTypes(); !reflect.DeepEqual(got, wanttypes) {
+		t.Errorf("UserdefTypes() = %v", got)
+	}
+	if u, ok := header.Userdef("EPC2"); !ok || u.Type != "S" {
+		t.Errorf("Userdef(EPC2) = %+v, %v", u, ok)
+	}
+
+	// Replace, add, and remove with renumbering
+	header.AddUserdef(Userdef{Name: "epc2", Type: "N"})
+	header.AddUserdef(Userdef{Name: "rank", Type: "E", Enum: []string{"A", "B"}})
+	if !header.RemoveUserdef("EPC") || header.RemoveUserdef("epc") {
+		t.Errorf("RemoveUserdef(EPC) failed")
+	}
+	want := []HeaderField{
+		{Name: "adif_ver", Value: "3.1.4"},
+		{Name: "userdef1", Value: "epc2", Type: "N"},
+		{Name: "userdef2", Value: "", Type: "N"},
+		{Name: "userdef3", Value: "ShipmentDt", Type: "D"},
+		{Name: "userdef4", Value: "rank,{A,B}", Type: "E"},
+	}
+	if !reflect.DeepEqual(header.Fields, want) {
+		t.Errorf("fields = %+v, want %+v", header.Fields, want)
+	}
+}
+
+func TestIsStandardField(t *testing.T) {
+	for _, name := range []string{"call", "QSO_DATE", "my_gridsquare", "pota_ref"} {
+		if !IsStandardField(name) {
+			t.Errorf("IsStandardField(%q) = false", name)
+		}
+	}
+	for _, name := range []string{"epc", "app_logger_id", "userdef1", ""} {
+		if IsStandardField(name) {
+			t.Errorf("IsStandardField(%q) = true", name)
+		}
+	}
+}
+
+func TestFieldType(t *testing.T) {
+	tests := map[string]string{
+		"freq":       "N",
+		"SRX":        "N",
+		"my_cq_zone": "N",
+		"qso_date":   "D",
+		"time_on":    "T",
+		"call":       "",
+		"epc":        "",
+	}
+	for name, want := range tests {
+		if got := FieldType(name); got != want {
+			t.Errorf("FieldType(%q) = %q, want %q", name, got, want)
+		}
+	}
+	// Typed fields are standard fields
+	for name := range fieldTypes {
+		if !IsStandardField(name) {
+			t.Errorf("%q is not a standard field", name)
+		}
+	}
+}
+
+func TestWriterInvalidUserdef(t *testing.T) {
+	header, _, err := ReadHeader(strings.NewReader(
+		"log <userdef1:9:N>EPC,{1:9}<userdef2:4:B>FLAG<eoh>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output bytes.Buffer
+	writer := NewWriter(&output, header, "test")
+	for _, tt := range []struct {
+		record string
+		valid  bool
+	}{
+		{"<call:4>A1AA<epc:1>5<flag:1>Y<eor>", true},
+		{"<call:4>A1AB<epc:2>10<flag:1>Y<eor>", false},
+		{"<call:4>A1AC<epc:3>NaN<flag:1>X<eor>", false},
+		{"<call:4>A1AD<epc:0><eor>", true},
+	} {
+		err := writer.WriteRecord(parseRecord(t, tt.record))
+		if tt.valid && err != nil {
+			t.Errorf("WriteRecord(%q): %v", tt.record, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidUserdefValue) {
+			t.Errorf("WriteRecord(%q) error = %v, want %v", tt.record, err, ErrInvalidUserdefValue)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// Records with invalid values are also written
+	got := strings.Join(readValues(t, &output, "call"), " ")
+	if got != "A1AA A1AB A1AC A1AD" {
+		t.Errorf("calls = %q", got)
+	}
+}
@@ -0,0 +1,241 @@
+// adifio: ADIF writer passing through the input header
+// by Kenji Rikitake, JJ1BDX
+//
+// The output header is made from the input header as follows:
+//
+//	preamble: the input preamble followed by a processing-history line
+//	  "PROGRAMID CREATED_TIMESTAMP: command arguments..."
+//	  (the comment set by SetComment is used if no input header)
+//	ADIF_VER: kept (3.1.4 if none)
+//	PROGRAMID: the program name
+//	PROGRAMVERSION: the module version of the program
+//	CREATED_TIMESTAMP: the current time in UTC
+//	other fields (e.g., USERDEFn): kept in the order of appearance
+//
+// The records are written by adifparser.ADIFWriter,
+// and the header written by adifparser.ADIFWriter is replaced.
+// Records with invalid USERDEF field values are written
+// and reported by the error of WriteRecord (see userdef.go).
+
+package adifio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jj1bdx/adifparser"
+)
+
+// ADIF version of the output header if no input header
+const DefaultADIFVersion = "3.1.4"
+
+// Header fields updated in the output header
+var updatedHeaderFields = map[string]bool{
+	"programid":         true,
+	"programversion":    true,
+	"created_timestamp": true,
+}
+
+// Writer discarding the header written by adifparser.ADIFWriter
+// and writing the given header instead
+type headerReplacer struct {
+	writer io.Writer
+	// Output header
+	header func() string
+	// Bytes until <eoh> written by adifparser.ADIFWriter
+	buffer bytes.Buffer
+	done   bool
+}
+
+func (r *headerReplacer) Write(p []byte) (int, error) {
+	if r.done {
+		return r.writer.Write(p)
+	}
+	r.buffer.Write(p)
+	index := bytes.Index(bytes.ToLower(r.buffer.Bytes()), []byte("<eoh>"))
+	if index < 0 {
+		return len(p), nil
+	}
+	r.done = true
+	// The output header ends with a newline
+	rest := bytes.TrimLeft(r.buffer.Bytes()[index+len("<eoh>"):], "\r\n")
+	if _, err := io.WriteString(r.writer, r.header()); err != nil {
+		return 0, err
+	}
+	if _, err := r.writer.Write(rest); err != nil {
+		return 0, err
+	}
+	r.buffer.Reset()
+	return len(p), nil
+}
+
+// Write the header if not written yet
+func (r *headerReplacer) finish() error {
+	if r.done {
+		return nil
+	}
+	r.done = true
+	if _, err := io.WriteString(r.writer, r.header()); err != nil {
+		return err
+	}
+	// Output of adifparser.ADIFWriter without <eoh> is not a header
+	_, err := r.writer.Write(r.buffer.Bytes())
+	r.buffer.Reset()
+	return err
+}
+
+// ADIF writer passing through the input header
+type headerWriter struct {
+	writer    adifparser.ADIFWriter
+	replacer  *headerReplacer
+	input     *Header
+	programid string
+	comment   string
+	created   time.Time
+	// USERDEF declarations for validation (nil before the first record)
+	userdefs map[string]Userdef
+}
+
+// Create an ADIF writer passing through the input header
+// input is the header read by ReadHeader, or nil for a fresh header
+func NewWriter(writer io.Writer, input *Header, programid string) adifparser.ADIFWriter {
+	w := &headerWriter{
+		input:     input,
+		programid: programid,
+		created:   time.Now().UTC(),
+	}
+	w.replacer = &headerReplacer{writer: writer, header: w.header}
+	w.writer = adifparser.NewADIFWriter(w.replacer)
+	return w
+}
+
+// Create an ADIF writer appending records to an existing ADIF file
+// written by NewWriter: no header is written
+// input is the header read by ReadHeader, or nil, used as in NewWriter
+func NewAppendWriter(writer io.Writer, input *Header, programid string) adifparser.ADIFWriter {
+	w := NewWriter(writer, input, programid).(*headerWriter)
+	w.replacer.header = func() string { return "" }
+	return w
+}
+
+// Set the comment used as the preamble if no input header
+func (w *headerWriter) SetComment(comment string) error {
+	w.comment = comment
+	return w.writer.SetComment(comment)
+}
+
+// Write the record
+// Returns ErrInvalidUserdefValue after writing the record
+// if a USERDEF field value is invalid for the declaration
+func (w *headerWriter) WriteRecord(record adifparser.ADIFRecord) error {
+	if w.userdefs == nil {
+		w.userdefs = make(map[string]Userdef)
+		if w.input != nil {
+			userdefs, _ := w.input.Userdefs()
+			for _, u := range userdefs {
+				w.userdefs[u.Name] = u
+			}
+		}
+	}
+	var errs []error
+	for _, field := range record.GetFields() {
+		u, exists := w.userdefs[strings.ToLower(field)]
+		if !exists {
+			continue
+		}
+		value, err := record.GetValue(field)
+		if err != nil {
+			return err
+		}
+		if err := u.Validate(value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := w.writer.WriteRecord(record); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+func (w *headerWriter) Flush() error {
+	if err := w.writer.Flush(); err != nil {
+		return err
+	}
+	return w.replacer.finish()
+}
+
+// Module version of the program
+func programVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "" {
+		return "(devel)"
+	}
+	return info.Main.Version
+}
+
+// Command line of the program with quoted arguments if needed
+func commandLine(programid string) string {
+	args := []string{programid}
+	for _, arg := range os.Args[1:] {
+		if arg == "" || strings.ContainsAny(arg, " \t\r\n\"'\\<>") {
+			arg = strconv.Quote(arg)
+		}
+		args = append(args, arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// Format a header field
+func formatHeaderField(f HeaderField) string {
+	if f.Type != "" {
+		return fmt.Sprintf("<%s:%d:%s>%s\n", f.Name, len(f.Value), f.Type, f.Value)
+	}
+	return fmt.Sprintf("<%s:%d>%s\n", f.Name, len(f.Value), f.Value)
+}
+
+// Build the output header
+func (w *headerWriter) header() string {
+	timestamp := w.created.Format("20060102 150405")
+	var b strings.Builder
+
+	// Preamble and the processing-history line
+	preamble := w.comment
+	if w.input != nil && w.input.Exists {
+		preamble = w.input.Preamble
+	}
+	preamble = strings.TrimRight(preamble, " \t\r\n")
+	if preamble != "" {
+		b.WriteString(preamble + "\n")
+	}
+	// "<" is not allowed in the preamble
+	history := strings.ReplaceAll(commandLine(w.programid), "<", "_")
+	fmt.Fprintf(&b, "%s %s: %s\n", w.programid, timestamp, history)
+
+	fields := []HeaderField{}
+	adifver := DefaultADIFVersion
+	if w.input != nil {
+		for _, f := range w.input.Fields {
+			if f.Name == "adif_ver" {
+				adifver = f.Value
+			} else if !updatedHeaderFields[f.Name] {
+				fields = append(fields, f)
+			}
+		}
+	}
+	b.WriteString(formatHeaderField(HeaderField{Name: "adif_ver", Value: adifver}))
+	b.WriteString(formatHeaderField(HeaderField{Name: "programid", Value: w.programid}))
+	b.WriteString(formatHeaderField(HeaderField{Name: "programversion", Value: programVersion()}))
+	b.WriteString(formatHeaderField(HeaderField{Name: "created_timestamp", Value: timestamp}))
+	for _, f := range fields {
+		b.WriteString(formatHeaderField(f))
+	}
+	b.WriteString("<eoh>\n")
+	return b.String()
+}
@@ -0,0 +1,124 @@
+package adifio
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jj1bdx/adifparser"
+)
+
+// Parse a single ADIF record
+func parseRecord(t *testing.T, s string) adifparser.ADIFRecord {
+	t.Helper()
+	record, err := adifparser.NewADIFReader(strings.NewReader(s)).ReadRecord()
+	if err != nil {
+		t.Fatalf("parsing %q: %v", s, err)
+	}
+	return record
+}
+
+// Read the values of the field of the ADIF records
+func readValues(t *testing.T, r io.Reader, field string) []string {
+	t.Helper()
+	values := []string{}
+	reader := adifparser.NewADIFReader(r)
+	for {
+		record, err := reader.ReadRecord()
+		if err == io.EOF {
+			return values
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, _ := record.GetValue(field)
+		values = append(values, value)
+	}
+}
+
+func TestNewAppendWriter(t *testing.T) {
+	var output bytes.Buffer
+	writer := NewWriter(&output, nil, "test")
+	if err := writer.WriteRecord(parseRecord(t, "<call:4>A1AA<eor>")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	for _, call := range []string{"A1AB", "A1AC"} {
+		writer = NewAppendWriter(&output, nil, "test")
+		if err := writer.SetComment("test\n"); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.WriteRecord(parseRecord(t, "<call:4>"+call+"<eor>")); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Flushing without records writes nothing
+	if err := NewAppendWriter(&output, nil, "test").Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := strings.Count(strings.ToLower(output.String()), "<eoh>"); n != 1 {
+		t.Errorf("%d headers in %q", n, output.String())
+	}
+	got := strings.Join(readValues(t, &output, "call"), " ")
+	if got != "A1AA A1AB A1AC" {
+		t.Errorf("calls = %q", got)
+	}
+}
+
+func TestNewWriter(t *testing.T) {
+	input := "Exported log\n<ADIF_VER:5>3.1.2 <programid:6>Logger <programversion:3>1.0\n" +
+		"<USERDEF1:8:N>EPC_RANK <created_timestamp:15>20001231 235959\n<eoh>\n"
+	header, _, err := ReadHeader(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []*Header{header, nil} {
+		var output bytes.Buffer
+		writer := NewWriter(&output, h, "test")
+		if err := writer.SetComment("Fresh log"); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.WriteRecord(parseRecord(t, "<call:4>A1AA<eor>")); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		written, reader, err := ReadHeader(&output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(written.Preamble, "\n")
+		preamble, adifver, userdef := "Fresh log", "3.1.4", ""
+		if h != nil {
+			preamble, adifver, userdef = "Exported log", "3.1.2", "EPC_RANK"
+		}
+		if len(lines) != 3 || lines[0] != preamble || !strings.HasPrefix(lines[1], "test ") {
+			t.Errorf("preamble = %q", written.Preamble)
+		}
+		for field, want := range map[string]string{
+			"adif_ver": adifver, "programid": "test", "userdef1": userdef} {
+			if value, _ := written.GetValue(field); value != want {
+				t.Errorf("%s = %q, want %q", field, value, want)
+			}
+		}
+		if value, _ := written.GetValue("created_timestamp"); value == "20001231 235959" ||
+			!strings.HasPrefix(lines[1], "test "+value+": ") {
+			t.Errorf("created_timestamp = %q, history = %q", value, lines[1])
+		}
+		if value, _ := written.GetValue("programversion"); value == "1.0" {
+			t.Errorf("programversion not updated")
+		}
+		if got := readValues(t, reader, "call"); len(got) != 1 || got[0] != "A1AA" {
+			t.Errorf("calls = %q", got)
+		}
+	}
+}
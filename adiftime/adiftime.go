@@ -0,0 +1,38 @@
+// adiftime: ADIF date and time handling
+// by Kenji Rikitake, JJ1BDX
+//
+// ADIF dates are in YYYYMMDD, and times are in HHMM or HHMMSS.
+// The time of a record is determined by QSO_DATE and TIME_ON in UTC.
+
+package adiftime
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jj1bdx/adifparser"
+)
+
+// Parse ADIF date (YYYYMMDD) and time (HHMM or HHMMSS) in the location
+func ParseDateTime(adifdate, adiftime string, loc *time.Location) (time.Time, error) {
+	switch len(adiftime) {
+	case 4:
+		return time.ParseInLocation("200601021504", adifdate+adiftime, loc)
+	case 6:
+		return time.ParseInLocation("20060102150405", adifdate+adiftime, loc)
+	}
+	return time.Time{}, fmt.Errorf("invalid ADIF time: %s", adiftime)
+}
+
+// Obtain the record time from qso_date and time_on in UTC
+func RecordTime(record adifparser.ADIFRecord) (time.Time, error) {
+	adifdate, err := record.GetValue("qso_date")
+	if err != nil {
+		return time.Time{}, err
+	}
+	adiftime, err := record.GetValue("time_on")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ParseDateTime(adifdate, adiftime, time.UTC)
+}
@@ -0,0 +1,75 @@
+package adiftime
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jj1bdx/adifparser"
+)
+
+func TestParseDateTime(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	tests := []struct {
+		date string
+		time string
+		loc  *time.Location
+		want time.Time
+	}{
+		{"20231125", "0830", time.UTC, time.Date(2023, 11, 25, 8, 30, 0, 0, time.UTC)},
+		{"20231125", "083012", time.UTC, time.Date(2023, 11, 25, 8, 30, 12, 0, time.UTC)},
+		{"20231125", "0830", tokyo, time.Date(2023, 11, 24, 23, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseDateTime(tt.date, tt.time, tt.loc)
+		if err != nil {
+			t.Errorf("ParseDateTime(%q, %q): %v", tt.date, tt.time, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseDateTime(%q, %q) = %v, want %v", tt.date, tt.time, got, tt.want)
+		}
+	}
+	invalid := []struct{ date, time string }{
+		{"20231125", "083"},
+		{"20231125", "08301"},
+		{"20231125", ""},
+		{"2023112", "0830"},
+		{"20230230", "0830"},
+		{"20231125", "2460"},
+		{"2023-11-25", "0830"},
+	}
+	for _, tt := range invalid {
+		if _, err := ParseDateTime(tt.date, tt.time, time.UTC); err == nil {
+			t.Errorf("ParseDateTime(%q, %q): no error", tt.date, tt.time)
+		}
+	}
+}
+
+func TestRecordTime(t *testing.T) {
+	reader := adifparser.NewADIFReader(strings.NewReader(
+		"<qso_date:8>20231125<time_on:6>083012<eor>\n" +
+			"<qso_date:8>20231125<eor>\n" +
+			"<time_on:4>0830<eor>\n"))
+	record, err := reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := RecordTime(record)
+	want := time.Date(2023, 11, 25, 8, 30, 12, 0, time.UTC)
+	if err != nil || !got.Equal(want) || got.Location() != time.UTC {
+		t.Errorf("RecordTime() = %v, %v, want %v", got, err, want)
+	}
+	for i := 0; i < 2; i++ {
+		record, err := reader.ReadRecord()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := RecordTime(record); err == nil {
+			t.Errorf("RecordTime(%s): no error", record.ToString())
+		}
+	}
+}
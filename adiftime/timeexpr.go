@@ -0,0 +1,183 @@
+// adiftime: time expressions for time filtering
+// by Kenji Rikitake, JJ1BDX
+//
+// A time expression represents a time period from start to end,
+// where the end is inclusive (e.g., 23:59:59 for the end of a day).
+// Valid time expressions:
+//
+//	RFC3339 time: 2022-10-11T12:33:45Z (start and end are the same)
+//	date only: 2023-11-25 (from 00:00:00 to 23:59:59)
+//	today, yesterday
+//	this week, last week (weeks begin on Monday)
+//	this month, last month
+//	this year, last year
+//	last N[hdw]: the last N hours, days, or weeks until now
+//	  (e.g., last 7d, last 12h, last 2w)
+//	named contest weekends with an optional year (this year if omitted):
+//	  (e.g., cqww-cw 2023, cqwpx-ssb)
+//
+// Dates, days, weeks, months, and years are in the specified time zone.
+// RFC3339 times have their own offsets, and contest weekends are in UTC.
+
+package adiftime
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidTimeExpr = errors.New("invalid time expression")
+
+// Contest weekend definition
+// The weekend is the nth full weekend (Saturday and Sunday in the month)
+// of the month, or the last full weekend if nth is -1
+type contestWeekend struct {
+	month time.Month
+	nth   int
+	// Start time offset from Saturday 00:00 UTC
+	start time.Duration
+	// Duration of the contest
+	length time.Duration
+}
+
+// Named contest weekends
+var contestWeekends = map[string]contestWeekend{
+	"arrldx-cw":  {time.February, 3, 0, 48 * time.Hour},
+	"arrldx-ssb": {time.March, 1, 0, 48 * time.Hour},
+	"arrl-10m":   {time.December, 2, 0, 48 * time.Hour},
+	"cqwpx-ssb":  {time.March, -1, 0, 48 * time.Hour},
+	"cqwpx-cw":   {time.May, -1, 0, 48 * time.Hour},
+	"cqww-rtty":  {time.September, -1, 0, 48 * time.Hour},
+	"cqww-ssb":   {time.October, -1, 0, 48 * time.Hour},
+	"cqww-cw":    {time.November, -1, 0, 48 * time.Hour},
+	"iaru-hf":    {time.July, 2, 12 * time.Hour, 24 * time.Hour},
+	"wae-cw":     {time.August, 2, 0, 48 * time.Hour},
+	"wae-ssb":    {time.September, 2, 0, 48 * time.Hour},
+}
+
+// Names of the contest weekends in sorted order
+func ContestNames() []string {
+	names := make([]string, 0, len(contestWeekends))
+	for name := range contestWeekends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Obtain the Saturday 00:00 UTC of the contest weekend in the year
+func (c contestWeekend) saturday(year int) time.Time {
+	// Saturdays in the month followed by Sunday in the same month
+	saturdays := []time.Time{}
+	for day := 1; day <= 31; day++ {
+		t := time.Date(year, c.month, day, 0, 0, 0, 0, time.UTC)
+		if t.Month() != c.month {
+			break
+		}
+		if t.Weekday() == time.Saturday && t.AddDate(0, 0, 1).Month() == c.month {
+			saturdays = append(saturdays, t)
+		}
+	}
+	if c.nth < 0 {
+		return saturdays[len(saturdays)-1]
+	}
+	return saturdays[c.nth-1]
+}
+
+// Obtain the start and end time of the day
+func dayPeriod(t time.Time) (time.Time, time.Time) {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return start, start.AddDate(0, 0, 1).Add(-time.Second)
+}
+
+var regLastDuration = regexp.MustCompile(`^last\s+([0-9]+)\s*([hdw])$`)
+var regContest = regexp.MustCompile(`^([a-z0-9-]+)(\s+([0-9]{4}))?$`)
+
+// Parse a time expression into the start and end time
+// now is the current time used for relative expressions
+// loc is the time zone of the dates
+func ParseTimeExpr(expr string, now time.Time, loc *time.Location) (time.Time, time.Time, error) {
+	expr = strings.TrimSpace(expr)
+	now = now.In(loc)
+
+	// RFC3339 time
+	if t, err := time.Parse(time.RFC3339, expr); err == nil {
+		return t.UTC(), t.UTC(), nil
+	}
+	// Date only
+	if t, err := time.ParseInLocation("2006-01-02", expr, loc); err == nil {
+		start, end := dayPeriod(t)
+		return start, end, nil
+	}
+
+	expr = strings.ToLower(expr)
+
+	today, _ := dayPeriod(now)
+	switch expr {
+	case "today":
+		start, end := dayPeriod(now)
+		return start, end, nil
+	case "yesterday":
+		start, end := dayPeriod(now.AddDate(0, 0, -1))
+		return start, end, nil
+	case "this week", "last week":
+		// Weeks begin on Monday
+		offset := (int(today.Weekday()) + 6) % 7
+		start := today.AddDate(0, 0, -offset)
+		if expr == "last week" {
+			start = start.AddDate(0, 0, -7)
+		}
+		return start, start.AddDate(0, 0, 7).Add(-time.Second), nil
+	case "this month", "last month":
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+		if expr == "last month" {
+			start = start.AddDate(0, -1, 0)
+		}
+		return start, start.AddDate(0, 1, 0).Add(-time.Second), nil
+	case "this year", "last year":
+		start := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, loc)
+		if expr == "last year" {
+			start = start.AddDate(-1, 0, 0)
+		}
+		return start, start.AddDate(1, 0, 0).Add(-time.Second), nil
+	}
+
+	// last N[hdw]
+	if match := regLastDuration.FindStringSubmatch(expr); match != nil {
+		n, err := strconv.Atoi(match[1])
+		if err != nil {
+			return now, now, fmt.Errorf("%w: %s", ErrInvalidTimeExpr, expr)
+		}
+		var unit time.Duration
+		switch match[2] {
+		case "h":
+			unit = time.Hour
+		case "d":
+			unit = 24 * time.Hour
+		case "w":
+			unit = 7 * 24 * time.Hour
+		}
+		return now.Add(-time.Duration(n) * unit), now, nil
+	}
+
+	// Named contest weekends
+	if match := regContest.FindStringSubmatch(expr); match != nil {
+		contest, exists := contestWeekends[match[1]]
+		if exists {
+			year := now.Year()
+			if match[3] != "" {
+				year, _ = strconv.Atoi(match[3])
+			}
+			start := contest.saturday(year).Add(contest.start)
+			end := start.Add(contest.length).Add(-time.Second)
+			return start, end, nil
+		}
+	}
+
+	return now, now, fmt.Errorf("%w: %s", ErrInvalidTimeExpr, expr)
+}
@@ -0,0 +1,97 @@
+package adiftime
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTimeExpr(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip(err)
+	}
+	// Wednesday
+	now := time.Date(2023, time.November, 15, 10, 30, 0, 0, time.UTC)
+	utc := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		expr  string
+		loc   *time.Location
+		start string
+		end   string
+	}{
+		{"2022-10-11T12:33:45Z", time.UTC, "2022-10-11T12:33:45Z", "2022-10-11T12:33:45Z"},
+		{"2022-10-11T21:33:45+09:00", time.UTC, "2022-10-11T12:33:45Z", "2022-10-11T12:33:45Z"},
+		{"2023-11-25", time.UTC, "2023-11-25T00:00:00Z", "2023-11-25T23:59:59Z"},
+		{" 2023-11-25 ", tokyo, "2023-11-24T15:00:00Z", "2023-11-25T14:59:59Z"},
+		{"today", time.UTC, "2023-11-15T00:00:00Z", "2023-11-15T23:59:59Z"},
+		{"Today", tokyo, "2023-11-14T15:00:00Z", "2023-11-15T14:59:59Z"},
+		{"yesterday", time.UTC, "2023-11-14T00:00:00Z", "2023-11-14T23:59:59Z"},
+		{"this week", time.UTC, "2023-11-13T00:00:00Z", "2023-11-19T23:59:59Z"},
+		{"last week", time.UTC, "2023-11-06T00:00:00Z", "2023-11-12T23:59:59Z"},
+		{"this month", time.UTC, "2023-11-01T00:00:00Z", "2023-11-30T23:59:59Z"},
+		{"last month", time.UTC, "2023-10-01T00:00:00Z", "2023-10-31T23:59:59Z"},
+		{"last month", tokyo, "2023-09-30T15:00:00Z", "2023-10-31T14:59:59Z"},
+		{"this year", time.UTC, "2023-01-01T00:00:00Z", "2023-12-31T23:59:59Z"},
+		{"last year", time.UTC, "2022-01-01T00:00:00Z", "2022-12-31T23:59:59Z"},
+		{"last 12h", time.UTC, "2023-11-14T22:30:00Z", "2023-11-15T10:30:00Z"},
+		{"last 7d", time.UTC, "2023-11-08T10:30:00Z", "2023-11-15T10:30:00Z"},
+		{"last 2 w", tokyo, "2023-11-01T10:30:00Z", "2023-11-15T10:30:00Z"},
+		{"cqww-cw 2023", tokyo, "2023-11-25T00:00:00Z", "2023-11-26T23:59:59Z"},
+		{"CQWW-SSB", time.UTC, "2023-10-28T00:00:00Z", "2023-10-29T23:59:59Z"},
+		{"cqwpx-ssb 2024", time.UTC, "2024-03-30T00:00:00Z", "2024-03-31T23:59:59Z"},
+		{"arrldx-cw 2023", time.UTC, "2023-02-18T00:00:00Z", "2023-02-19T23:59:59Z"},
+		{"iaru-hf 2023", time.UTC, "2023-07-08T12:00:00Z", "2023-07-09T11:59:59Z"},
+		// A weekend beginning on the last day of the month is not full
+		{"arrldx-ssb 2023", time.UTC, "2023-03-04T00:00:00Z", "2023-03-05T23:59:59Z"},
+		{"cqww-rtty 2023", time.UTC, "2023-09-23T00:00:00Z", "2023-09-24T23:59:59Z"},
+	}
+	for _, tt := range tests {
+		start, end, err := ParseTimeExpr(tt.expr, now, tt.loc)
+		if err != nil {
+			t.Errorf("ParseTimeExpr(%q): %v", tt.expr, err)
+			continue
+		}
+		if !start.Equal(utc(tt.start)) || !end.Equal(utc(tt.end)) {
+			t.Errorf("ParseTimeExpr(%q, %v) = %v, %v, want %s, %s", tt.expr, tt.loc,
+				start.UTC(), end.UTC(), tt.start, tt.end)
+		}
+	}
+}
+
+func TestParseTimeExprInvalid(t *testing.T) {
+	now := time.Date(2023, time.November, 15, 10, 30, 0, 0, time.UTC)
+	for _, expr := range []string{"", "2023-13-01", "2023/11/25", "tomorrow",
+		"last 7", "last d", "last 7m", "next week", "cqww", "cqww-cw 23",
+		"2022-10-11T12:33:45"} {
+		if _, _, err := ParseTimeExpr(expr, now, time.UTC); !errors.Is(err, ErrInvalidTimeExpr) {
+			t.Errorf("ParseTimeExpr(%q) error = %v, want %v", expr, err, ErrInvalidTimeExpr)
+		}
+	}
+}
+
+func TestContestNames(t *testing.T) {
+	names := ContestNames()
+	if len(names) != len(contestWeekends) {
+		t.Fatalf("ContestNames() has %d names, want %d", len(names), len(contestWeekends))
+	}
+	for i, name := range names {
+		if i > 0 && names[i-1] >= name {
+			t.Errorf("ContestNames() not sorted: %v", names)
+		}
+		if _, _, err := ParseTimeExpr(name+" 2024", time.Now(), time.UTC); err != nil {
+			t.Errorf("ParseTimeExpr(%q): %v", name, err)
+		}
+	}
+	if !reflect.DeepEqual(names[:2], []string{"arrl-10m", "arrldx-cw"}) {
+		t.Errorf("ContestNames() = %v", names)
+	}
+}
@@ -0,0 +1,279 @@
+// callsign: parse amateur radio callsigns
+// by Kenji Rikitake, JJ1BDX
+//
+// A callsign is split into the following parts:
+//
+//	prefix override: location designator (e.g., W1 of W1/JA1ABC or JA1ABC/W1)
+//	base callsign: the part which looks like a callsign (e.g., JA1ABC)
+//	suffixes: operation modifiers and area numbers (e.g., P, MM, AM, QRP, 1)
+//
+// Examples:
+//
+//	JA1ABC/P: base JA1ABC, suffix P
+//	W1/JA1ABC: prefix override W1, base JA1ABC
+//	JA1ABC/KH6: prefix override KH6, base JA1ABC
+//	KH6/JA1ABC/AM: prefix override KH6, base JA1ABC, suffix AM
+//	JA1ABC/2: base JA1ABC, suffix 2
+//
+// The normalized callsign is in the form of PREFIX/BASE/SUFFIXES,
+// such as KH6/JA1ABC/P for JA1ABC/KH6/P.
+
+package callsign
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Maximum length of a callsign (as in Club Log)
+const MaxLength = 16
+
+var ErrInvalidCallsign = errors.New("invalid callsign")
+
+// Parsed callsign
+type Callsign struct {
+	// Prefix override (location designator)
+	Prefix string
+	// Base callsign
+	Base string
+	// Suffixes (operation modifiers and area numbers)
+	Suffixes []string
+}
+
+// Callsign suffixes which are operation modifiers, not location designators
+var modifierSuffixes = map[string]bool{
+	"A":    true, // alternate location
+	"AE":   true, // FCC Rules Part 97.119(f)(3)
+	"AG":   true, // FCC Rules Part 97.119(f)(2)
+	"AM":   true, // aeronautical mobile
+	"B":    true, // beacon
+	"KT":   true, // FCC Rules Part 97.119(f)(1)
+	"LGT":  true, // lighthouse
+	"LH":   true, // lighthouse
+	"M":    true, // mobile
+	"MM":   true, // maritime mobile
+	"P":    true, // portable
+	"QRP":  true, // low power
+	"QRPP": true, // low power
+}
+
+// Prefixes of the callsign series for special-event stations
+var specialEventPrefixes = map[string]bool{
+	"8J": true, // Japan
+	"8N": true, // Japan
+	"GB": true, // United Kingdom
+	"TM": true, // France
+	"II": true, // Italy
+	"IO": true, // Italy
+	"IR": true, // Italy
+}
+
+// Base callsign: one to three letters and digits, a digit,
+// then letters and digits ending with a letter
+var regBaseCall = regexp.MustCompile(`^[A-Z0-9]{1,3}[0-9][A-Z0-9]*[A-Z]$`)
+
+// Callsign characters
+var regCallChars = regexp.MustCompile(`^[A-Z0-9/]+$`)
+
+// Part of a callsign without slashes
+var regCallPart = regexp.MustCompile(`^[A-Z0-9]+$`)
+
+// Prefix part of a base callsign: all but the last letters
+var regBasePrefix = regexp.MustCompile(`^(.*[0-9])[A-Z]*$`)
+
+// Part which looks like a complete callsign
+// with two or more letters after the last digit (e.g., JA1XYZ)
+var regFullCall = regexp.MustCompile(`^[A-Z0-9]*[0-9][A-Z]{2,}$`)
+
+// Base callsign with two or more consecutive digits,
+// or four or more letters after the last digit
+var regSpecialBase = regexp.MustCompile(`[0-9]{2,}|[0-9][A-Z]{4,}$`)
+
+// Convert a callsign into uppercase without spaces
+func clean(call string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(call), ""))
+}
+
+// Parse a callsign
+// Returns ErrInvalidCallsign if the callsign contains
+// invalid characters, empty parts, two or more location designators,
+// or a location designator which looks like a complete callsign
+// (e.g., JA1ABC/JA1XYZ)
+func Parse(call string) (Callsign, error) {
+	var c Callsign
+	call = clean(call)
+	if call == "" || len(call) > MaxLength || !regCallChars.MatchString(call) {
+		return c, ErrInvalidCallsign
+	}
+	parts := strings.Split(call, "/")
+	if len(parts) > 4 {
+		return c, ErrInvalidCallsign
+	}
+	for _, p := range parts {
+		if !regCallPart.MatchString(p) {
+			return c, ErrInvalidCallsign
+		}
+	}
+
+	// The longest part which looks like a callsign is the base callsign
+	// If no such part exists, the longest non-modifier part is
+	baseindex := -1
+	for i, p := range parts {
+		if regBaseCall.MatchString(p) &&
+			(baseindex < 0 || len(p) > len(parts[baseindex])) {
+			baseindex = i
+		}
+	}
+	if baseindex < 0 {
+		for i, p := range parts {
+			if modifierSuffixes[p] {
+				continue
+			}
+			if baseindex < 0 || len(p) > len(parts[baseindex]) {
+				baseindex = i
+			}
+		}
+	}
+	if baseindex < 0 {
+		return c, ErrInvalidCallsign
+	}
+	c.Base = parts[baseindex]
+
+	// Parts before the base callsign are prefix overrides
+	// Parts after the base callsign are suffixes,
+	// or prefix overrides if they are not modifiers or area numbers
+	for i, p := range parts {
+		if i == baseindex {
+			continue
+		}
+		_, err := strconv.Atoi(p)
+		isdigit := err == nil && len(p) == 1
+		if i > baseindex && (modifierSuffixes[p] || isdigit) {
+			c.Suffixes = append(c.Suffixes, p)
+			continue
+		}
+		if c.Prefix != "" || regFullCall.MatchString(p) {
+			return c, ErrInvalidCallsign
+		}
+		c.Prefix = p
+	}
+	return c, nil
+}
+
+// Return the normalized callsign string in the form of PREFIX/BASE/SUFFIXES
+func (c Callsign) String() string {
+	parts := []string{}
+	if c.Prefix != "" {
+		parts = append(parts, c.Prefix)
+	}
+	parts = append(parts, c.Base)
+	parts = append(parts, c.Suffixes...)
+	return strings.Join(parts, "/")
+}
+
+// Return true if the callsign has the suffix
+func (c Callsign) HasSuffix(suffix string) bool {
+	for _, s := range c.Suffixes {
+		if s == suffix {
+			return true
+		}
+	}
+	return false
+}
+
+// Return true if the callsign is maritime mobile (/MM)
+// Note: /MM does not count for DXCC
+func (c Callsign) IsMaritimeMobile() bool {
+	return c.HasSuffix("MM")
+}
+
+// Return true if the callsign is aeronautical mobile (/AM)
+// Note: /AM does not count for DXCC
+func (c Callsign) IsAeronauticalMobile() bool {
+	return c.HasSuffix("AM")
+}
+
+// Return true if the base callsign looks like a special-event callsign
+// Heuristics:
+//
+//	no digit in the base callsign (e.g., RAEM)
+//	two or more consecutive digits (e.g., HG19ABC, VK100ANZAC)
+//	four or more letters after the digit (e.g., DA0WRTC)
+//	special-event prefix series (e.g., GB, TM, 8J)
+func (c Callsign) IsSpecialEvent() bool {
+	if !strings.ContainsAny(c.Base, "0123456789") {
+		return true
+	}
+	// Skip the digit of letter-digit prefixes (e.g., A6 of A61AB)
+	base := c.Base
+	if len(base) >= 2 && base[0] >= 'A' && base[0] <= 'Z' &&
+		base[1] >= '0' && base[1] <= '9' {
+		base = base[2:]
+	}
+	if regSpecialBase.MatchString(base) {
+		return true
+	}
+	if len(c.Base) >= 2 && specialEventPrefixes[c.Base[0:2]] {
+		return true
+	}
+	return false
+}
+
+// Obtain WPX prefix with the CQ WPX Contest rules
+// Examples:
+//
+//	N8BJQ -> N8, HG19ABC -> HG19, RAEM -> RA0
+//	PA/N8BJQ -> PA0, N8BJQ/KH6 -> KH6, N8BJQ/1 -> N1, N8BJQ/P -> N8
+//	HG19ABC/5 -> HG5
+func (c Callsign) WpxPrefix() string {
+	// Prefix override is the prefix
+	// Designators without numbers end with "0"
+	if c.Prefix != "" {
+		if strings.ContainsAny(c.Prefix[len(c.Prefix)-1:], "0123456789") {
+			return c.Prefix
+		}
+		return c.Prefix + "0"
+	}
+
+	// Prefix of the base callsign
+	var prefix string
+	if match := regBasePrefix.FindStringSubmatch(c.Base); match != nil {
+		prefix = match[1]
+	} else if len(c.Base) >= 2 {
+		// Calls without numbers: first two letters + "0"
+		prefix = c.Base[0:2] + "0"
+	} else {
+		return ""
+	}
+
+	// Single digit area number replaces the digits of the prefix
+	// Example: N8BJQ/1 -> N1, HG19ABC/5 -> HG5
+	for _, s := range c.Suffixes {
+		if len(s) == 1 && s[0] >= '0' && s[0] <= '9' && len(prefix) >= 2 {
+			return strings.TrimRight(prefix, "0123456789") + s
+		}
+	}
+	return prefix
+}
+
+// Normalize a callsign
+// Returns the uppercase callsign without spaces as is if invalid
+func Normalize(call string) string {
+	c, err := Parse(call)
+	if err != nil {
+		return clean(call)
+	}
+	return c.String()
+}
+
+// Obtain WPX prefix of a callsign
+// Returns empty string if the callsign is invalid
+func WpxPrefix(call string) string {
+	c, err := Parse(call)
+	if err != nil {
+		return ""
+	}
+	return c.WpxPrefix()
+}
@@ -0,0 +1,224 @@
+package callsign
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		call     string
+		prefix   string
+		base     string
+		suffixes []string
+	}{
+		// Plain callsigns
+		{"JA1ABC", "", "JA1ABC", nil},
+		{"ja1abc", "", "JA1ABC", nil},
+		{" JA1 ABC ", "", "JA1ABC", nil},
+		{"N8BJQ", "", "N8BJQ", nil},
+		{"A61AB", "", "A61AB", nil},
+		{"4U1UN", "", "4U1UN", nil},
+		{"3D2AG", "", "3D2AG", nil},
+		{"HG19ABC", "", "HG19ABC", nil},
+		{"VK100ANZAC", "", "VK100ANZAC", nil},
+		{"RAEM", "", "RAEM", nil},
+		// Prefix overrides
+		{"W1/JA1ABC", "W1", "JA1ABC", nil},
+		{"JA1ABC/W1", "W1", "JA1ABC", nil},
+		{"JA1ABC/KH6", "KH6", "JA1ABC", nil},
+		{"KH6/JA1ABC", "KH6", "JA1ABC", nil},
+		{"PA/N8BJQ", "PA", "N8BJQ", nil},
+		{"VP2E/K1ABC", "VP2E", "K1ABC", nil},
+		{"F/DL1ABC", "F", "DL1ABC", nil},
+		// Suffixes
+		{"JA1ABC/P", "", "JA1ABC", []string{"P"}},
+		{"JA1ABC/M", "", "JA1ABC", []string{"M"}},
+		{"JA1ABC/MM", "", "JA1ABC", []string{"MM"}},
+		{"JA1ABC/AM", "", "JA1ABC", []string{"AM"}},
+		{"JA1ABC/QRP", "", "JA1ABC", []string{"QRP"}},
+		{"K1ABC/AG", "", "K1ABC", []string{"AG"}},
+		{"JA1ABC/2", "", "JA1ABC", []string{"2"}},
+		{"JA1ABC/2/P", "", "JA1ABC", []string{"2", "P"}},
+		{"JA1ABC/P/QRP", "", "JA1ABC", []string{"P", "QRP"}},
+		// Prefix overrides and suffixes
+		{"KH6/JA1ABC/AM", "KH6", "JA1ABC", []string{"AM"}},
+		{"JA1ABC/KH6/P", "KH6", "JA1ABC", []string{"P"}},
+		{"W1/JA1ABC/MM", "W1", "JA1ABC", []string{"MM"}},
+		{"DL/JA1ABC/P/QRP", "DL", "JA1ABC", []string{"P", "QRP"}},
+		{"DL1ABC/W1/QRP/P", "W1", "DL1ABC", []string{"QRP", "P"}},
+		// Double prefixes are only valid when one is a designator
+		{"3D2/VK9X/K1ABC", "", "", nil},
+	}
+	for _, tt := range tests {
+		c, err := Parse(tt.call)
+		if tt.base == "" {
+			if err != ErrInvalidCallsign {
+				t.Errorf("Parse(%q) = %+v, %v, want %v", tt.call, c, err, ErrInvalidCallsign)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.call, err)
+			continue
+		}
+		if c.Prefix != tt.prefix || c.Base != tt.base ||
+			!reflect.DeepEqual(c.Suffixes, tt.suffixes) {
+			t.Errorf("Parse(%q) = %q, %q, %q, want %q, %q, %q", tt.call,
+				c.Prefix, c.Base, c.Suffixes, tt.prefix, tt.base, tt.suffixes)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, call := range []string{
+		"",
+		"   ",
+		"/",
+		"JA1ABC/",
+		"/JA1ABC",
+		"JA1ABC//P",
+		"JA1-ABC",
+		"JA1ABC.",
+		"JÄ1ABC",
+		"JA1ABCDEFGHIJKLMN", // longer than MaxLength
+		"A/B/C/D/E",         // too many parts
+		"P",
+		"P/MM",
+		"W1/JA1ABC/KH6", // two designators
+		"JA1ABC/JA1XYZ", // call/call
+		"JA1XYZ/JA1ABC", // call/call
+		"K1ABC/DL1ABCD", // call/call with different lengths
+	} {
+		if c, err := Parse(call); err != ErrInvalidCallsign {
+			t.Errorf("Parse(%q) = %+v, %v, want %v", call, c, err, ErrInvalidCallsign)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		call string
+		want string
+	}{
+		{"ja1abc", "JA1ABC"},
+		{"JA1ABC/KH6/P", "KH6/JA1ABC/P"},
+		{"JA1ABC/W1", "W1/JA1ABC"},
+		{"kh6/ja1abc/am", "KH6/JA1ABC/AM"},
+		{"JA1ABC/2/P", "JA1ABC/2/P"},
+		// Invalid callsigns are returned in uppercase as is
+		{"ja1-abc ", "JA1-ABC"},
+		{"JA1ABC/JA1XYZ", "JA1ABC/JA1XYZ"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.call); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.call, got, tt.want)
+		}
+	}
+}
+
+func TestMobile(t *testing.T) {
+	tests := []struct {
+		call string
+		mm   bool
+		am   bool
+	}{
+		{"JA1ABC", false, false},
+		{"JA1ABC/P", false, false},
+		{"JA1ABC/M", false, false},
+		{"JA1ABC/MM", true, false},
+		{"JA1ABC/AM", false, true},
+		{"W1/JA1ABC/MM", true, false},
+		{"KH6/JA1ABC/AM", false, true},
+		// MM as a prefix is not maritime mobile
+		{"MM/JA1ABC", false, false},
+	}
+	for _, tt := range tests {
+		c, err := Parse(tt.call)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.call, err)
+			continue
+		}
+		if c.IsMaritimeMobile() != tt.mm || c.IsAeronauticalMobile() != tt.am {
+			t.Errorf("%q: maritime %v, aeronautical %v, want %v, %v", tt.call,
+				c.IsMaritimeMobile(), c.IsAeronauticalMobile(), tt.mm, tt.am)
+		}
+	}
+}
+
+func TestIsSpecialEvent(t *testing.T) {
+	tests := []struct {
+		call string
+		want bool
+	}{
+		{"JA1ABC", false},
+		{"N8BJQ", false},
+		{"A61AB", false},
+		{"K1A", false},
+		{"W1/JA1ABC/P", false},
+		{"RAEM", true},
+		{"HG19ABC", true},
+		{"VK100ANZAC", true},
+		{"DA0WRTC", true},
+		{"GB2RS", true},
+		{"TM5ABC", true},
+		{"8J1RL", true},
+		{"II2ABC", true},
+		{"W1/HG19ABC", true},
+	}
+	for _, tt := range tests {
+		c, err := Parse(tt.call)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.call, err)
+			continue
+		}
+		if got := c.IsSpecialEvent(); got != tt.want {
+			t.Errorf("IsSpecialEvent(%q) = %v, want %v", tt.call, got, tt.want)
+		}
+	}
+}
+
+func TestWpxPrefix(t *testing.T) {
+	tests := []struct {
+		call string
+		want string
+	}{
+		// Examples of the CQ WPX Contest rules
+		{"N8BJQ", "N8"},
+		{"WN5N", "WN5"},
+		{"OH2BH", "OH2"},
+		{"JA1ABC", "JA1"},
+		{"A61AB", "A61"},
+		{"4U1UN", "4U1"},
+		{"HG19ABC", "HG19"},
+		{"VK100ANZAC", "VK100"},
+		{"RAEM", "RA0"},
+		{"PA/N8BJQ", "PA0"},
+		{"N8BJQ/KH6", "KH6"},
+		{"KH6/N8BJQ", "KH6"},
+		{"3D2/N8BJQ", "3D2"},
+		{"N8BJQ/1", "N1"},
+		{"N8BJQ/P", "N8"},
+		{"N8BJQ/MM", "N8"},
+		{"N8BJQ/AM", "N8"},
+		{"N8BJQ/QRP", "N8"},
+		// Area numbers replace all the digits of the prefix
+		{"HG19ABC/5", "HG5"},
+		{"VK100ANZAC/3", "VK3"},
+		{"A61AB/2", "A2"},
+		{"JA1ABC/3/P", "JA3"},
+		// Prefix overrides take precedence over area numbers
+		{"W1/JA1ABC/3", "W1"},
+		{"F/DL1ABC/P", "F0"},
+		// Invalid callsigns
+		{"", ""},
+		{"JA1-ABC", ""},
+		{"JA1ABC/JA1XYZ", ""},
+		{"W1/JA1ABC/KH6", ""},
+	}
+	for _, tt := range tests {
+		if got := WpxPrefix(tt.call); got != tt.want {
+			t.Errorf("WpxPrefix(%q) = %q, want %q", tt.call, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// dxcc: DXCC entity information
+// by Kenji Rikitake, JJ1BDX
+//
+// DXCC entity names, main prefixes, and continents are resolved
+// from the ADIF DXCC entity codes with the Club Log database (cty.xml)
+// through gocldb.
+// cty.xml is searched in /usr/local/share/dxcc
+// and the directory of the executable (see gocldb).
+// Without cty.xml, all entities are unknown.
+
+package dxcc
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/jj1bdx/gocldb"
+)
+
+var ErrNoDatabase = errors.New("Club Log database cty.xml not available")
+
+// DXCC entity
+type Entity struct {
+	Code    int
+	Prefix  string
+	Name    string
+	Cont    string
+	Deleted bool
+}
+
+// Directories of cty.xml in the search order of gocldb
+func ctyXmlDirs() []string {
+	dirs := []string{"/usr/local/share/dxcc"}
+	if executable, err := os.Executable(); err == nil {
+		dirs = append(dirs, filepath.Dir(executable))
+	}
+	return dirs
+}
+
+// Find cty.xml in the directories
+func findCtyXml(dirs []string) (string, error) {
+	for _, dir := range dirs {
+		filename := filepath.Join(dir, "cty.xml")
+		if _, err := os.Stat(filename); err == nil {
+			return filename, nil
+		}
+	}
+	return "", ErrNoDatabase
+}
+
+// Check that cty.xml is readable as XML within the size limit of gocldb
+func checkCtyXml(filename string) error {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNoDatabase, err)
+	}
+	defer fp.Close()
+	decoder := xml.NewDecoder(io.LimitReader(fp, gocldb.MaxCtyXmlSize))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrNoDatabase, filename, err)
+		}
+	}
+}
+
+// Load the Club Log database
+// Must be called before Lookup
+// Returns ErrNoDatabase if cty.xml is not found or not readable,
+// since gocldb aborts the program for such errors
+func Load() error {
+	filename, err := findCtyXml(ctyXmlDirs())
+	if err != nil {
+		return err
+	}
+	if err := checkCtyXml(filename); err != nil {
+		return err
+	}
+	gocldb.LoadCtyXml()
+	// Disable debug mode logging of gocldb
+	gocldb.DebugLogger.SetOutput(io.Discard)
+	return nil
+}
+
+// Look up the DXCC entity of the ADIF DXCC entity code
+// Returns false for unknown entities
+// with "(UNKNOWN)" as the name and "?" as the prefix and the continent
+func Lookup(code int) (Entity, bool) {
+	entity := Entity{Code: code}
+	cldentity, exists := gocldb.CLDMapEntityByAdif[uint16(code)]
+	if code > 0 && code <= 0xffff && exists {
+		entity.Prefix = cldentity.Prefix
+		entity.Name = cldentity.Name
+		entity.Cont = cldentity.Cont
+		entity.Deleted = cldentity.Deleted
+		return entity, true
+	}
+	entity.Prefix = "?"
+	entity.Name = "(UNKNOWN)"
+	entity.Cont = "?"
+	return entity, false
+}
+
+// Describe the DXCC entity of the code
+// as the code, the prefix, and the name (e.g., "339 JA JAPAN")
+// Returns only the code for unknown entities
+func Describe(code int) string {
+	entity, known := Lookup(code)
+	if !known {
+		return strconv.Itoa(code)
+	}
+	deleted := ""
+	if entity.Deleted {
+		deleted = " (DELETED)"
+	}
+	return fmt.Sprintf("%d %s %s%s", code, entity.Prefix, entity.Name, deleted)
+}
@@ -0,0 +1,76 @@
+package dxcc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jj1bdx/gocldb"
+)
+
+func TestLookup(t *testing.T) {
+	gocldb.CLDMapEntityByAdif[339] = gocldb.CLDEntityByAdif{
+		Name: "JAPAN", Prefix: "JA", Cont: "AS"}
+	gocldb.CLDMapEntityByAdif[81] = gocldb.CLDEntityByAdif{
+		Name: "GERMANY", Prefix: "DL", Cont: "EU", Deleted: true}
+	unknown := func(code int) Entity {
+		return Entity{Code: code, Prefix: "?", Name: "(UNKNOWN)", Cont: "?"}
+	}
+	tests := []struct {
+		code  int
+		want  Entity
+		known bool
+	}{
+		{339, Entity{339, "JA", "JAPAN", "AS", false}, true},
+		{81, Entity{81, "DL", "GERMANY", "EU", true}, true},
+		{0, unknown(0), false},
+		{999, unknown(999), false},
+		{-1, unknown(-1), false},
+		// Must not wrap around to 339
+		{339 + 0x10000, unknown(339 + 0x10000), false},
+	}
+	for _, tt := range tests {
+		got, known := Lookup(tt.code)
+		if got != tt.want || known != tt.known {
+			t.Errorf("Lookup(%d) = %+v, %v, want %+v, %v",
+				tt.code, got, known, tt.want, tt.known)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := findCtyXml([]string{filepath.Join(dir, "none")}); !errors.Is(err, ErrNoDatabase) {
+		t.Errorf("findCtyXml(no file) error = %v, want %v", err, ErrNoDatabase)
+	}
+	filename := filepath.Join(dir, "cty.xml")
+	if err := os.WriteFile(filename, []byte("<clublog><entities>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := findCtyXml([]string{filepath.Join(dir, "none"), dir}); got != filename || err != nil {
+		t.Errorf("findCtyXml = %q, %v", got, err)
+	}
+	if err := checkCtyXml(filename); !errors.Is(err, ErrNoDatabase) {
+		t.Errorf("checkCtyXml(truncated) error = %v, want %v", err, ErrNoDatabase)
+	}
+	if err := os.WriteFile(filename, []byte("<clublog><entities/></clublog>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkCtyXml(filename); err != nil {
+		t.Errorf("checkCtyXml: %v", err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	gocldb.CLDMapEntityByAdif[339] = gocldb.CLDEntityByAdif{
+		Name: "JAPAN", Prefix: "JA", Cont: "AS"}
+	gocldb.CLDMapEntityByAdif[81] = gocldb.CLDEntityByAdif{
+		Name: "GERMANY", Prefix: "DL", Cont: "EU", Deleted: true}
+	for code, want := range map[int]string{
+		339: "339 JA JAPAN", 81: "81 DL GERMANY (DELETED)", 999: "999"} {
+		if got := Describe(code); got != want {
+			t.Errorf("Describe(%d) = %q, want %q", code, got, want)
+		}
+	}
+}
@@ -1,7 +1,8 @@
 // goadifcsv: pick up specified ADIF fields and output in CSV format
 // by Kenji Rikitake, JJ1BDX
-// Usage: goadifcsv [-f infile] [-o outfile] field_names...
+// Usage: goadifcsv [-f infile] [-o outfile] [-u] field_names...
 // Values of non-existing fields are set to empty strings
+// -u: append the user-defined fields declared by USERDEFn header fields
 
 package main
 
@@ -10,6 +11,7 @@ import (
 	"flag"
 	"fmt"
 	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifio"
 	"io"
 	"os"
 	"strings"
@@ -18,6 +20,7 @@ import (
 func main() {
 	var infile = flag.String("f", "", "input file (stdout in none)")
 	var outfile = flag.String("o", "", "output file (stdout if none)")
+	var userdef = flag.Bool("u", false, "append user-defined fields in the header")
 
 	var fp *os.File
 	var err error
@@ -27,9 +30,11 @@ func main() {
 		fmt.Fprintln(flag.CommandLine.Output(),
 			"goadifcsv: remove specified ADIF fields")
 		fmt.Fprintf(flag.CommandLine.Output(),
-			"Usage: %s [-f infile] [-o outfile] field_names...\n", execname)
+			"Usage: %s [-f infile] [-o outfile] [-u] field_names...\n", execname)
 		fmt.Fprintf(flag.CommandLine.Output(),
 			"Values of non-existing fields are set to empty strings\n")
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"-u: append the fields declared by USERDEFn header fields\n")
 		flag.PrintDefaults()
 	}
 
@@ -45,6 +50,12 @@ func main() {
 		}
 	}
 
+	header, input, err := adifio.ReadHeader(fp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
 	var writer *csv.Writer
 	var writefp *os.File
 	if *outfile != "" {
@@ -69,15 +80,27 @@ func main() {
 
 	// Write field names first
 	var fieldnames []string
+	listed := make(map[string]bool)
 	for i := range fields {
 		fieldnames = append(fieldnames, strings.ToLower(fields[i]))
+		listed[strings.ToLower(fields[i])] = true
+	}
+	// Append user-defined fields not listed in the arguments
+	if *userdef {
+		for _, name := range header.UserdefNames() {
+			if !listed[name] {
+				fields = append(fields, name)
+				fieldnames = append(fieldnames, name)
+				listed[name] = true
+			}
+		}
 	}
 	writer.Write(fieldnames)
 
 	// For deduping, use this filter API:
 	// reader := adifparser.NewDedupeADIFReader(fp)
 
-	reader := adifparser.NewADIFReader(fp)
+	reader := adifparser.NewADIFReader(input)
 	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
 		if err != nil {
 			if err != io.EOF {
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+const testMainEnv = "GOADIFCSV_TEST_MAIN"
+
+// Run main() in a child process of the test binary
+func TestMain(m *testing.M) {
+	testutil.Main(m, testMainEnv, main)
+}
+
+// Run goadifcsv with the input and the arguments,
+// and return the CSV rows
+func runCSV(t *testing.T, input string, args ...string) [][]string {
+	t.Helper()
+	stdout, stderr := testutil.Run(t, testMainEnv, input, args...)
+	if stderr != "" {
+		t.Fatalf("goadifcsv %v: %s", args, stderr)
+	}
+	rows, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+const testLog = "Test log <adif_ver:5>3.1.4<userdef1:3:N>EPC<userdef2:10:E>Size,{S,M}<eoh>\n" +
+	"<call:4>A1AA<band:3>20m<epc:2>12<size:1>S<eor>\n" +
+	"<call:4>A1AB<comment:6>a, \"b\"<eor>\n"
+
+func TestCSV(t *testing.T) {
+	tests := []struct {
+		args []string
+		want [][]string
+	}{
+		{[]string{"CALL", "band", "comment"}, [][]string{
+			{"call", "band", "comment"},
+			{"A1AA", "20m", ""},
+			{"A1AB", "", `a, "b"`}}},
+		{[]string{"-u", "call"}, [][]string{
+			{"call", "epc", "size"},
+			{"A1AA", "12", "S"},
+			{"A1AB", "", ""}}},
+		// Listed user-defined fields are not appended again
+		{[]string{"-u", "size", "call"}, [][]string{
+			{"size", "call", "epc"},
+			{"S", "A1AA", "12"},
+			{"", "A1AB", ""}}},
+	}
+	for _, tt := range tests {
+		if got := runCSV(t, testLog, tt.args...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("goadifcsv %v = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	// No header
+	got := runCSV(t, "<call:4>A1AA<eor>\n", "-u", "call")
+	if want := [][]string{{"call"}, {"A1AA"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("goadifcsv without header = %q, want %q", got, want)
+	}
+}
@@ -1,21 +1,82 @@
 // goadifdelf: remove specified ADIF fields
 // by Kenji Rikitake, JJ1BDX
-// Usage: goadifdelf [-f infile] [-o outfile] field_names...
+// Usage: goadifdelf [-f infile] [-o outfile] [-keep] [-r] [-e expression] field_patterns...
+// -nh: do not pass through the input ADIF header
+// Field patterns are glob patterns of the field names, e.g., app_*, my_*
+//   Field names without *, ?, or [ are matched as they are
+// -r: field patterns are Go RE2 regexes matching the whole field names
+// -keep: keep only the fields matching the patterns and delete the others
+// -e: delete the fields only from the records matching the expression
+//     (see adifexpr package for the expression syntax)
+//     The other records are output as they are
+// Note: field names and patterns are case insensitive
+// Examples:
+//   goadifdelf app_* my_*
+//   goadifdelf -keep call qso_date time_on band mode freq
+//   goadifdelf -e 'notes =~ "(?i)test"' notes
 
 package main
 
 import (
 	"flag"
 	"fmt"
-	"github.com/jj1bdx/adifparser"
 	"io"
 	"os"
+	"path"
+	"regexp"
 	"strings"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifexpr"
+	"github.com/jj1bdx/goadiftools/adifio"
 )
 
+// Field name pattern
+type fieldPattern struct {
+	glob  string
+	regex *regexp.Regexp
+}
+
+// Create a field pattern
+// isregex chooses a regex instead of a glob pattern
+func newFieldPattern(pattern string, isregex bool) (fieldPattern, error) {
+	pattern = strings.ToLower(pattern)
+	if isregex {
+		regex, err := regexp.Compile("^(?:" + pattern + ")$")
+		return fieldPattern{regex: regex}, err
+	}
+	// Check the syntax of the glob pattern
+	_, err := path.Match(pattern, "")
+	return fieldPattern{glob: pattern}, err
+}
+
+// Check if the field name matches the pattern
+func (p fieldPattern) matches(field string) bool {
+	field = strings.ToLower(field)
+	if p.regex != nil {
+		return p.regex.MatchString(field)
+	}
+	matched, _ := path.Match(p.glob, field)
+	return matched
+}
+
+// Check if the field name matches any of the patterns
+func matchesAny(patterns []fieldPattern, field string) bool {
+	for _, p := range patterns {
+		if p.matches(field) {
+			return true
+		}
+	}
+	return false
+}
+
 func main() {
 	var infile = flag.String("f", "", "input file (stdout in none)")
 	var outfile = flag.String("o", "", "output file (stdout if none)")
+	var noheader = flag.Bool("nh", false, "do not pass through the input header")
+	var keep = flag.Bool("keep", false, "keep only the fields matching the patterns")
+	var isregex = flag.Bool("r", false, "field patterns are regexes")
+	var expression = flag.String("e", "", "delete only from records matching the expression")
 
 	var fp *os.File
 	var err error
@@ -25,12 +86,37 @@ func main() {
 		fmt.Fprintln(flag.CommandLine.Output(),
 			"goadifdelf: remove specified ADIF fields")
 		fmt.Fprintf(flag.CommandLine.Output(),
-			"Usage: %s [-f infile] [-o outfile] field_names...\n", execname)
+			"Usage: %s [-f infile] [-o outfile] [-keep] [-r] [-e expression] "+
+				"field_patterns...\n", execname)
 		flag.PrintDefaults()
+		details :=
+			"Field patterns are glob patterns of the field names, e.g., app_*, my_*\n" +
+				"  Field names without *, ?, or [ are matched as they are\n" +
+				"-r: field patterns are Go RE2 regexes matching the whole field names\n" +
+				"-keep: keep only the fields matching the patterns and delete the others\n" +
+				"-e: delete the fields only from the records matching the expression\n" +
+				"    (same as goadifgrep -e)\n" +
+				"    The other records are output as they are\n" +
+				"Note: field names and patterns are case insensitive\n" +
+				"Examples:\n" +
+				"  goadifdelf app_* my_*\n" +
+				"  goadifdelf -keep call qso_date time_on band mode freq\n" +
+				"  goadifdelf -e 'notes =~ \"(?i)test\"' notes\n"
+		fmt.Fprint(flag.CommandLine.Output(), details)
 	}
 
 	flag.Parse()
 
+	var patterns []fieldPattern
+	for _, arg := range flag.Args() {
+		pattern, err := newFieldPattern(arg, *isregex)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		patterns = append(patterns, pattern)
+	}
+
 	if *infile == "" {
 		fp = os.Stdin
 	} else {
@@ -41,6 +127,32 @@ func main() {
 		}
 	}
 
+	header, input, err := adifio.ReadHeader(fp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	// Compare user-defined fields by the declared data types
+	var query adifexpr.Node
+	if *expression != "" {
+		query, err = adifexpr.Parse(*expression, header.UserdefTypes())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+	}
+	if *noheader {
+		header = nil
+	}
+	// Remove USERDEF declarations of the fields deleted from all records
+	if header != nil && query == nil {
+		for _, name := range header.UserdefNames() {
+			if matchesAny(patterns, name) != *keep {
+				header.RemoveUserdef(name)
+			}
+		}
+	}
+
 	var writer adifparser.ADIFWriter
 	var writefp *os.File
 	if *outfile != "" {
@@ -55,23 +167,21 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error: file %s already exists\n", *outfile)
 			return
 		}
-		writer = adifparser.NewADIFWriter(writefp)
+		writer = adifio.NewWriter(writefp, header, "goadifdelf")
 	} else {
 		writefp = nil
-		writer = adifparser.NewADIFWriter(os.Stdout)
+		writer = adifio.NewWriter(os.Stdout, header, "goadifdelf")
 	}
 
-	fieldstodelete := flag.Args()
-
 	if writer.SetComment("goadifdelf\n") != nil {
 		fmt.Fprint(os.Stderr, err)
 		return
 	}
 
 	// For deduping, use this filter API:
-	// reader := adifparser.NewDedupeADIFReader(fp)
+	// reader := adifparser.NewDedupeADIFReader(input)
 
-	reader := adifparser.NewADIFReader(fp)
+	reader := adifparser.NewADIFReader(input)
 	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
 		if err != nil {
 			if err != io.EOF {
@@ -80,12 +190,35 @@ func main() {
 			break // when io.EOF break the loop!
 		}
 
-		// Delete specified fields
-		for i := range fieldstodelete {
-			// Do not use retuen values
-			record.DeleteField(strings.ToLower(fieldstodelete[i]))
+		// Delete fields only from the matching records
+		selected := true
+		if query != nil {
+			selected, err = query.Eval(record)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				break
+			}
+			for _, warning := range query.Warnings(record) {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+			}
+		}
+
+		if selected {
+			// Collect the fields first, then delete them
+			fieldstodelete := []string{}
+			for _, field := range record.GetFields() {
+				if matchesAny(patterns, field) != *keep {
+					fieldstodelete = append(fieldstodelete, field)
+				}
+			}
+			for _, field := range fieldstodelete {
+				// Do not use retuen values
+				record.DeleteField(field)
+			}
+		}
+		if err := writer.WriteRecord(record); err != nil {
+			fmt.Fprintln(os.Stderr, err)
 		}
-		writer.WriteRecord(record)
 
 	}
 
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+const testMainEnv = "GOADIFDELF_TEST_MAIN"
+
+// Run main() in a child process of the test binary
+func TestMain(m *testing.M) {
+	testutil.Main(m, testMainEnv, main)
+}
+
+// Run goadifdelf with the input and the arguments,
+// and return the sorted lowercase field names of each output record
+func runDelf(t *testing.T, input string, args ...string) [][]string {
+	t.Helper()
+	stdout, stderr := testutil.Run(t, testMainEnv, input, args...)
+	if stderr != "" {
+		t.Fatalf("goadifdelf %v: %s", args, stderr)
+	}
+	records := [][]string{}
+	for _, record := range testutil.ReadRecords(t, stdout) {
+		records = append(records, testutil.FieldNames(record))
+	}
+	return records
+}
+
+func TestFieldPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		isregex bool
+		field   string
+		want    bool
+	}{
+		{"call", false, "CALL", true},
+		{"call", false, "call_x", false},
+		{"APP_*", false, "app_n1mm_id", true},
+		{"my_*", false, "my_gridsquare", true},
+		{"my_*", false, "gridsquare", false},
+		{"time_o?", false, "time_on", true},
+		{"time_o?", false, "time_off", false},
+		{"qsl_[rs]cvd", false, "qsl_rcvd", true},
+		{"qsl_[rs]cvd", false, "qsl_sent", false},
+		{"app_.*", true, "APP_X", true},
+		{"my_|call", true, "my_gridsquare", false},
+		{"my_.*|call", true, "call", true},
+		{"call", true, "call_x", false},
+	}
+	for _, tt := range tests {
+		p, err := newFieldPattern(tt.pattern, tt.isregex)
+		if err != nil {
+			t.Errorf("newFieldPattern(%q, %v): %v", tt.pattern, tt.isregex, err)
+			continue
+		}
+		if got := p.matches(tt.field); got != tt.want {
+			t.Errorf("%q (regex %v) matches %q = %v, want %v",
+				tt.pattern, tt.isregex, tt.field, got, tt.want)
+		}
+	}
+	if _, err := newFieldPattern("[a-", false); err == nil {
+		t.Errorf("newFieldPattern(invalid glob): no error")
+	}
+	if _, err := newFieldPattern("(", true); err == nil {
+		t.Errorf("newFieldPattern(invalid regex): no error")
+	}
+}
+
+const testLog = "<call:4>A1AA<band:3>20m<my_gridsquare:4>PM95<app_x_id:1>1<notes:4>test<eor>\n" +
+	"<call:4>A1AB<band:3>40m<my_rig:6>IC-705<notes:3>abc<eor>\n"
+
+func TestDelf(t *testing.T) {
+	tests := []struct {
+		args   []string
+		fields [][]string
+	}{
+		{[]string{"app_*", "MY_*"}, [][]string{
+			{"band", "call", "notes"}, {"band", "call", "notes"}}},
+		{[]string{"-keep", "call", "band"}, [][]string{
+			{"band", "call"}, {"band", "call"}}},
+		{[]string{"-r", "my_.*|notes"}, [][]string{
+			{"app_x_id", "band", "call"}, {"band", "call"}}},
+		{[]string{"-keep", "-r", "c.*"}, [][]string{{"call"}, {"call"}}},
+		{[]string{"-e", `notes == "test"`, "notes", "app_*"}, [][]string{
+			{"band", "call", "my_gridsquare"}, {"band", "call", "my_rig", "notes"}}},
+		{[]string{"-keep", "-e", `band == "40m"`, "call"}, [][]string{
+			{"app_x_id", "band", "call", "my_gridsquare", "notes"}, {"call"}}},
+		{[]string{"gridsquare"}, [][]string{
+			{"app_x_id", "band", "call", "my_gridsquare", "notes"},
+			{"band", "call", "my_rig", "notes"}}},
+	}
+	for _, tt := range tests {
+		if got := runDelf(t, testLog, tt.args...); !reflect.DeepEqual(got, tt.fields) {
+			t.Errorf("goadifdelf %v = %v, want %v", tt.args, got, tt.fields)
+		}
+	}
+}
+
+func TestDelfUserdefTypes(t *testing.T) {
+	input := "log <userdef1:5:T>SHIPT<eoh>\n" +
+		"<call:4>A1AA<shipt:4>0830<eor>\n" +
+		"<call:4>A1AB<shipt:4>0900<eor>\n"
+	// SHIPT is compared as Time
+	got := runDelf(t, input, "-e", `shipt == 083000`, "shipt")
+	want := [][]string{{"call"}, {"call", "shipt"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("goadifdelf = %v, want %v", got, want)
+	}
+}
@@ -1,6 +1,7 @@
 // goadifdump: reformat preserve all ADIF file fields without deduping
 // by Kenji Rikitake, JJ1BDX
 // Usage: goadifdump [-f infile] [-o outfile]
+// -nh: do not pass through the input ADIF header
 //
 // This is a skeleton code set for adding further processing
 //
@@ -14,6 +15,7 @@ import (
 	"flag"
 	"fmt"
 	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifio"
 	"io"
 	"os"
 )
@@ -21,6 +23,7 @@ import (
 func main() {
 	var infile = flag.String("f", "", "input file (stdout in none)")
 	var outfile = flag.String("o", "", "output file (stdout if none)")
+	var noheader = flag.Bool("nh", false, "do not pass through the input header")
 
 	var fp *os.File
 	var err error
@@ -46,6 +49,15 @@ func main() {
 		}
 	}
 
+	header, input, err := adifio.ReadHeader(fp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if *noheader {
+		header = nil
+	}
+
 	var writer adifparser.ADIFWriter
 	var writefp *os.File
 	if *outfile != "" {
@@ -60,10 +72,10 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error: file %s already exists\n", *outfile)
 			return
 		}
-		writer = adifparser.NewADIFWriter(writefp)
+		writer = adifio.NewWriter(writefp, header, "goadifdump")
 	} else {
 		writefp = nil
-		writer = adifparser.NewADIFWriter(os.Stdout)
+		writer = adifio.NewWriter(os.Stdout, header, "goadifdump")
 	}
 
 	if writer.SetComment("goadifdump\n") != nil {
@@ -72,9 +84,9 @@ func main() {
 	}
 
 	// For deduping, use this filter API:
-	// reader := adifparser.NewDedupeADIFReader(fp)
+	// reader := adifparser.NewDedupeADIFReader(input)
 
-	reader := adifparser.NewADIFReader(fp)
+	reader := adifparser.NewADIFReader(input)
 	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
 		if err != nil {
 			if err != io.EOF {
@@ -84,7 +96,9 @@ func main() {
 		}
 
 		// process things here with the record
-		writer.WriteRecord(record)
+		if err := writer.WriteRecord(record); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
 
 	}
 
@@ -1,6 +1,7 @@
 // goadifdxcc: add DXCC related fields
 // by Kenji Rikitake, JJ1BDX
 // Usage: goadifdxcc [-f infile] [-o outfile]
+// -nh: do not pass through the input ADIF header
 
 package main
 
@@ -8,16 +9,18 @@ import (
 	"flag"
 	"fmt"
 	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifio"
+	"github.com/jj1bdx/goadiftools/callsign"
 	"github.com/jj1bdx/godxcc"
 	"io"
 	"os"
 	"strconv"
-	"strings"
 )
 
 func main() {
 	var infile = flag.String("f", "", "input file (stdout in none)")
 	var outfile = flag.String("o", "", "output file (stdout if none)")
+	var noheader = flag.Bool("nh", false, "do not pass through the input header")
 
 	var fp *os.File
 	var err error
@@ -50,6 +53,15 @@ func main() {
 		}
 	}
 
+	header, input, err := adifio.ReadHeader(fp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if *noheader {
+		header = nil
+	}
+
 	var writer adifparser.ADIFWriter
 	var writefp *os.File
 	if *outfile != "" {
@@ -64,10 +76,10 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error: file %s already exists\n", *outfile)
 			return
 		}
-		writer = adifparser.NewADIFWriter(writefp)
+		writer = adifio.NewWriter(writefp, header, "goadifdxcc")
 	} else {
 		writefp = nil
-		writer = adifparser.NewADIFWriter(os.Stdout)
+		writer = adifio.NewWriter(os.Stdout, header, "goadifdxcc")
 	}
 
 	// Initialize godxcc
@@ -78,7 +90,7 @@ func main() {
 		return
 	}
 
-	reader := adifparser.NewADIFReader(fp)
+	reader := adifparser.NewADIFReader(input)
 	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
 		if err != nil {
 			if err != io.EOF {
@@ -94,7 +106,7 @@ func main() {
 			continue
 		}
 		// Fetch DXCC database data
-		dxccdata := godxcc.DXCCGetRecord(strings.ToUpper(call))
+		dxccdata := godxcc.DXCCGetRecord(callsign.Normalize(call))
 
 		// For each ADIF field of country, cqz, ituz, cont, dxcc:
 		// fill in the field with the DXCC database data if the fieldis empty
@@ -121,7 +133,9 @@ func main() {
 		}
 
 		// Write the record
-		writer.WriteRecord(record)
+		if err := writer.WriteRecord(record); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
 
 	}
 
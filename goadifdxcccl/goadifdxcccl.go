@@ -1,6 +1,7 @@
 // goadifdxcccl: add DXCC/CQ Zone info with Club Log database reference
 // by Kenji Rikitake, JJ1BDX
 // Usage: goadifdxcc [-f infile] [-o outfile]
+// -nh: do not pass through the input ADIF header
 
 package main
 
@@ -10,16 +11,18 @@ import (
 	"io"
 	"os"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifio"
+	"github.com/jj1bdx/goadiftools/callsign"
 	"github.com/jj1bdx/gocldb"
 )
 
 func main() {
 	var infile = flag.String("f", "", "input file (stdout in none)")
 	var outfile = flag.String("o", "", "output file (stdout if none)")
+	var noheader = flag.Bool("nh", false, "do not pass through the input header")
 
 	var fp *os.File
 	var err error
@@ -55,6 +58,15 @@ func main() {
 		}
 	}
 
+	header, input, err := adifio.ReadHeader(fp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if *noheader {
+		header = nil
+	}
+
 	var writer adifparser.ADIFWriter
 	var writefp *os.File
 	if *outfile != "" {
@@ -69,10 +81,10 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error: file %s already exists\n", *outfile)
 			return
 		}
-		writer = adifparser.NewADIFWriter(writefp)
+		writer = adifio.NewWriter(writefp, header, "goadifdxcccl")
 	} else {
 		writefp = nil
-		writer = adifparser.NewADIFWriter(os.Stdout)
+		writer = adifio.NewWriter(os.Stdout, header, "goadifdxcccl")
 	}
 
 	// Initialize gocldb
@@ -85,7 +97,7 @@ func main() {
 		return
 	}
 
-	reader := adifparser.NewADIFReader(fp)
+	reader := adifparser.NewADIFReader(input)
 	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
 		if err != nil {
 			if err != io.EOF {
@@ -147,7 +159,7 @@ func main() {
 			0, time.UTC)
 
 		// Fetch DXCC database data
-		result, err := gocldb.CheckCallsign(strings.ToUpper(call), recordtime)
+		result, err := gocldb.CheckCallsign(callsign.Normalize(call), recordtime)
 		if err == nil {
 
 			// For each ADIF field of country, cqz, cont, dxcc:
@@ -177,7 +189,9 @@ func main() {
 		}
 
 		// Write the record
-		writer.WriteRecord(record)
+		if err := writer.WriteRecord(record); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
 
 	}
 
@@ -0,0 +1,321 @@
+// goadiffields: output field inventory of ADIF records
+// by Kenji Rikitake, JJ1BDX
+// Usage: goadiffields [-f infile] [-o outfile] [-n samplelength]
+// Output:
+//   header: preamble and header fields (e.g., ADIF_VER, PROGRAMID)
+//   fields: standard fields, APP_ fields, USERDEF fields,
+//     and unknown fields (neither standard, APP_, nor declared USERDEF)
+//     separately
+//     field name, number of records with the field,
+//     number of records with the empty value, inferred type,
+//     min and max values, and a sample value
+// Inferred types (in this order of precedence):
+//   Boolean (Y or N), Date (YYYYMMDD), Time (HHMM or HHMMSS for time_* fields),
+//   Integer, Number, String
+//   Integer and Number are decimal values as defined in ADIF
+//   (e.g., 1e3, NaN, and Inf are String)
+//   Empty values are not used for the inference
+// Min and max values are compared numerically for Integer and Number,
+// and as strings for the other types
+// Long values are truncated to the sample length in characters
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifio"
+)
+
+// Inferred types in the order of precedence
+var fieldTypes = []string{"Boolean", "Date", "Time", "Integer", "Number", "String"}
+
+// Inventory of a field
+type fieldInventory struct {
+	name    string
+	records int
+	empty   int
+	// Possible types of the non-empty values
+	types  map[string]bool
+	sample string
+	// Min and max values as strings
+	strmin string
+	strmax string
+	// Min and max values as numbers (valid only for Integer and Number)
+	nummin    string
+	nummax    string
+	numminval float64
+	nummaxval float64
+}
+
+func newFieldInventory(name string) *fieldInventory {
+	types := make(map[string]bool)
+	for _, t := range fieldTypes {
+		types[t] = true
+	}
+	return &fieldInventory{name: name, types: types}
+}
+
+// Check if the value is of the type
+func isType(field, value, fieldtype string) bool {
+	switch fieldtype {
+	case "Boolean":
+		return strings.EqualFold(value, "Y") || strings.EqualFold(value, "N")
+	case "Date":
+		if len(value) != 8 {
+			return false
+		}
+		_, err := time.Parse("20060102", value)
+		return err == nil
+	case "Time":
+		if !strings.HasPrefix(field, "time_") {
+			return false
+		}
+		if len(value) == 4 {
+			value = value + "00"
+		}
+		if len(value) != 6 {
+			return false
+		}
+		_, err := time.Parse("150405", value)
+		return err == nil
+	case "Integer":
+		return adifio.IsInteger(value)
+	case "Number":
+		return adifio.IsNumber(value)
+	}
+	return true
+}
+
+// Add a value of the field
+func (f *fieldInventory) add(value string) {
+	f.records++
+	if value == "" {
+		f.empty++
+		return
+	}
+	if f.sample == "" {
+		f.sample = value
+		f.strmin = value
+		f.strmax = value
+	}
+	for _, t := range fieldTypes {
+		if f.types[t] && !isType(f.name, value, t) {
+			f.types[t] = false
+		}
+	}
+	if value < f.strmin {
+		f.strmin = value
+	}
+	if value > f.strmax {
+		f.strmax = value
+	}
+	if f.types["Number"] {
+		n, _ := adifio.ParseNumber(value)
+		if f.nummin == "" || n < f.numminval {
+			f.nummin = value
+			f.numminval = n
+		}
+		if f.nummax == "" || n > f.nummaxval {
+			f.nummax = value
+			f.nummaxval = n
+		}
+	}
+}
+
+// Inferred type of the field
+func (f *fieldInventory) inferredType() string {
+	if f.records == f.empty {
+		return "(EMPTY)"
+	}
+	for _, t := range fieldTypes {
+		if f.types[t] {
+			return t
+		}
+	}
+	return "String"
+}
+
+// Min and max values of the field
+func (f *fieldInventory) minMax() (string, string) {
+	switch f.inferredType() {
+	case "Integer", "Number":
+		return f.nummin, f.nummax
+	}
+	return f.strmin, f.strmax
+}
+
+// Truncate a value to the length, and make it printable in one line
+func truncate(value string, length int) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if runes := []rune(value); len(runes) > length {
+		return string(runes[:length]) + "..."
+	}
+	return value
+}
+
+func fieldsOutput(title string, inventories []*fieldInventory,
+	samplelength int, writer io.Writer) {
+	fmt.Fprintf(writer, "%s: %d\n", title, len(inventories))
+	if len(inventories) == 0 {
+		return
+	}
+	tw := tabwriter.NewWriter(writer, 0, 8, 1, ' ', 0)
+	fmt.Fprintln(tw, "  FIELD\tRECORDS\tEMPTY\tTYPE\tMIN\tMAX\tSAMPLE")
+	for _, f := range inventories {
+		min, max := f.minMax()
+		fmt.Fprintf(tw, "  %s\t%d\t%d\t%s\t%s\t%s\t%s\n",
+			f.name, f.records, f.empty, f.inferredType(),
+			truncate(min, samplelength), truncate(max, samplelength),
+			truncate(f.sample, samplelength))
+	}
+	tw.Flush()
+}
+
+func main() {
+	var infile = flag.String("f", "", "input file (stdout in none)")
+	var outfile = flag.String("o", "", "output file (stdout if none)")
+	var samplelength = flag.Int("n", 20, "maximum length of output values")
+
+	var fp *os.File
+	var err error
+
+	flag.Usage = func() {
+		execname := os.Args[0]
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"goadiffields: output field inventory of ADIF records")
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [-f infile] [-o outfile] [-n samplelength]\n", execname)
+		flag.PrintDefaults()
+		details :=
+			"Output:\n" +
+				"  header: preamble and header fields (e.g., ADIF_VER, PROGRAMID)\n" +
+				"  fields: standard fields, APP_ fields, USERDEF fields,\n" +
+				"    and unknown fields (neither standard, APP_, nor declared USERDEF)\n" +
+				"    separately\n" +
+				"    field name, number of records with the field,\n" +
+				"    number of records with the empty value, inferred type,\n" +
+				"    min and max values, and a sample value\n" +
+				"Inferred types (in this order of precedence):\n" +
+				"  Boolean (Y or N), Date (YYYYMMDD), Time (HHMM or HHMMSS for time_* fields),\n" +
+				"  Integer, Number, String\n"
+		fmt.Fprint(flag.CommandLine.Output(), details)
+	}
+
+	flag.Parse()
+
+	if *infile == "" {
+		fp = os.Stdin
+	} else {
+		fp, err = os.Open(*infile)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+			return
+		}
+	}
+
+	var writefp *os.File
+	if *outfile != "" {
+		if _, err := os.Stat(*outfile); os.IsNotExist(err) {
+			// File does not exist: create it
+			writefp, err = os.Create(*outfile)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				return
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: file %s already exists\n", *outfile)
+			return
+		}
+	} else {
+		writefp = os.Stdout
+	}
+
+	header, input, err := adifio.ReadHeader(fp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	userdefs := make(map[string]bool)
+	for _, name := range header.UserdefNames() {
+		userdefs[name] = true
+	}
+
+	inventories := make(map[string]*fieldInventory)
+	nrecords := 0
+
+	reader := adifparser.NewADIFReader(input)
+	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprint(os.Stderr, err)
+			}
+			break // when io.EOF break the loop!
+		}
+		nrecords++
+
+		for _, field := range record.GetFields() {
+			field = strings.ToLower(field)
+			value, err := record.GetValue(field)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				continue
+			}
+			inventory, exists := inventories[field]
+			if !exists {
+				inventory = newFieldInventory(field)
+				inventories[field] = inventory
+			}
+			inventory.add(value)
+		}
+	}
+
+	// Classify the fields
+	var standard, app, userdef, unknown []*fieldInventory
+	names := make([]string, 0, len(inventories))
+	for name := range inventories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if userdefs[name] {
+			userdef = append(userdef, inventories[name])
+		} else if strings.HasPrefix(name, "app_") {
+			app = append(app, inventories[name])
+		} else if adifio.IsStandardField(name) {
+			standard = append(standard, inventories[name])
+		} else {
+			unknown = append(unknown, inventories[name])
+		}
+	}
+
+	if header.Exists {
+		fmt.Fprintf(writefp, "header: %d\n", len(header.Fields))
+		if preamble := truncate(header.Preamble, 60); preamble != "" {
+			fmt.Fprintf(writefp, "  preamble: %s\n", preamble)
+		}
+		for _, f := range header.Fields {
+			fmt.Fprintf(writefp, "  %s: %s\n", f.Name, f.Value)
+		}
+	} else {
+		fmt.Fprintf(writefp, "header: (NONE)\n")
+	}
+	fmt.Fprintf(writefp, "records: %d\n", nrecords)
+	fieldsOutput("fields", standard, *samplelength, writefp)
+	fieldsOutput("app_fields", app, *samplelength, writefp)
+	fieldsOutput("userdef_fields", userdef, *samplelength, writefp)
+	fieldsOutput("unknown_fields", unknown, *samplelength, writefp)
+
+	if writefp != os.Stdout {
+		writefp.Close()
+	}
+
+}
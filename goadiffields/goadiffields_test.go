@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+const testMainEnv = "GOADIFFIELDS_TEST_MAIN"
+
+// Run main() in a child process of the test binary
+func TestMain(m *testing.M) {
+	testutil.Main(m, testMainEnv, main)
+}
+
+func TestIsType(t *testing.T) {
+	tests := []struct {
+		field     string
+		value     string
+		fieldtype string
+		want      bool
+	}{
+		{"qsl_rcvd", "y", "Boolean", true},
+		{"qsl_rcvd", "Yes", "Boolean", false},
+		{"qso_date", "20231125", "Date", true},
+		{"qso_date", "20231325", "Date", false},
+		{"qso_date", "2023112", "Date", false},
+		{"time_on", "1230", "Time", true},
+		{"time_on", "123045", "Time", true},
+		{"time_on", "2460", "Time", false},
+		{"srx", "1230", "Time", false},
+		{"srx", "-12", "Integer", true},
+		{"srx", "+12", "Integer", false},
+		{"freq", "14.074", "Number", true},
+		{"freq", ".5", "Number", true},
+		{"freq", "1e3", "Number", false},
+		{"freq", "NaN", "Number", false},
+		{"freq", "Inf", "Number", false},
+		{"freq", "0x10", "Number", false},
+		{"notes", "anything", "String", true},
+	}
+	for _, tt := range tests {
+		if got := isType(tt.field, tt.value, tt.fieldtype); got != tt.want {
+			t.Errorf("isType(%q, %q, %q) = %v, want %v",
+				tt.field, tt.value, tt.fieldtype, got, tt.want)
+		}
+	}
+}
+
+func TestFieldInventory(t *testing.T) {
+	tests := []struct {
+		field    string
+		values   []string
+		wanttype string
+		min      string
+		max      string
+	}{
+		{"qsl_rcvd", []string{"Y", "n", ""}, "Boolean", "Y", "n"},
+		{"qso_date", []string{"20231125", "20220101"}, "Date", "20220101", "20231125"},
+		{"time_on", []string{"1230", "001500"}, "Time", "001500", "1230"},
+		// Dates are also Integers, but Date has the precedence
+		{"srx", []string{"20231125", "9"}, "Integer", "9", "20231125"},
+		{"freq", []string{"14.074", "7.0", "144"}, "Number", "7.0", "144"},
+		{"freq", []string{"14.074", "NaN"}, "String", "14.074", "NaN"},
+		{"freq", []string{"1e3", "5"}, "String", "1e3", "5"},
+		{"rx_pwr", []string{"+5", "10"}, "String", "+5", "10"},
+		{"notes", []string{"", ""}, "(EMPTY)", "", ""},
+	}
+	for _, tt := range tests {
+		f := newFieldInventory(tt.field)
+		for _, value := range tt.values {
+			f.add(value)
+		}
+		min, max := f.minMax()
+		if f.inferredType() != tt.wanttype || min != tt.min || max != tt.max {
+			t.Errorf("%s %q = %s, %q, %q, want %s, %q, %q", tt.field, tt.values,
+				f.inferredType(), min, max, tt.wanttype, tt.min, tt.max)
+		}
+		if f.records != len(tt.values) {
+			t.Errorf("%s %q: records = %d", tt.field, tt.values, f.records)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("a  b\nc", 10); got != "a b c" {
+		t.Errorf("truncate = %q", got)
+	}
+	if got := truncate("abcdef", 3); got != "abc..." {
+		t.Errorf("truncate = %q", got)
+	}
+	// Truncated by characters, not by bytes
+	if got := truncate("東京都千代田区", 3); got != "東京都..." {
+		t.Errorf("truncate = %q", got)
+	}
+	if got := truncate("東京都", 3); got != "東京都" {
+		t.Errorf("truncate = %q", got)
+	}
+}
+
+func TestFields(t *testing.T) {
+	input := "Test log <adif_ver:5>3.1.4<userdef1:8:N>EPC_RANK<eoh>\n" +
+		"<call:4>A1AA<freq:6>14.074<app_x_id:1>1<epc_rank:2>12<my_grid:4>PM95<eor>\n" +
+		"<call:4>A1AB<freq:3>NaN<app_x_id:0><eor>\n"
+	stdout, stderr := testutil.Run(t, testMainEnv, input)
+	if stderr != "" {
+		t.Fatalf("goadiffields: %s", stderr)
+	}
+	// Compare without the column alignment
+	lines := []string{}
+	for _, line := range strings.Split(stdout, "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	want := []string{
+		"header: 2",
+		"preamble: Test log",
+		"adif_ver: 3.1.4",
+		"userdef1: EPC_RANK",
+		"records: 2",
+		"fields: 2",
+		"FIELD RECORDS EMPTY TYPE MIN MAX SAMPLE",
+		"call 2 0 String A1AA A1AB A1AA",
+		"freq 2 0 String 14.074 NaN 14.074",
+		"app_fields: 1",
+		"FIELD RECORDS EMPTY TYPE MIN MAX SAMPLE",
+		"app_x_id 2 1 Integer 1 1 1",
+		"userdef_fields: 1",
+		"FIELD RECORDS EMPTY TYPE MIN MAX SAMPLE",
+		"epc_rank 1 0 Integer 12 12 12",
+		"unknown_fields: 1",
+		"FIELD RECORDS EMPTY TYPE MIN MAX SAMPLE",
+		"my_grid 1 0 String PM95 PM95 PM95",
+		"",
+	}
+	if got := strings.Join(lines, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("output = %s\nwant %s", got, strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,332 @@
+// goadifgeo: add distance, antenna azimuth and location fields
+// by Kenji Rikitake, JJ1BDX
+// Usage: goadifgeo [-f infile] [-o outfile] [-w]
+// -nh: do not pass through the input ADIF header
+//
+// Position of each station determined by:
+//  the other station: lat/lon, or gridsquare if lat/lon missing
+//  my station: my_lat/my_lon, or my_gridsquare if my_lat/my_lon missing
+// The center of the grid square is used as the position
+//
+// Fields to be filled in:
+//  lat, lon: from gridsquare
+//  my_lat, my_lon: from my_gridsquare
+//  distance: great circle distance in km
+//  ant_az: azimuth from my station to the other station in degrees
+// Only empty fields are filled in
+// With -w, existing distance and ant_az fields are overwritten
+// Valid existing lat/lon/my_lat/my_lon fields are always kept and used
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifio"
+)
+
+// Mean radius of the Earth in km
+const earthRadius = 6371.0
+
+var ErrInvalidGridsquare = errors.New("invalid gridsquare")
+var ErrInvalidLocation = errors.New("invalid location")
+
+// Convert a Maidenhead grid square locator (2, 4, 6, or 8 letters)
+// to the latitude and longitude of the center of the square
+func gridToLatLon(grid string) (float64, float64, error) {
+	grid = strings.ToUpper(grid)
+	l := len(grid)
+	if l < 2 || l > 8 || l%2 != 0 {
+		return 0, 0, ErrInvalidGridsquare
+	}
+	lon := -180.0
+	lat := -90.0
+	// Size of each square in degrees
+	lonsize := 20.0
+	latsize := 10.0
+	for i := 0; i < l; i += 2 {
+		lonc := grid[i]
+		latc := grid[i+1]
+		var lonn, latn int
+		switch i {
+		case 0:
+			// Field: A to R
+			if lonc < 'A' || lonc > 'R' || latc < 'A' || latc > 'R' {
+				return 0, 0, ErrInvalidGridsquare
+			}
+			lonn = int(lonc - 'A')
+			latn = int(latc - 'A')
+		case 2, 6:
+			// Square and extended square: 0 to 9
+			if lonc < '0' || lonc > '9' || latc < '0' || latc > '9' {
+				return 0, 0, ErrInvalidGridsquare
+			}
+			lonsize /= 10
+			latsize /= 10
+			lonn = int(lonc - '0')
+			latn = int(latc - '0')
+		case 4:
+			// Subsquare: A to X
+			if lonc < 'A' || lonc > 'X' || latc < 'A' || latc > 'X' {
+				return 0, 0, ErrInvalidGridsquare
+			}
+			lonsize /= 24
+			latsize /= 24
+			lonn = int(lonc - 'A')
+			latn = int(latc - 'A')
+		}
+		lon += float64(lonn) * lonsize
+		lat += float64(latn) * latsize
+	}
+	// Use the center of the square
+	return lat + latsize/2, lon + lonsize/2, nil
+}
+
+// Parse ADIF Location value "XDDD MM.MMM"
+// where X is one of N, S, E, W
+func parseLocation(loc string) (float64, error) {
+	loc = strings.TrimSpace(strings.ToUpper(loc))
+	if len(loc) < 6 {
+		return 0, ErrInvalidLocation
+	}
+	parts := strings.Fields(loc[1:])
+	if len(parts) != 2 {
+		return 0, ErrInvalidLocation
+	}
+	deg, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, ErrInvalidLocation
+	}
+	min, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || min < 0 || min >= 60 {
+		return 0, ErrInvalidLocation
+	}
+	value := float64(deg) + min/60
+	switch loc[0] {
+	case 'N', 'S':
+		if value > 90 {
+			return 0, ErrInvalidLocation
+		}
+	case 'E', 'W':
+		if value > 180 {
+			return 0, ErrInvalidLocation
+		}
+	default:
+		return 0, ErrInvalidLocation
+	}
+	if loc[0] == 'S' || loc[0] == 'W' {
+		value = -value
+	}
+	return value, nil
+}
+
+// Format degrees into ADIF Location value "XDDD MM.MMM"
+// pos and neg are the direction letters for positive and negative values
+func formatLocation(value float64, pos byte, neg byte) string {
+	dir := pos
+	if value < 0 {
+		dir = neg
+		value = -value
+	}
+	// Round to 0.001 minutes first to avoid 60.000 minutes
+	minutes := math.Round(value*60*1000) / 1000
+	deg := int(minutes / 60)
+	min := minutes - float64(deg*60)
+	return fmt.Sprintf("%c%03d %06.3f", dir, deg, min)
+}
+
+// Compute great circle distance in km and initial bearing in degrees
+// from (lat1, lon1) to (lat2, lon2)
+func distanceAzimuth(lat1, lon1, lat2, lon2 float64) (float64, float64) {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	dlambda := (lon2 - lon1) * math.Pi / 180
+	dphi := phi2 - phi1
+
+	// Haversine formula
+	a := math.Sin(dphi/2)*math.Sin(dphi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(dlambda/2)*math.Sin(dlambda/2)
+	distance := 2 * earthRadius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+
+	y := math.Sin(dlambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) -
+		math.Sin(phi1)*math.Cos(phi2)*math.Cos(dlambda)
+	azimuth := math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+
+	return distance, azimuth
+}
+
+// Return true if the field is missing or empty
+func isEmptyField(record adifparser.ADIFRecord, field string) bool {
+	value, err := record.GetValue(field)
+	return err == adifparser.ErrNoSuchField || value == ""
+}
+
+// Obtain the station position from the lat/lon fields and the grid field,
+// and fill in the empty lat/lon fields from the grid field
+// Valid lat/lon fields take precedence over the grid field
+// Returns false if the position is not available
+func stationPosition(record adifparser.ADIFRecord,
+	latfield, lonfield, gridfield string) (float64, float64, bool) {
+	var lat, lon float64
+	var laterr, lonerr error
+	latvalue, err := record.GetValue(latfield)
+	if err != nil {
+		laterr = err
+	} else {
+		lat, laterr = parseLocation(latvalue)
+	}
+	lonvalue, err := record.GetValue(lonfield)
+	if err != nil {
+		lonerr = err
+	} else {
+		lon, lonerr = parseLocation(lonvalue)
+	}
+	latlonvalid := laterr == nil && lonerr == nil
+
+	if latlonvalid {
+		return lat, lon, true
+	}
+	grid, err := record.GetValue(gridfield)
+	if err != nil || grid == "" {
+		return lat, lon, false
+	}
+	gridlat, gridlon, err := gridToLatLon(grid)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", err, grid)
+		return lat, lon, false
+	}
+	if isEmptyField(record, latfield) {
+		record.SetValue(latfield, formatLocation(gridlat, 'N', 'S'))
+	}
+	if isEmptyField(record, lonfield) {
+		record.SetValue(lonfield, formatLocation(gridlon, 'E', 'W'))
+	}
+	return gridlat, gridlon, true
+}
+
+func main() {
+	var infile = flag.String("f", "", "input file (stdout in none)")
+	var outfile = flag.String("o", "", "output file (stdout if none)")
+	var noheader = flag.Bool("nh", false, "do not pass through the input header")
+	var overwrite = flag.Bool("w", false, "overwrite existing distance and ant_az fields")
+
+	var fp *os.File
+	var err error
+
+	flag.Usage = func() {
+		execname := os.Args[0]
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"goadifgeo: add distance, antenna azimuth and location fields")
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [-f infile] [-o outfile] [-w]\n", execname)
+		fmt.Fprintf(flag.CommandLine.Output(), "\n")
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"How goadifgeo works:\n"+
+				"For each record, obtain the station positions from\n"+
+				"lat/lon (or gridsquare if missing) for the other station, and\n"+
+				"my_lat/my_lon (or my_gridsquare if missing) for my station.\n"+
+				"The center of the grid square is used as the position.\n"+
+				"Then for each ADIF field of lat, lon, my_lat, my_lon, distance, ant_az:\n"+
+				"fill in the field with the computed value if the field is empty.\n"+
+				"distance is in km, ant_az is in degrees from my station.\n"+
+				"With -w, existing distance and ant_az fields are overwritten.\n"+
+				"Valid existing lat/lon/my_lat/my_lon fields are always kept and used.\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if *infile == "" {
+		fp = os.Stdin
+	} else {
+		fp, err = os.Open(*infile)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+			return
+		}
+	}
+
+	header, input, err := adifio.ReadHeader(fp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if *noheader {
+		header = nil
+	}
+
+	var writer adifparser.ADIFWriter
+	var writefp *os.File
+	if *outfile != "" {
+		if _, err := os.Stat(*outfile); os.IsNotExist(err) {
+			// File does not exist: create it
+			writefp, err = os.Create(*outfile)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				return
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: file %s already exists\n", *outfile)
+			return
+		}
+		writer = adifio.NewWriter(writefp, header, "goadifgeo")
+	} else {
+		writefp = nil
+		writer = adifio.NewWriter(os.Stdout, header, "goadifgeo")
+	}
+
+	if err := writer.SetComment("goadifgeo\n"); err != nil {
+		fmt.Fprint(os.Stderr, err)
+		return
+	}
+
+	reader := adifparser.NewADIFReader(input)
+	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprint(os.Stderr, err)
+			}
+			break // when io.EOF break the loop!
+		}
+
+		lat, lon, dxexists := stationPosition(record,
+			"lat", "lon", "gridsquare")
+		mylat, mylon, myexists := stationPosition(record,
+			"my_lat", "my_lon", "my_gridsquare")
+
+		// Distance and azimuth require both positions
+		if dxexists && myexists {
+			distance, azimuth := distanceAzimuth(mylat, mylon, lat, lon)
+			if *overwrite || isEmptyField(record, "distance") {
+				record.SetValue("distance",
+					strconv.Itoa(int(math.Round(distance))))
+			}
+			if *overwrite || isEmptyField(record, "ant_az") {
+				// ANT_AZ range: 0 to 360 degrees
+				record.SetValue("ant_az",
+					strconv.Itoa(int(math.Round(azimuth))%360))
+			}
+		}
+
+		// Write the record
+		if err := writer.WriteRecord(record); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+
+	}
+
+	// Flush and close the output
+	writer.Flush()
+	if writefp != os.Stdout {
+		writefp.Close()
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+const testMainEnv = "GOADIFGEO_TEST_MAIN"
+
+// Run main() in a child process of the test binary
+func TestMain(m *testing.M) {
+	testutil.Main(m, testMainEnv, main)
+}
+
+func closeTo(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestGridToLatLon(t *testing.T) {
+	tests := []struct {
+		grid string
+		lat  float64
+		lon  float64
+	}{
+		{"PM", 35, 130},
+		{"PM95", 35.5, 139},
+		{"pm95", 35.5, 139},
+		{"JN58td", 48.0 + 3.0/24 + 1.0/48, 10 + 19.0/12 + 1.0/24},
+		{"JN58td12", 48.0 + 3.0/24 + 2.0/240 + 1.0/480, 10 + 19.0/12 + 1.0/120 + 1.0/240},
+		{"AA00", -89.5, -179},
+		{"RR99", 89.5, 179},
+	}
+	for _, tt := range tests {
+		lat, lon, err := gridToLatLon(tt.grid)
+		if err != nil {
+			t.Errorf("gridToLatLon(%q): %v", tt.grid, err)
+			continue
+		}
+		if !closeTo(lat, tt.lat, 1e-9) || !closeTo(lon, tt.lon, 1e-9) {
+			t.Errorf("gridToLatLon(%q) = %v, %v, want %v, %v",
+				tt.grid, lat, lon, tt.lat, tt.lon)
+		}
+	}
+}
+
+func TestGridToLatLonInvalid(t *testing.T) {
+	for _, grid := range []string{"", "P", "PM9", "SM95", "PMA5", "PM95YA", "PM95ab1", "PM95ab1234"} {
+		if _, _, err := gridToLatLon(grid); err != ErrInvalidGridsquare {
+			t.Errorf("gridToLatLon(%q) error = %v, want %v", grid, err, ErrInvalidGridsquare)
+		}
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		loc   string
+		value float64
+	}{
+		{"N035 30.000", 35.5},
+		{"S035 30.000", -35.5},
+		{"E139 45.000", 139.75},
+		{"W000 07.668", -0.1278},
+		{"n035 30.000", 35.5},
+	}
+	for _, tt := range tests {
+		value, err := parseLocation(tt.loc)
+		if err != nil {
+			t.Errorf("parseLocation(%q): %v", tt.loc, err)
+			continue
+		}
+		if !closeTo(value, tt.value, 1e-9) {
+			t.Errorf("parseLocation(%q) = %v, want %v", tt.loc, value, tt.value)
+		}
+	}
+	for _, loc := range []string{"", "N035", "X035 30.000", "N091 00.000",
+		"E181 00.000", "N035 60.000", "N035 -1.000", "N0AB 30.000"} {
+		if _, err := parseLocation(loc); err != ErrInvalidLocation {
+			t.Errorf("parseLocation(%q) error = %v, want %v", loc, err, ErrInvalidLocation)
+		}
+	}
+}
+
+func TestFormatLocation(t *testing.T) {
+	tests := []struct {
+		value float64
+		pos   byte
+		neg   byte
+		want  string
+	}{
+		{35.5, 'N', 'S', "N035 30.000"},
+		{-35.5, 'N', 'S', "S035 30.000"},
+		{-0.1278, 'E', 'W', "W000 07.668"},
+		{139.75, 'E', 'W', "E139 45.000"},
+		// Rounding must not produce 60 minutes
+		{10.9999999, 'N', 'S', "N011 00.000"},
+	}
+	for _, tt := range tests {
+		if got := formatLocation(tt.value, tt.pos, tt.neg); got != tt.want {
+			t.Errorf("formatLocation(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+		// Round trip
+		value, err := parseLocation(formatLocation(tt.value, tt.pos, tt.neg))
+		if err != nil || !closeTo(value, tt.value, 0.001/60) {
+			t.Errorf("round trip of %v = %v, %v", tt.value, value, err)
+		}
+	}
+}
+
+func TestDistanceAzimuth(t *testing.T) {
+	quarter := earthRadius * math.Pi / 2
+	tests := []struct {
+		lat1, lon1, lat2, lon2 float64
+		distance, azimuth      float64
+	}{
+		{0, 0, 0, 90, quarter, 90},
+		{0, 0, 0, -90, quarter, 270},
+		{0, 0, 10, 0, earthRadius * 10 * math.Pi / 180, 0},
+		{10, 0, 0, 0, earthRadius * 10 * math.Pi / 180, 180},
+		{0, 0, 90, 0, quarter, 0},
+	}
+	for _, tt := range tests {
+		distance, azimuth := distanceAzimuth(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+		if !closeTo(distance, tt.distance, 1e-6) || !closeTo(azimuth, tt.azimuth, 1e-6) {
+			t.Errorf("distanceAzimuth(%v, %v, %v, %v) = %v, %v, want %v, %v",
+				tt.lat1, tt.lon1, tt.lat2, tt.lon2,
+				distance, azimuth, tt.distance, tt.azimuth)
+		}
+	}
+}
+
+func TestStationPosition(t *testing.T) {
+	// Grid only: lat/lon are filled in
+	record := testutil.ParseRecord(t, "<gridsquare:4>PM95<eor>")
+	lat, lon, ok := stationPosition(record, "lat", "lon", "gridsquare")
+	if !ok || lat != 35.5 || lon != 139 {
+		t.Errorf("grid only: %v, %v, %v", lat, lon, ok)
+	}
+	if v, _ := record.GetValue("lat"); v != "N035 30.000" {
+		t.Errorf("grid only: lat = %q", v)
+	}
+	if v, _ := record.GetValue("lon"); v != "E139 00.000" {
+		t.Errorf("grid only: lon = %q", v)
+	}
+
+	// Valid lat/lon are preferred and kept without -w
+	record = testutil.ParseRecord(t,
+		"<gridsquare:4>PM95<lat:11>N035 00.000<lon:11>E135 00.000<eor>")
+	lat, lon, ok = stationPosition(record, "lat", "lon", "gridsquare")
+	if !ok || lat != 35 || lon != 135 {
+		t.Errorf("lat/lon: %v, %v, %v", lat, lon, ok)
+	}
+	if v, _ := record.GetValue("lat"); v != "N035 00.000" {
+		t.Errorf("lat/lon: lat = %q", v)
+	}
+
+	// Invalid lat/lon: the grid is used and the fields are kept
+	record = testutil.ParseRecord(t, "<gridsquare:4>PM95<lat:3>bad<lon:0><eor>")
+	lat, lon, ok = stationPosition(record, "lat", "lon", "gridsquare")
+	if !ok || lat != 35.5 || lon != 139 {
+		t.Errorf("invalid lat/lon: %v, %v, %v", lat, lon, ok)
+	}
+	if v, _ := record.GetValue("lat"); v != "bad" {
+		t.Errorf("invalid lat/lon: lat = %q", v)
+	}
+	if v, _ := record.GetValue("lon"); v != "E139 00.000" {
+		t.Errorf("invalid lat/lon: lon = %q", v)
+	}
+
+	// Neither grid nor lat/lon
+	record = testutil.ParseRecord(t, "<call:5>A1AAA<eor>")
+	if _, _, ok := stationPosition(record, "lat", "lon", "gridsquare"); ok {
+		t.Errorf("no position: ok = true")
+	}
+
+	// Invalid grid without lat/lon
+	record = testutil.ParseRecord(t, "<gridsquare:4>ZZ99<eor>")
+	if _, _, ok := stationPosition(record, "lat", "lon", "gridsquare"); ok {
+		t.Errorf("invalid grid: ok = true")
+	}
+}
+
+func TestGeoOverwrite(t *testing.T) {
+	// Explicit positions differ from the grid square centers
+	input := "<call:5>A1AAA<gridsquare:4>FN31<lat:11>N040 00.000<lon:11>W075 00.000" +
+		"<my_gridsquare:4>PM95<my_lat:11>N035 00.000<my_lon:11>E135 00.000" +
+		"<distance:1>1<ant_az:1>1<eor>\n"
+	stdout, stderr := testutil.Run(t, testMainEnv, input, "-w")
+	if stderr != "" {
+		t.Fatalf("goadifgeo -w: %s", stderr)
+	}
+	record := testutil.ParseRecord(t, stdout)
+	distance, azimuth := distanceAzimuth(35, 135, 40, -75)
+	want := map[string]string{
+		"lat":      "N040 00.000",
+		"lon":      "W075 00.000",
+		"my_lat":   "N035 00.000",
+		"my_lon":   "E135 00.000",
+		"distance": strconv.Itoa(int(math.Round(distance))),
+		"ant_az":   strconv.Itoa(int(math.Round(azimuth)) % 360),
+	}
+	for field, value := range want {
+		if got, _ := record.GetValue(field); got != value {
+			t.Errorf("%s = %q, want %q", field, got, value)
+		}
+	}
+}
@@ -1,27 +1,57 @@
 // goadifgrep: search specified ADIF field with a regex and output matched ADIF record
 // by Kenji Rikitake, JJ1BDX
-// Usage: goadifgrep [-v] [-f infile] [-o outfile] field regex
+// Usage: goadifgrep [-v] [-c] [-p] [-m max] [-f infile] [-o outfile] field regex
+//        goadifgrep [-v] [-c] [-p] [-m max] [-f infile] [-o outfile] -a regex
+//        goadifgrep [-v] [-c] [-p] [-m max] [-f infile] [-o outfile] -e expression
+//        goadifgrep [-v] [-c] [-p] [-m max] [-f infile] [-o outfile]
+//                   [-mobile type] -b callsign
+// -nh: do not pass through the input ADIF header
+// -a: search all fields with the regex
+// -c: output only the number of selected records
+// -m: stop after the number of selected records (0 for no limit)
+// -p: output the matched field names and values of selected records
+//     as "record number:field name:value" instead of ADIF records
+//     With -a, only the fields whose values matched are output
+//     Otherwise, the fields referred by the query are output
+// -b: match the base callsign of the call field
+//     e.g., JA1ABC matches JA1ABC/P, W1/JA1ABC, and JA1ABC/MM
+// -mobile: with -b, restrict the maritime/aeronautical mobile type:
+//     mm (/MM), am (/AM), any (/MM or /AM), none (neither /MM nor /AM)
+//     Note: /MM and /AM do not count for DXCC
 // Note: field name is case insensitive
 // Note 2: regex is Go RE2 as defined in Go regexp package
 //         Use "(?i)" flag prefix for case-insensitive matching
+// Note 3: see adifexpr package for the expression syntax
+// Expression example:
+//   band == "20m" && mode in ("FT8","FT4") && !has(qsl_rcvd) && cont =~ "EU"
+//   freq between 14.000 14.070 && tx_pwr <= 5 && qso_date >= 20230101
 
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
 	"io"
 	"os"
-	"regexp"
-	"strings"
 
 	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifexpr"
+	"github.com/jj1bdx/goadiftools/adifio"
 )
 
 func main() {
 	var infile = flag.String("f", "", "input file (stdout in none)")
 	var outfile = flag.String("o", "", "output file (stdout if none)")
+	var noheader = flag.Bool("nh", false, "do not pass through the input header")
 	var invertmatch = flag.Bool("v", false, "invert match if specified")
+	var expression = flag.String("e", "", "query expression")
+	var anyfield = flag.Bool("a", false, "search all fields with the regex")
+	var countonly = flag.Bool("c", false, "output only the number of selected records")
+	var maxcount = flag.Int("m", 0, "stop after the number of selected records")
+	var printfields = flag.Bool("p", false, "output matched field names and values")
+	var basecall = flag.Bool("b", false, "match the base callsign of the call field")
+	var mobile = flag.String("mobile", "", "mobile type for -b: mm, am, any, none")
 
 	var fp *os.File
 	var err error
@@ -31,11 +61,50 @@ func main() {
 		fmt.Fprintln(flag.CommandLine.Output(),
 			"goadifgrep: search specified ADIF field with a regex and output matched ADIF record")
 		fmt.Fprintf(flag.CommandLine.Output(),
-			"Usage: %s [-v] [-f infile] [-o outfile] field regex\n", execname)
+			"Usage: %s [-v] [-c] [-p] [-nh] [-m max] [-f infile] [-o outfile] field regex\n", execname)
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"       %s [-v] [-c] [-p] [-nh] [-m max] [-f infile] [-o outfile] -a regex\n", execname)
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"       %s [-v] [-c] [-p] [-nh] [-m max] [-f infile] [-o outfile] -e expression\n", execname)
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"       %s [-v] [-c] [-p] [-nh] [-m max] [-f infile] [-o outfile]\n"+
+				"          [-mobile type] -b callsign\n", execname)
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"-p output format: \"record number:field name:value\"\n"+
+				"   With -a, only the fields whose values matched are output\n"+
+				"   Otherwise, the fields referred by the query are output\n")
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"-b: match the base callsign of the call field\n"+
+				"    e.g., JA1ABC matches JA1ABC/P, W1/JA1ABC, and JA1ABC/MM\n"+
+				"-mobile: with -b, restrict the maritime/aeronautical mobile type:\n"+
+				"    mm (/MM), am (/AM), any (/MM or /AM), none (neither /MM nor /AM)\n"+
+				"    Note: /MM and /AM do not count for DXCC\n")
 		fmt.Fprintf(flag.CommandLine.Output(),
 			"Note: field name is case insensitive\n"+
 				"Note 2: regex is Go RE2 as defined in Go regexp package\n"+
 				"        Use \"(?i)\" flag prefix for case-insensitive matching\n")
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Expression operators:\n"+
+				"  field == \"value\", field != \"value\" (case insensitive)\n"+
+				"  field =~ \"regex\", field !~ \"regex\"\n"+
+				"  field < value, <=, >, >= (numeric if both are numbers)\n"+
+				"  field in (\"value1\", \"value2\", ...)\n"+
+				"  field between low high (both ends included)\n"+
+				"  has(field): field exists and is not empty\n"+
+				"  any(\"regex\"): any field value matches the regex\n"+
+				"  basecall(field, \"callsign\"): base callsign of the field matches\n"+
+				"  mobile(field, \"type\"): mobile type is mm, am, any, or none\n"+
+				"  !, &&, ||, and parentheses for grouping\n"+
+				"  Date values in YYYY-MM-DD are converted to YYYYMMDD\n"+
+				"  Number, Date, and Time fields (e.g., freq, tx_pwr, distance,\n"+
+				"  qso_date, time_on) are compared by the ADIF data type,\n"+
+				"  and unparseable values are reported to stderr\n"+
+				"  USERDEF fields of N, D, and T types in the input header\n"+
+				"  are compared as Number, Date, and Time\n"+
+				"Expression example:\n"+
+				"  band == \"20m\" && mode in (\"FT8\",\"FT4\") && "+
+				"!has(qsl_rcvd) && cont =~ \"EU\"\n"+
+				"  freq between 14.000 14.070 && tx_pwr <= 5 && qso_date >= 20230101\n")
 		flag.PrintDefaults()
 	}
 
@@ -51,7 +120,22 @@ func main() {
 		}
 	}
 
+	header, input, err := adifio.ReadHeader(fp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	// Compare user-defined fields by the declared data types
+	if _, err := header.Userdefs(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	types := header.UserdefTypes()
+	if *noheader {
+		header = nil
+	}
+
 	var writer adifparser.ADIFWriter
+	var textwriter *bufio.Writer
 	var writefp *os.File
 	if *outfile != "" {
 		if _, err := os.Stat(*outfile); os.IsNotExist(err) {
@@ -65,27 +149,81 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error: file %s already exists\n", *outfile)
 			return
 		}
-		writer = adifparser.NewADIFWriter(writefp)
+		writer = adifio.NewWriter(writefp, header, "goadifgrep")
+		textwriter = bufio.NewWriter(writefp)
 	} else {
 		writefp = nil
-		writer = adifparser.NewADIFWriter(os.Stdout)
+		writer = adifio.NewWriter(os.Stdout, header, "goadifgrep")
+		textwriter = bufio.NewWriter(os.Stdout)
 	}
 
-	if writer.SetComment("goadifgrep\n") != nil {
+	if err := writer.SetComment("goadifgrep\n"); err != nil {
 		fmt.Fprint(os.Stderr, err)
 		return
 	}
 
 	cliargs := flag.Args()
-	if len(cliargs) != 2 {
-		fmt.Fprint(os.Stderr, "Error: incorrect arguments\n")
-		flag.Usage()
-		return
+	var query adifexpr.Node
+	if *basecall {
+		if *anyfield || *expression != "" || len(cliargs) != 1 {
+			fmt.Fprint(os.Stderr, "Error: incorrect arguments\n")
+			flag.Usage()
+			return
+		}
+		query, err = adifexpr.NewBasecallNode("call", cliargs[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		if *mobile != "" {
+			mobilequery, err := adifexpr.NewMobileNode("call", *mobile)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return
+			}
+			query = adifexpr.NewAndNode(query, mobilequery)
+		}
+	} else if *anyfield {
+		if *expression != "" || len(cliargs) != 1 {
+			fmt.Fprint(os.Stderr, "Error: incorrect arguments\n")
+			flag.Usage()
+			return
+		}
+		query, err = adifexpr.NewAnyNode(cliargs[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+	} else if *expression != "" {
+		if len(cliargs) != 0 {
+			fmt.Fprint(os.Stderr, "Error: incorrect arguments\n")
+			flag.Usage()
+			return
+		}
+		query, err = adifexpr.Parse(*expression, types)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+	} else {
+		if len(cliargs) != 2 {
+			fmt.Fprint(os.Stderr, "Error: incorrect arguments\n")
+			flag.Usage()
+			return
+		}
+		query, err = adifexpr.NewMatchNode(cliargs[0], cliargs[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
 	}
-	var fieldname = strings.ToLower(cliargs[0])
-	var regpattern = regexp.MustCompile(cliargs[1])
 
-	reader := adifparser.NewADIFReader(fp)
+	// Text output instead of ADIF records
+	textoutput := *countonly || *printfields
+	count := 0
+	recordnumber := 0
+
+	reader := adifparser.NewADIFReader(input)
 	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
 		if err != nil {
 			if err != io.EOF {
@@ -93,18 +231,17 @@ func main() {
 			}
 			break // when io.EOF break the loop!
 		}
+		recordnumber++
 
-		// obtain selected field value
-		fieldvalue, err := record.GetValue(fieldname)
-		if err == adifparser.ErrNoSuchField {
-			fieldvalue = ""
-		} else if err != nil {
+		// Evaluate the query for the record
+		matched, err := query.Eval(record)
+		if err != nil {
 			fmt.Fprint(os.Stderr, err)
 			break
 		}
-
-		// Check regex pattern matching
-		matched := regpattern.MatchString(fieldvalue)
+		for _, warning := range query.Warnings(record) {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
 		var selected bool
 		if *invertmatch {
 			selected = !matched
@@ -112,14 +249,39 @@ func main() {
 			selected = matched
 		}
 
+		if !selected {
+			continue
+		}
+		count++
+
 		// Output selected record
-		if selected {
-			writer.WriteRecord(record)
+		if *printfields && !*countonly {
+			for _, field := range query.Fields(record) {
+				value, _ := record.GetValue(field)
+				fmt.Fprintf(textwriter, "%d:%s:%s\n",
+					recordnumber, field, value)
+			}
+		} else if !textoutput {
+			if err := writer.WriteRecord(record); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
 		}
+
+		if *maxcount > 0 && count >= *maxcount {
+			break
+		}
+	}
+
+	if *countonly {
+		fmt.Fprintln(textwriter, count)
 	}
 
 	// Flush and close the output
-	writer.Flush()
+	if textoutput {
+		textwriter.Flush()
+	} else {
+		writer.Flush()
+	}
 	if writefp != os.Stdout {
 		writefp.Close()
 	}
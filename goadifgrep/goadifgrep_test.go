@@ -0,0 +1,114 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+const testMainEnv = "GOADIFGREP_TEST_MAIN"
+
+// Run main() in a child process of the test binary
+func TestMain(m *testing.M) {
+	testutil.Main(m, testMainEnv, main)
+}
+
+// Run goadifgrep with the input and the arguments, and return stdout
+func runGrep(t *testing.T, input string, args ...string) string {
+	t.Helper()
+	stdout, _ := testutil.Run(t, testMainEnv, input, args...)
+	return stdout
+}
+
+const testLog = "<call:5>A1AAA<band:3>20m<mode:2>CW<comment:4>test<eor>\n" +
+	"<call:5>A1AAB<band:3>40m<mode:3>FT8<eor>\n" +
+	"<call:8>JA1ABC/P<band:3>20m<mode:3>FT8<notes:7>Testing<eor>\n" +
+	"<call:9>JA1ABC/MM<band:3>15m<mode:3>SSB<eor>\n"
+
+func TestGrepModes(t *testing.T) {
+	tests := []struct {
+		args  []string
+		calls []string
+	}{
+		{[]string{"band", "^20m$"}, []string{"A1AAA", "JA1ABC/P"}},
+		{[]string{"-v", "band", "^20m$"}, []string{"A1AAB", "JA1ABC/MM"}},
+		{[]string{"-m", "1", "band", "^20m$"}, []string{"A1AAA"}},
+		{[]string{"-a", "(?i)test"}, []string{"A1AAA", "JA1ABC/P"}},
+		{[]string{"-e", `band == "20m" && mode == "FT8"`}, []string{"JA1ABC/P"}},
+		{[]string{"-b", "ja1abc"}, []string{"JA1ABC/P", "JA1ABC/MM"}},
+		{[]string{"-b", "-mobile", "none", "JA1ABC"}, []string{"JA1ABC/P"}},
+		{[]string{"-b", "-mobile", "mm", "JA1ABC"}, []string{"JA1ABC/MM"}},
+	}
+	for _, tt := range tests {
+		got := testutil.Calls(t, runGrep(t, testLog, tt.args...))
+		if !reflect.DeepEqual(got, tt.calls) {
+			t.Errorf("goadifgrep %v = %v, want %v", tt.args, got, tt.calls)
+		}
+	}
+}
+
+func TestGrepTextOutput(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-c", "band", "^20m$"}, "2\n"},
+		{[]string{"-c", "-v", "band", "^20m$"}, "2\n"},
+		{[]string{"-c", "-m", "1", "band", "^20m$"}, "1\n"},
+		{[]string{"-c", "band", "^80m$"}, "0\n"},
+		{[]string{"-p", "-a", "(?i)test"}, "1:comment:test\n3:notes:Testing\n"},
+		{[]string{"-p", "-e", `band == "15m" || mode == "CW"`}, "1:band:20m\n1:mode:CW\n4:band:15m\n4:mode:SSB\n"},
+		{[]string{"-p", "-m", "1", "mode", "FT8"}, "2:mode:FT8\n"},
+	}
+	for _, tt := range tests {
+		if got := runGrep(t, testLog, tt.args...); got != tt.want {
+			t.Errorf("goadifgrep %v = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestGrepUserdefTypes(t *testing.T) {
+	input := "log <userdef1:5:T>SHIPT<eoh>\n" +
+		"<call:4>A1AA<shipt:4>0830<eor>\n" +
+		"<call:4>A1AB<shipt:4>0900<eor>\n"
+	// SHIPT is compared as Time
+	got := testutil.Calls(t, runGrep(t, input, "-e", `shipt == 083000`))
+	if !reflect.DeepEqual(got, []string{"A1AA"}) {
+		t.Errorf("goadifgrep = %v", got)
+	}
+}
+
+func TestGrepWarnings(t *testing.T) {
+	input := "<call:4>A1AA<freq:4>abcd<eor>\n" +
+		"<call:4>A1AB<freq:6>14.074<eor>\n"
+	stdout, stderr := testutil.Run(t, testMainEnv, input, "-e", "freq > 14")
+	if got := testutil.Calls(t, stdout); !reflect.DeepEqual(got, []string{"A1AB"}) {
+		t.Errorf("goadifgrep = %v", got)
+	}
+	want := "Warning: freq value \"abcd\" is not a valid Number (call: A1AA)\n"
+	if stderr != want {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+}
+
+func TestGrepHeader(t *testing.T) {
+	input := "Test log <adif_ver:5>3.1.4<userdef1:3:N>EPC<eoh>\n" + testLog
+	for _, tt := range []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"band", "^20m$"}, true},
+		{[]string{"-nh", "band", "^20m$"}, false},
+	} {
+		got := runGrep(t, input, tt.args...)
+		if strings.Contains(got, "<userdef1:3:N>EPC") != tt.want {
+			t.Errorf("goadifgrep %v: header passed through = %v, want %v: %q",
+				tt.args, !tt.want, tt.want, got)
+		}
+		if calls := testutil.Calls(t, got); !reflect.DeepEqual(calls, []string{"A1AAA", "JA1ABC/P"}) {
+			t.Errorf("goadifgrep %v = %v", tt.args, calls)
+		}
+	}
+}
@@ -0,0 +1,552 @@
+// goadifreport: output logbook statistics report in HTML
+// by Kenji Rikitake, JJ1BDX
+// Usage: goadifreport [-f infile] [-o outfile] [-title title]
+//        [-n recent QSOs] [-t time series period]
+//
+// The report is a single self-contained HTML file
+// without any external assets, including:
+//  summary: QSO count, period, DXCC entities, CQ/ITU zones, grid squares
+//  band and mode tables
+//  DXCC entity table (entity names, prefixes, and continents
+//   resolved from the dxcc field with cty.xml of Club Log through gocldb,
+//   as goadifstat -q dxcc)
+//  worked CQ and ITU zones
+//  QSO count time series chart as inline SVG
+//   (all periods from the first to the last QSO including periods without QSOs;
+//    consecutive periods are combined into a bar
+//    if there are more periods than the chart width allows)
+//  recent QSO table (countries resolved from the dxcc field if possible)
+// Valid time series periods: day, month (default), year
+// Time of ADIF record determined by: qso_date and time_on
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifband"
+	"github.com/jj1bdx/goadiftools/adiftime"
+	"github.com/jj1bdx/goadiftools/dxcc"
+)
+
+var ErrNoSuchField = adifparser.ErrNoSuchField
+
+// Chart size in pixels
+const chartWidth = 720
+const chartHeight = 240
+const chartMargin = 32
+
+// Minimum horizontal space of a bar in pixels
+const chartMinSlot = 2
+
+// Name and count pair for tables
+type countEntry struct {
+	Name  string
+	Count int
+}
+
+// DXCC entity table entry
+type dxccEntry struct {
+	dxcc.Entity
+	Count int
+}
+
+// Bar of the time series chart
+type chartBar struct {
+	Label  string
+	Count  int
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+// Recent QSO table entry
+type qsoEntry struct {
+	time    time.Time
+	Date    string
+	Time    string
+	Call    string
+	Band    string
+	Mode    string
+	Country string
+}
+
+// All data for the report template
+type reportData struct {
+	Title       string
+	Generated   string
+	NQSO        int
+	FirstQSO    string
+	LastQSO     string
+	NGrid       int
+	Bands       []countEntry
+	Modes       []countEntry
+	Dxcc        []dxccEntry
+	Cqz         []int
+	Ituz        []int
+	Chart       []chartBar
+	ChartWidth  int
+	ChartHeight int
+	ChartBase   int
+	ChartMargin int
+	ChartMax    int
+	Period      string
+	Recent      []qsoEntry
+}
+
+// Statistics of the report
+type reportStats struct {
+	band   map[string]int
+	mode   map[string]int
+	dxcc   map[int]int
+	cqz    map[int]bool
+	ituz   map[int]bool
+	grid   map[string]bool
+	period map[string]int
+	// Most recent QSOs, newest first (at most nrecent)
+	recent  []qsoEntry
+	nrecent int
+	// Time of the first and the last QSOs (valid if ntimed > 0)
+	first  time.Time
+	last   time.Time
+	ntimed int
+}
+
+// Create empty statistics keeping nrecent most recent QSOs
+func newReportStats(nrecent int) *reportStats {
+	if nrecent < 0 {
+		nrecent = 0
+	}
+	return &reportStats{
+		band:    make(map[string]int),
+		mode:    make(map[string]int),
+		dxcc:    make(map[int]int),
+		cqz:     make(map[int]bool),
+		ituz:    make(map[int]bool),
+		grid:    make(map[string]bool),
+		period:  make(map[string]int),
+		nrecent: nrecent,
+	}
+}
+
+// Obtain a field value, or empty string if the field does not exist
+func getField(record adifparser.ADIFRecord, field string) string {
+	value, err := record.GetValue(field)
+	if err != nil && err != ErrNoSuchField {
+		fmt.Fprint(os.Stderr, err)
+	}
+	return value
+}
+
+// Time layout of the time series period labels
+func periodLayout(period string) string {
+	switch period {
+	case "day":
+		return "2006-01-02"
+	case "year":
+		return "2006"
+	default:
+		return "2006-01"
+	}
+}
+
+// Return the time series period label of the time
+func periodLabel(t time.Time, period string) string {
+	return t.Format(periodLayout(period))
+}
+
+// Return the labels of all periods from the first to the last label
+func periodLabels(first, last, period string) []string {
+	layout := periodLayout(period)
+	t, err := time.Parse(layout, first)
+	if err != nil {
+		return nil
+	}
+	end, err := time.Parse(layout, last)
+	if err != nil {
+		return nil
+	}
+	labels := []string{}
+	for !t.After(end) {
+		labels = append(labels, t.Format(layout))
+		switch period {
+		case "day":
+			t = t.AddDate(0, 0, 1)
+		case "year":
+			t = t.AddDate(1, 0, 0)
+		default:
+			t = t.AddDate(0, 1, 0)
+		}
+	}
+	return labels
+}
+
+// Add a QSO to the most recent QSOs
+// QSOs of the same time are kept in the input order
+func (s *reportStats) addRecent(q qsoEntry) {
+	i := sort.Search(len(s.recent), func(i int) bool {
+		return s.recent[i].time.Before(q.time)
+	})
+	if i >= s.nrecent {
+		return
+	}
+	if len(s.recent) < s.nrecent {
+		s.recent = append(s.recent, qsoEntry{})
+	}
+	copy(s.recent[i+1:], s.recent[i:])
+	s.recent[i] = q
+}
+
+// Update the statistics with the record
+func (s *reportStats) update(record adifparser.ADIFRecord, period string) {
+	band := strings.ToLower(getField(record, "band"))
+	if band != "" {
+		s.band[band]++
+	}
+	mode := strings.ToUpper(getField(record, "mode"))
+	if mode != "" {
+		s.mode[mode]++
+	}
+	country := strings.ToUpper(getField(record, "country"))
+	dxcccode := getField(record, "dxcc")
+	if dxcccode != "" {
+		code, err := strconv.Atoi(dxcccode)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+		} else {
+			s.dxcc[code]++
+			if entity, known := dxcc.Lookup(code); known {
+				country = entity.Name
+			}
+		}
+	}
+	cqz := getField(record, "cqz")
+	if cqz != "" {
+		n, err := strconv.Atoi(cqz)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+		} else {
+			s.cqz[n] = true
+		}
+	}
+	ituz := getField(record, "ituz")
+	if ituz != "" {
+		n, err := strconv.Atoi(ituz)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+		} else {
+			s.ituz[n] = true
+		}
+	}
+	grid := getField(record, "gridsquare")
+	if len(grid) >= 4 {
+		s.grid[strings.ToUpper(grid[0:4])] = true
+	}
+
+	qsotime, err := adiftime.RecordTime(record)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	s.period[periodLabel(qsotime, period)]++
+	if s.ntimed == 0 || qsotime.Before(s.first) {
+		s.first = qsotime
+	}
+	if s.ntimed == 0 || qsotime.After(s.last) {
+		s.last = qsotime
+	}
+	s.ntimed++
+	s.addRecent(qsoEntry{
+		time:    qsotime,
+		Date:    qsotime.Format("2006-01-02"),
+		Time:    qsotime.Format("15:04"),
+		Call:    strings.ToUpper(getField(record, "call")),
+		Band:    band,
+		Mode:    mode,
+		Country: country,
+	})
+}
+
+// Convert a count map into a slice sorted by name
+func sortedCounts(m map[string]int) []countEntry {
+	entries := make([]countEntry, 0, len(m))
+	for k, v := range m {
+		entries = append(entries, countEntry{k, v})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// Convert a set of integers into a sorted slice
+func sortedInts(m map[int]bool) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// Build the bars of the time series chart from the QSO counts of periods
+// All periods from the first to the last are shown,
+// including periods without QSOs
+// Consecutive periods are combined into a bar labeled "first - last"
+// so that each bar has at least chartMinSlot pixels
+func buildChart(data *reportData, periods map[string]int, period string) {
+	labels := make([]string, 0, len(periods))
+	for k := range periods {
+		labels = append(labels, k)
+	}
+	sort.Strings(labels)
+	if len(labels) > 0 {
+		labels = periodLabels(labels[0], labels[len(labels)-1], period)
+	}
+	data.ChartWidth = chartWidth
+	data.ChartHeight = chartHeight
+	data.ChartBase = chartHeight - chartMargin
+	data.ChartMargin = chartMargin
+	data.ChartMax = 0
+	if len(labels) == 0 {
+		return
+	}
+	plotwidth := chartWidth - 2*chartMargin
+	plotheight := chartHeight - 2*chartMargin
+	maxbars := plotwidth / chartMinSlot
+	// Number of periods per bar
+	group := (len(labels) + maxbars - 1) / maxbars
+	bars := []chartBar{}
+	for i := 0; i < len(labels); i += group {
+		last := i + group - 1
+		if last >= len(labels) {
+			last = len(labels) - 1
+		}
+		bar := chartBar{Label: labels[i]}
+		if last > i {
+			bar.Label = labels[i] + " - " + labels[last]
+		}
+		for _, label := range labels[i : last+1] {
+			bar.Count += periods[label]
+		}
+		if bar.Count > data.ChartMax {
+			data.ChartMax = bar.Count
+		}
+		bars = append(bars, bar)
+	}
+	slot := plotwidth / len(bars)
+	width := slot * 4 / 5
+	if width < 1 {
+		width = 1
+	}
+	for i := range bars {
+		height := bars[i].Count * plotheight / data.ChartMax
+		bars[i].X = chartMargin + i*slot
+		bars[i].Y = data.ChartBase - height
+		bars[i].Width = width
+		bars[i].Height = height
+	}
+	data.Chart = bars
+}
+
+const reportTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #aaa; padding: 0.2em 0.6em; }
+th { background: #eee; }
+td.num { text-align: right; }
+svg text { font-size: 10px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Generated: {{.Generated}}</p>
+<h2>Summary</h2>
+<table>
+<tr><th>QSOs</th><td class="num">{{.NQSO}}</td></tr>
+<tr><th>First QSO</th><td>{{.FirstQSO}}</td></tr>
+<tr><th>Last QSO</th><td>{{.LastQSO}}</td></tr>
+<tr><th>DXCC entities</th><td class="num">{{len .Dxcc}}</td></tr>
+<tr><th>CQ zones</th><td class="num">{{len .Cqz}}</td></tr>
+<tr><th>ITU zones</th><td class="num">{{len .Ituz}}</td></tr>
+<tr><th>Grid squares</th><td class="num">{{.NGrid}}</td></tr>
+</table>
+<h2>QSOs per {{.Period}}</h2>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{.ChartWidth}}" height="{{.ChartHeight}}" viewBox="0 0 {{.ChartWidth}} {{.ChartHeight}}">
+<line x1="{{.ChartMargin}}" y1="{{.ChartBase}}" x2="{{.ChartWidth}}" y2="{{.ChartBase}}" stroke="#222"/>
+<text x="2" y="{{.ChartMargin}}" dy="8">{{.ChartMax}}</text>
+{{range .Chart}}<rect x="{{.X}}" y="{{.Y}}" width="{{.Width}}" height="{{.Height}}" fill="#4878a8"><title>{{.Label}}: {{.Count}}</title></rect>
+{{end}}{{with .Chart}}<text x="{{$.ChartMargin}}" y="{{$.ChartHeight}}" dy="-12">{{(index . 0).Label}}</text>
+<text x="{{$.ChartWidth}}" y="{{$.ChartHeight}}" dy="-12" dx="-4" text-anchor="end">{{(index . (len . | dec)).Label}}</text>
+{{end}}</svg>
+<h2>Bands</h2>
+<table>
+<tr><th>Band</th><th>QSOs</th></tr>
+{{range .Bands}}<tr><td>{{.Name}}</td><td class="num">{{.Count}}</td></tr>
+{{end}}</table>
+<h2>Modes</h2>
+<table>
+<tr><th>Mode</th><th>QSOs</th></tr>
+{{range .Modes}}<tr><td>{{.Name}}</td><td class="num">{{.Count}}</td></tr>
+{{end}}</table>
+<h2>DXCC entities</h2>
+<table>
+<tr><th>Code</th><th>Prefix</th><th>Cont</th><th>Entity</th><th>QSOs</th></tr>
+{{range .Dxcc}}<tr><td class="num">{{.Code}}</td><td>{{.Prefix}}</td><td>{{.Cont}}</td><td>{{.Name}}{{if .Deleted}} (DELETED){{end}}</td><td class="num">{{.Count}}</td></tr>
+{{end}}</table>
+<h2>Zones</h2>
+<p>CQ zones: {{range .Cqz}}{{.}} {{end}}</p>
+<p>ITU zones: {{range .Ituz}}{{.}} {{end}}</p>
+<h2>Recent QSOs</h2>
+<table>
+<tr><th>Date</th><th>Time (UTC)</th><th>Call</th><th>Band</th><th>Mode</th><th>Country</th></tr>
+{{range .Recent}}<tr><td>{{.Date}}</td><td>{{.Time}}</td><td>{{.Call}}</td><td>{{.Band}}</td><td>{{.Mode}}</td><td>{{.Country}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`
+
+func main() {
+	var infile = flag.String("f", "", "input file (stdin if none)")
+	var outfile = flag.String("o", "", "output file (stdout if none)")
+	var title = flag.String("title", "Logbook Statistics", "report title")
+	var nrecent = flag.Int("n", 20, "number of recent QSOs")
+	var period = flag.String("t", "month", "time series period")
+	var fp *os.File
+	var err error
+
+	flag.Usage = func() {
+		execname := os.Args[0]
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"goadifreport: output logbook statistics report in HTML")
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [-f infile] [-o outfile] [-title title]\n"+
+				"       [-n recent QSOs] [-t time series period]\n", execname)
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"Valid time series periods: day, month (default), year")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	switch *period {
+	case "day", "month", "year":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown period %s\n", *period)
+		flag.Usage()
+		return
+	}
+
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"dec": func(n int) int { return n - 1 },
+	}).Parse(reportTemplate)
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		return
+	}
+
+	if *infile == "" {
+		fp = os.Stdin
+	} else {
+		fp, err = os.Open(*infile)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+			return
+		}
+	}
+
+	var writefp *os.File
+	var writer io.Writer
+	if *outfile != "" {
+		if _, err := os.Stat(*outfile); os.IsNotExist(err) {
+			// File does not exist: create it
+			writefp, err = os.Create(*outfile)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				return
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: file %s already exists\n", *outfile)
+			return
+		}
+		writer = io.Writer(writefp)
+	} else {
+		writefp = nil
+		writer = io.Writer(os.Stdout)
+	}
+
+	if err := dxcc.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v: DXCC entity names are not shown\n", err)
+	}
+
+	stats := newReportStats(*nrecent)
+	reader := adifparser.NewADIFReader(fp)
+	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprint(os.Stderr, err)
+			}
+			break // when io.EOF break the loop!
+		}
+		stats.update(record, *period)
+	}
+
+	var data reportData
+	data.Title = *title
+	data.Generated = time.Now().UTC().Format("2006-01-02 15:04:05 UTC")
+	data.NQSO = reader.RecordCount()
+	data.NGrid = len(stats.grid)
+	data.Period = *period
+
+	// Bands in the frequency order, then unknown bands
+	for _, band := range adifband.Bands {
+		if num, exists := stats.band[band]; exists {
+			data.Bands = append(data.Bands, countEntry{band, num})
+			delete(stats.band, band)
+		}
+	}
+	data.Bands = append(data.Bands, sortedCounts(stats.band)...)
+	data.Modes = sortedCounts(stats.mode)
+	for code, count := range stats.dxcc {
+		entity, _ := dxcc.Lookup(code)
+		data.Dxcc = append(data.Dxcc, dxccEntry{entity, count})
+	}
+	sort.Slice(data.Dxcc, func(i, j int) bool {
+		return data.Dxcc[i].Code < data.Dxcc[j].Code
+	})
+	data.Cqz = sortedInts(stats.cqz)
+	data.Ituz = sortedInts(stats.ituz)
+	buildChart(&data, stats.period, *period)
+
+	// Recent QSOs: newest first
+	if stats.ntimed > 0 {
+		data.LastQSO = stats.last.Format("2006-01-02 15:04 UTC")
+		data.FirstQSO = stats.first.Format("2006-01-02 15:04 UTC")
+	}
+	data.Recent = stats.recent
+
+	err = tmpl.Execute(writer, data)
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+	}
+
+	// Close output here
+	if writefp != nil {
+		writefp.Close()
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/gocldb"
+)
+
+func TestPeriodLabel(t *testing.T) {
+	qsotime := time.Date(2023, 11, 25, 8, 30, 0, 0, time.UTC)
+	tests := map[string]string{
+		"day":   "2023-11-25",
+		"month": "2023-11",
+		"year":  "2023",
+	}
+	for period, want := range tests {
+		if got := periodLabel(qsotime, period); got != want {
+			t.Errorf("periodLabel(%q) = %q, want %q", period, got, want)
+		}
+	}
+}
+
+func TestBuildChart(t *testing.T) {
+	var data reportData
+	buildChart(&data, map[string]int{"2023-01": 10, "2023-03": 5, "2023-02": 20}, "month")
+	if len(data.Chart) != 3 || data.ChartMax != 20 {
+		t.Fatalf("buildChart() = %+v, max %d", data.Chart, data.ChartMax)
+	}
+	plotheight := chartHeight - 2*chartMargin
+	for i, want := range []chartBar{
+		{Label: "2023-01", Count: 10, Height: plotheight / 2},
+		{Label: "2023-02", Count: 20, Height: plotheight},
+		{Label: "2023-03", Count: 5, Height: plotheight / 4},
+	} {
+		bar := data.Chart[i]
+		if bar.Label != want.Label || bar.Count != want.Count || bar.Height != want.Height {
+			t.Errorf("bar %d = %+v, want %+v", i, bar, want)
+		}
+		if bar.Y+bar.Height != data.ChartBase {
+			t.Errorf("bar %d is not on the base line: %+v", i, bar)
+		}
+	}
+
+	var empty reportData
+	buildChart(&empty, map[string]int{}, "month")
+	if len(empty.Chart) != 0 || empty.ChartWidth != chartWidth {
+		t.Errorf("buildChart(empty) = %+v", empty)
+	}
+}
+
+func TestBuildChartGaps(t *testing.T) {
+	// Periods without QSOs are shown as zero
+	var data reportData
+	buildChart(&data, map[string]int{"2023-11": 3, "2024-02": 6}, "month")
+	got := []string{}
+	for _, bar := range data.Chart {
+		got = append(got, fmt.Sprintf("%s:%d", bar.Label, bar.Count))
+	}
+	if want := "2023-11:3 2023-12:0 2024-01:0 2024-02:6"; strings.Join(got, " ") != want {
+		t.Errorf("bars = %q, want %q", strings.Join(got, " "), want)
+	}
+	if data.Chart[1].Height != 0 || data.Chart[1].Y != data.ChartBase {
+		t.Errorf("empty bar = %+v", data.Chart[1])
+	}
+
+	// Combined bars cover the gaps
+	var years reportData
+	buildChart(&years, map[string]int{"2000-01-01": 1, "2003-12-31": 2}, "day")
+	if len(years.Chart) < 2 {
+		t.Fatalf("bars = %+v", years.Chart)
+	}
+	if first := years.Chart[0].Label; !strings.HasPrefix(first, "2000-01-01 - ") {
+		t.Errorf("first label = %q", first)
+	}
+	if last := years.Chart[len(years.Chart)-1].Label; !strings.HasSuffix(last, "2003-12-31") {
+		t.Errorf("last label = %q", last)
+	}
+}
+
+func TestPeriodLabels(t *testing.T) {
+	tests := []struct {
+		first, last, period string
+		want                string
+	}{
+		{"2023-12-30", "2024-01-02", "day", "2023-12-30 2023-12-31 2024-01-01 2024-01-02"},
+		{"2023-11", "2024-01", "month", "2023-11 2023-12 2024-01"},
+		{"2021", "2023", "year", "2021 2022 2023"},
+		{"2023", "2023", "year", "2023"},
+	}
+	for _, tt := range tests {
+		got := strings.Join(periodLabels(tt.first, tt.last, tt.period), " ")
+		if got != tt.want {
+			t.Errorf("periodLabels(%q, %q, %q) = %q, want %q",
+				tt.first, tt.last, tt.period, got, tt.want)
+		}
+	}
+}
+
+func TestBuildChartManyPeriods(t *testing.T) {
+	// Daily periods over two years are more than the chart width allows
+	periods := make(map[string]int)
+	total := 0
+	day := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 730; i++ {
+		periods[periodLabel(day.AddDate(0, 0, i), "day")] = i%7 + 1
+		total += i%7 + 1
+	}
+	var data reportData
+	buildChart(&data, periods, "day")
+	if len(data.Chart) == 0 || len(data.Chart) >= len(periods) {
+		t.Fatalf("bars = %d, want combined bars", len(data.Chart))
+	}
+	sum := 0
+	for _, bar := range data.Chart {
+		sum += bar.Count
+		if bar.X < chartMargin || bar.X+bar.Width > chartWidth-chartMargin {
+			t.Errorf("bar out of the plot area: %+v", bar)
+		}
+		if bar.Width < 1 || bar.Height > chartHeight-2*chartMargin {
+			t.Errorf("invalid bar size: %+v", bar)
+		}
+	}
+	if sum != total {
+		t.Errorf("total count = %d, want %d", sum, total)
+	}
+	if first := data.Chart[0].Label; !strings.HasPrefix(first, "2022-01-01 - ") {
+		t.Errorf("first label = %q", first)
+	}
+	if last := data.Chart[len(data.Chart)-1].Label; !strings.HasSuffix(last, "2023-12-31") {
+		t.Errorf("last label = %q", last)
+	}
+}
+
+func TestReportStatsUpdate(t *testing.T) {
+	gocldb.CLDMapEntityByAdif[339] = gocldb.CLDEntityByAdif{
+		Name: "JAPAN", Prefix: "JA", Cont: "AS"}
+	input := "<call:6>jj1bdx<qso_date:8>20231125<time_on:4>0830<band:2>8M<mode:3>ft8<dxcc:3>339<country:5>Nihon<eor>\n" +
+		"<call:5>A1AAA<qso_date:8>20231126<time_on:4>0100<band:5>submm<mode:2>CW<dxcc:3>999<country:7>Nowhere<eor>\n"
+	stats := newReportStats(10)
+	reader := adifparser.NewADIFReader(strings.NewReader(input))
+	for record, err := reader.ReadRecord(); err == nil; record, err = reader.ReadRecord() {
+		stats.update(record, "month")
+	}
+	if stats.band["8m"] != 1 || stats.band["submm"] != 1 {
+		t.Errorf("band = %v", stats.band)
+	}
+	if stats.mode["FT8"] != 1 || stats.period["2023-11"] != 2 {
+		t.Errorf("mode = %v, period = %v", stats.mode, stats.period)
+	}
+	if len(stats.recent) != 2 {
+		t.Fatalf("recent = %v", stats.recent)
+	}
+	// Newest first
+	// Country from the DXCC entity if known, otherwise from the country field
+	got := fmt.Sprintf("%s %s %s/%s %s %s", stats.recent[1].Call, stats.recent[1].Country,
+		stats.recent[1].Date, stats.recent[1].Time, stats.recent[0].Call, stats.recent[0].Country)
+	if want := "JJ1BDX JAPAN 2023-11-25/08:30 A1AAA NOWHERE"; got != want {
+		t.Errorf("recent = %q, want %q", got, want)
+	}
+}
+
+func TestReportStatsRecent(t *testing.T) {
+	// Only the most recent QSOs are kept regardless of the input order
+	stats := newReportStats(3)
+	input := ""
+	for _, day := range []int{5, 1, 9, 3, 7, 9, 2, 8} {
+		input += fmt.Sprintf("<call:4>CALL<qso_date:8>202301%02d<time_on:4>1200<comment:1>%d<eor>\n", day, day)
+	}
+	reader := adifparser.NewADIFReader(strings.NewReader(input))
+	n := 0
+	for record, err := reader.ReadRecord(); err == nil; record, err = reader.ReadRecord() {
+		stats.update(record, "day")
+		n++
+		if len(stats.recent) > 3 {
+			t.Fatalf("record %d: recent = %d entries", n, len(stats.recent))
+		}
+	}
+	got := []string{}
+	for _, q := range stats.recent {
+		got = append(got, q.Date)
+	}
+	if want := "2023-01-09 2023-01-09 2023-01-08"; strings.Join(got, " ") != want {
+		t.Errorf("recent = %q, want %q", strings.Join(got, " "), want)
+	}
+	if first := stats.first.Format("2006-01-02"); first != "2023-01-01" {
+		t.Errorf("first = %s", first)
+	}
+	if last := stats.last.Format("2006-01-02"); last != "2023-01-09" {
+		t.Errorf("last = %s", last)
+	}
+
+	// No recent QSOs
+	none := newReportStats(0)
+	reader = adifparser.NewADIFReader(strings.NewReader(input))
+	for record, err := reader.ReadRecord(); err == nil; record, err = reader.ReadRecord() {
+		none.update(record, "day")
+	}
+	if len(none.recent) != 0 || none.ntimed != 8 {
+		t.Errorf("recent = %v, ntimed = %d", none.recent, none.ntimed)
+	}
+}
@@ -0,0 +1,288 @@
+// goadifsession: group QSOs into operating sessions separated by gaps
+// by Kenji Rikitake, JJ1BDX
+// Usage: goadifsession [-f infile] [-o outfile] [-g minutes] [-t field]
+// -nh: do not pass through the input ADIF header
+// -g: minimum gap between sessions in minutes (default 30)
+// -t: output ADIF records with the session number in the field
+//     instead of the session report
+//     (e.g., -t app_goadifsession_id)
+//     The field name is case insensitive, and a field other than
+//     the standard and APP_ fields is declared as a USERDEF field of Number
+// Time of ADIF record determined by: qso_date and time_on
+// The input must be sorted by time in increasing order (e.g., by goadiftime)
+// Records out of the time order start a new session with a warning
+//
+// Session report output format:
+//   session N: start - end duration D qsos Q
+//     bands: band count ...
+//     modes: mode count ...
+//   (SESSIONS): number of sessions
+// Bands and modes are listed in the order of appearance
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifio"
+	"github.com/jj1bdx/goadiftools/adiftime"
+)
+
+var ErrNoSuchField = adifparser.ErrNoSuchField
+
+var regFieldName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Counts of values in the order of appearance
+type orderedCount struct {
+	keys   []string
+	counts map[string]int
+}
+
+func newOrderedCount() *orderedCount {
+	return &orderedCount{counts: make(map[string]int)}
+}
+
+func (c *orderedCount) add(key string) {
+	if _, exists := c.counts[key]; !exists {
+		c.keys = append(c.keys, key)
+	}
+	c.counts[key]++
+}
+
+func (c *orderedCount) output(writer *bufio.Writer) {
+	for _, k := range c.keys {
+		fmt.Fprintf(writer, "%s %d ", k, c.counts[k])
+	}
+	fmt.Fprintf(writer, "\n")
+}
+
+// Operating session
+type session struct {
+	id    int
+	start time.Time
+	end   time.Time
+	nqso  int
+	bands *orderedCount
+	modes *orderedCount
+}
+
+func newSession(id int, start time.Time) *session {
+	return &session{
+		id:    id,
+		start: start,
+		end:   start,
+		bands: newOrderedCount(),
+		modes: newOrderedCount(),
+	}
+}
+
+// Add a record to the session
+func (s *session) add(recordtime time.Time, record adifparser.ADIFRecord) {
+	s.end = recordtime
+	s.nqso++
+	// Bands in lowercase, modes in uppercase
+	for _, f := range []struct {
+		field string
+		count *orderedCount
+		conv  func(string) string
+	}{{"band", s.bands, strings.ToLower}, {"mode", s.modes, strings.ToUpper}} {
+		value, err := record.GetValue(f.field)
+		if err != nil && err != ErrNoSuchField {
+			fmt.Fprint(os.Stderr, err)
+		}
+		if value == "" {
+			f.count.add("(UNKNOWN)")
+		} else {
+			f.count.add(f.conv(value))
+		}
+	}
+}
+
+func (s *session) output(writer *bufio.Writer) {
+	fmt.Fprintf(writer, "session %d: %s - %s duration %s qsos %d\n",
+		s.id, s.start.Format(time.RFC3339), s.end.Format(time.RFC3339),
+		s.end.Sub(s.start), s.nqso)
+	fmt.Fprintf(writer, "  bands: ")
+	s.bands.output(writer)
+	fmt.Fprintf(writer, "  modes: ")
+	s.modes.output(writer)
+}
+
+// Declare the tag field as a USERDEF field of Number
+// unless it is a standard, an APP_, or an already declared field
+func declareTagField(header *adifio.Header, tag string) {
+	if adifio.IsStandardField(tag) || strings.HasPrefix(tag, "app_") {
+		return
+	}
+	if _, exists := header.Userdef(tag); exists {
+		return
+	}
+	header.AddUserdef(adifio.Userdef{Name: tag, Type: "N"})
+}
+
+func main() {
+	var infile = flag.String("f", "", "input file (stdout in none)")
+	var outfile = flag.String("o", "", "output file (stdout if none)")
+	var noheader = flag.Bool("nh", false, "do not pass through the input header")
+	var gapminutes = flag.Int("g", 30, "minimum gap between sessions in minutes")
+	var tagfield = flag.String("t", "", "field name to tag records with the session number")
+
+	var fp *os.File
+	var err error
+
+	flag.Usage = func() {
+		execname := os.Args[0]
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"goadifsession: group QSOs into operating sessions separated by gaps")
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [-f infile] [-o outfile] [-g minutes] [-t field]\n", execname)
+		flag.PrintDefaults()
+		details :=
+			"-t: output ADIF records with the session number in the field\n" +
+				"    instead of the session report\n" +
+				"    (e.g., -t app_goadifsession_id)\n" +
+				"    The field name is case insensitive, and a field other than\n" +
+				"    the standard and APP_ fields is declared as a USERDEF field of Number\n" +
+				"Time of ADIF record determined by: qso_date and time_on\n" +
+				"The input must be sorted by time in increasing order (e.g., by goadiftime)\n" +
+				"Records out of the time order start a new session with a warning\n" +
+				"Session report output format:\n" +
+				"  session N: start - end duration D qsos Q\n" +
+				"    bands: band count ...\n" +
+				"    modes: mode count ...\n" +
+				"  (SESSIONS): number of sessions\n"
+		fmt.Fprint(flag.CommandLine.Output(), details)
+	}
+
+	flag.Parse()
+
+	if *gapminutes <= 0 {
+		fmt.Fprint(os.Stderr, errors.New("gap must be positive"))
+		return
+	}
+	gap := time.Duration(*gapminutes) * time.Minute
+	tag := strings.ToLower(strings.TrimSpace(*tagfield))
+	tagmode := tag != ""
+	if tagmode && !regFieldName.MatchString(tag) {
+		fmt.Fprint(os.Stderr, fmt.Errorf("invalid field name %q", *tagfield))
+		return
+	}
+
+	if *infile == "" {
+		fp = os.Stdin
+	} else {
+		fp, err = os.Open(*infile)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+			return
+		}
+	}
+
+	header, input, err := adifio.ReadHeader(fp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if *noheader {
+		header = nil
+	}
+	if tagmode && header != nil {
+		declareTagField(header, tag)
+	}
+
+	var writer adifparser.ADIFWriter
+	var textwriter *bufio.Writer
+	var writefp *os.File
+	if *outfile != "" {
+		if _, err := os.Stat(*outfile); os.IsNotExist(err) {
+			// File does not exist: create it
+			writefp, err = os.Create(*outfile)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				return
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: file %s already exists\n", *outfile)
+			return
+		}
+		writer = adifio.NewWriter(writefp, header, "goadifsession")
+		textwriter = bufio.NewWriter(writefp)
+	} else {
+		writefp = nil
+		writer = adifio.NewWriter(os.Stdout, header, "goadifsession")
+		textwriter = bufio.NewWriter(os.Stdout)
+	}
+
+	if err := writer.SetComment("goadifsession\n"); err != nil {
+		fmt.Fprint(os.Stderr, err)
+		return
+	}
+
+	var current *session
+	nsession := 0
+	recordnumber := 0
+
+	reader := adifparser.NewADIFReader(input)
+	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprint(os.Stderr, err)
+			}
+			break // when io.EOF break the loop!
+		}
+		recordnumber++
+
+		recordtime, err := adiftime.RecordTime(record)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+			return
+		}
+
+		// Start a new session after a gap or out of the time order
+		if current != nil && recordtime.Before(current.end) {
+			fmt.Fprintf(os.Stderr,
+				"Warning: record %d is out of the time order\n", recordnumber)
+		}
+		if current == nil ||
+			recordtime.Before(current.end) || recordtime.Sub(current.end) > gap {
+			if current != nil && !tagmode {
+				current.output(textwriter)
+			}
+			nsession++
+			current = newSession(nsession, recordtime)
+		}
+		current.add(recordtime, record)
+
+		if tagmode {
+			record.SetValue(tag, strconv.Itoa(current.id))
+			if err := writer.WriteRecord(record); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+
+	// Flush and close output here
+	if tagmode {
+		writer.Flush()
+	} else {
+		if current != nil {
+			current.output(textwriter)
+		}
+		fmt.Fprintf(textwriter, "(SESSIONS): %d\n", nsession)
+		textwriter.Flush()
+	}
+	if writefp != os.Stdout {
+		writefp.Close()
+	}
+
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+const testMainEnv = "GOADIFSESSION_TEST_MAIN"
+
+// Run main() in a child process of the test binary
+func TestMain(m *testing.M) {
+	testutil.Main(m, testMainEnv, main)
+}
+
+func TestSessionOutput(t *testing.T) {
+	start := time.Date(2023, time.November, 25, 10, 0, 0, 0, time.UTC)
+	s := newSession(3, start)
+	s.add(start, testutil.ParseRecord(t, "<band:3>20M<mode:2>cw<eor>"))
+	s.add(start.Add(5*time.Minute), testutil.ParseRecord(t, "<band:3>40m<mode:3>FT8<eor>"))
+	s.add(start.Add(90*time.Minute), testutil.ParseRecord(t, "<band:3>20m<eor>"))
+	var output bytes.Buffer
+	writer := bufio.NewWriter(&output)
+	s.output(writer)
+	writer.Flush()
+	want := "session 3: 2023-11-25T10:00:00Z - 2023-11-25T11:30:00Z duration 1h30m0s qsos 3\n" +
+		"  bands: 20m 2 40m 1 \n" +
+		"  modes: CW 1 FT8 1 (UNKNOWN) 1 \n"
+	if output.String() != want {
+		t.Errorf("output = %q, want %q", output.String(), want)
+	}
+}
+
+const testLog = "<call:4>A1AA<band:3>20m<mode:2>CW<qso_date:8>20231125<time_on:4>1000<eor>\n" +
+	"<call:4>A1AB<band:3>20m<mode:2>CW<qso_date:8>20231125<time_on:4>1030<eor>\n" +
+	"<call:4>A1AC<band:3>40m<mode:3>FT8<qso_date:8>20231125<time_on:4>1101<eor>\n" +
+	"<call:4>A1AD<band:3>40m<mode:3>FT8<qso_date:8>20231126<time_on:4>0000<eor>\n"
+
+func TestSessionReport(t *testing.T) {
+	stdout, stderr := testutil.Run(t, testMainEnv, testLog)
+	want := "session 1: 2023-11-25T10:00:00Z - 2023-11-25T10:30:00Z duration 30m0s qsos 2\n" +
+		"  bands: 20m 2 \n" +
+		"  modes: CW 2 \n" +
+		"session 2: 2023-11-25T11:01:00Z - 2023-11-25T11:01:00Z duration 0s qsos 1\n" +
+		"  bands: 40m 1 \n" +
+		"  modes: FT8 1 \n" +
+		"session 3: 2023-11-26T00:00:00Z - 2023-11-26T00:00:00Z duration 0s qsos 1\n" +
+		"  bands: 40m 1 \n" +
+		"  modes: FT8 1 \n" +
+		"(SESSIONS): 3\n"
+	if stdout != want || stderr != "" {
+		t.Errorf("report = %q, stderr %q, want %q", stdout, stderr, want)
+	}
+
+	stdout, _ = testutil.Run(t, testMainEnv, testLog, "-g", "31")
+	if !strings.HasSuffix(stdout, "(SESSIONS): 2\n") {
+		t.Errorf("-g 31: %q", stdout)
+	}
+
+	stdout, _ = testutil.Run(t, testMainEnv, "")
+	if stdout != "(SESSIONS): 0\n" {
+		t.Errorf("empty input: %q", stdout)
+	}
+}
+
+func TestSessionTag(t *testing.T) {
+	// Records out of the time order start a new session
+	input := testLog +
+		"<call:4>A1AE<band:3>40m<mode:3>FT8<qso_date:8>20231125<time_on:4>2359<eor>\n"
+	stdout, stderr := testutil.Run(t, testMainEnv, input, "-t", "app_goadifsession_id")
+	if stderr != "Warning: record 5 is out of the time order\n" {
+		t.Errorf("stderr = %q", stderr)
+	}
+	ids := testutil.FieldValues(testutil.ReadRecords(t, stdout), "app_goadifsession_id")
+	if want := []string{"1", "1", "2", "3", "4"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("session ids = %v, want %v", ids, want)
+	}
+}
+
+func TestSessionTagField(t *testing.T) {
+	input := "Test log <adif_ver:5>3.1.4<eoh>\n" + testLog
+	tests := []struct {
+		field    string
+		userdef  string
+		declared bool
+	}{
+		// Non-standard fields are declared as USERDEF in lowercase
+		{"Session_No", "<userdef1:10:N>session_no", true},
+		{"app_goadifsession_id", "<userdef1:", false},
+		{"CONTEST_ID", "<userdef1:", false},
+	}
+	for _, tt := range tests {
+		stdout, stderr := testutil.Run(t, testMainEnv, input, "-t", tt.field)
+		if stderr != "" {
+			t.Errorf("-t %s: stderr = %q", tt.field, stderr)
+		}
+		if strings.Contains(stdout, tt.userdef) != tt.declared {
+			t.Errorf("-t %s: declared = %v, want %v: %q",
+				tt.field, !tt.declared, tt.declared, stdout)
+		}
+		ids := testutil.FieldValues(testutil.ReadRecords(t, stdout), strings.ToLower(tt.field))
+		if want := []string{"1", "1", "2", "3"}; !reflect.DeepEqual(ids, want) {
+			t.Errorf("-t %s: session ids = %v, want %v", tt.field, ids, want)
+		}
+	}
+
+	// Already declared fields are kept
+	declared := "Test log <userdef1:7:S>session<eoh>\n" + testLog
+	stdout, _ := testutil.Run(t, testMainEnv, declared, "-t", "session")
+	if !strings.Contains(stdout, "<userdef1:7:S>session") || strings.Contains(stdout, "<userdef2:") {
+		t.Errorf("declared field: %q", stdout)
+	}
+
+	// Invalid field names
+	for _, field := range []string{"bad field", "tag<1>", "-"} {
+		stdout, stderr := testutil.Run(t, testMainEnv, input, "-t", field)
+		if stdout != "" || !strings.Contains(stderr, "invalid field name") {
+			t.Errorf("-t %q: stdout %q, stderr %q", field, stdout, stderr)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// goadifset: set, rename, copy, delete, and compute ADIF fields
+// by Kenji Rikitake, JJ1BDX
+// Usage: goadifset [-f infile] [-o outfile] [-e expression] operations...
+// -nh: do not pass through the input ADIF header
+// Operations (applied in the order of the command line):
+//   -set field=template: set the field value
+//   -setempty field=template: set the field value only if empty
+//   -rename old=new: rename the field
+//   -copy src=dst: copy the field value
+//   -delete field: delete the field
+//   -subst field=/regex/replacement/: substitute the regex matches
+//   -upper field: convert the field value to uppercase
+//   -lower field: convert the field value to lowercase
+// Templates have {field} placeholders replaced by the field values
+// See ops.go for the details
+// -e: apply the operations only to the records matching the expression
+//     (see adifexpr package for the expression syntax)
+// If an operation fails, the error is reported with the record number
+// and the command stops without writing the failed record
+// Examples:
+//   goadifset -set 'comment={contest_id} #{srx}'
+//   goadifset -setempty my_rig=IC-705 -upper call -rename notes=comment
+//   goadifset -subst 'call=/\/QRP$//' -e 'call =~ "/QRP$"'
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifexpr"
+	"github.com/jj1bdx/goadiftools/adifio"
+)
+
+func main() {
+	var infile = flag.String("f", "", "input file (stdout in none)")
+	var outfile = flag.String("o", "", "output file (stdout if none)")
+	var noheader = flag.Bool("nh", false, "do not pass through the input header")
+	var expression = flag.String("e", "", "apply only to records matching the expression")
+
+	// Operations in the order of the command line
+	var ops []fieldOp
+	addOp := func(name, usage string, newop func(string) (fieldOp, error)) {
+		flag.Func(name, usage, func(arg string) error {
+			op, err := newop(arg)
+			if err != nil {
+				return err
+			}
+			ops = append(ops, op)
+			return nil
+		})
+	}
+	addOp("set", "set the field value: field=template",
+		func(arg string) (fieldOp, error) { return newSetOp(arg, false) })
+	addOp("setempty", "set the field value only if empty: field=template",
+		func(arg string) (fieldOp, error) { return newSetOp(arg, true) })
+	addOp("rename", "rename the field: old=new",
+		func(arg string) (fieldOp, error) { return newRenameOp(arg, false) })
+	addOp("copy", "copy the field value: src=dst",
+		func(arg string) (fieldOp, error) { return newRenameOp(arg, true) })
+	addOp("delete", "delete the field: field", newDeleteOp)
+	addOp("subst", "substitute the regex matches: field=/regex/replacement/", newSubstOp)
+	addOp("upper", "convert the field value to uppercase: field",
+		func(arg string) (fieldOp, error) { return newCaseOp(arg, true) })
+	addOp("lower", "convert the field value to lowercase: field",
+		func(arg string) (fieldOp, error) { return newCaseOp(arg, false) })
+
+	var fp *os.File
+	var err error
+
+	flag.Usage = func() {
+		execname := os.Args[0]
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"goadifset: set, rename, copy, delete, and compute ADIF fields")
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [-f infile] [-o outfile] [-e expression] operations...\n", execname)
+		flag.PrintDefaults()
+		details :=
+			"Operations are applied in the order of the command line\n" +
+				"Templates have {field} placeholders replaced by the field values\n" +
+				"A value enclosed in double quotes is unquoted\n" +
+				"Fields set to empty strings are deleted\n" +
+				"-subst: any character can be used as the delimiter instead of \"/\",\n" +
+				"        and $1 or ${1} in the replacement refers to the submatch\n" +
+				"-e: same expression as goadifgrep -e\n" +
+				"Examples:\n" +
+				"  goadifset -set 'comment={contest_id} #{srx}'\n" +
+				"  goadifset -setempty my_rig=IC-705 -upper call -rename notes=comment\n" +
+				"  goadifset -subst 'call=/\\/QRP$//' -e 'call =~ \"/QRP$\"'\n"
+		fmt.Fprint(flag.CommandLine.Output(), details)
+	}
+
+	flag.Parse()
+
+	if len(ops) == 0 || len(flag.Args()) != 0 {
+		fmt.Fprint(os.Stderr, "Error: incorrect arguments\n")
+		flag.Usage()
+		return
+	}
+
+	if *infile == "" {
+		fp = os.Stdin
+	} else {
+		fp, err = os.Open(*infile)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+			return
+		}
+	}
+
+	header, input, err := adifio.ReadHeader(fp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	// Compare user-defined fields by the declared data types
+	var query adifexpr.Node
+	if *expression != "" {
+		query, err = adifexpr.Parse(*expression, header.UserdefTypes())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+	}
+	if *noheader {
+		header = nil
+	}
+	if header != nil {
+		updateUserdefs(header, ops, query == nil)
+	}
+
+	var writer adifparser.ADIFWriter
+	var writefp *os.File
+	if *outfile != "" {
+		if _, err := os.Stat(*outfile); os.IsNotExist(err) {
+			// File does not exist: create it
+			writefp, err = os.Create(*outfile)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				return
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: file %s already exists\n", *outfile)
+			return
+		}
+		writer = adifio.NewWriter(writefp, header, "goadifset")
+	} else {
+		writefp = nil
+		writer = adifio.NewWriter(os.Stdout, header, "goadifset")
+	}
+
+	if err := writer.SetComment("goadifset\n"); err != nil {
+		fmt.Fprint(os.Stderr, err)
+		return
+	}
+
+	recordnumber := 0
+	reader := adifparser.NewADIFReader(input)
+	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprint(os.Stderr, err)
+			}
+			break // when io.EOF break the loop!
+		}
+		recordnumber++
+
+		selected := true
+		if query != nil {
+			selected, err = query.Eval(record)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				break
+			}
+			for _, warning := range query.Warnings(record) {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+			}
+		}
+		if selected {
+			if err := applyOps(record, ops); err != nil {
+				// Do not write the partially modified record
+				fmt.Fprintf(os.Stderr, "Error: record %d: %v\n", recordnumber, err)
+				break
+			}
+		}
+
+		if err := writer.WriteRecord(record); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	// Flush and close output here
+	writer.Flush()
+	if writefp != os.Stdout {
+		writefp.Close()
+	}
+
+}
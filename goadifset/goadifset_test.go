@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+const testMainEnv = "GOADIFSET_TEST_MAIN"
+
+// Run main() in a child process of the test binary
+func TestMain(m *testing.M) {
+	testutil.Main(m, testMainEnv, main)
+}
+
+// Run goadifset with the input and the arguments,
+// and return the field values of the output records
+func runSet(t *testing.T, input string, args ...string) []map[string]string {
+	t.Helper()
+	stdout, stderr := testutil.Run(t, testMainEnv, input, args...)
+	if stderr != "" {
+		t.Fatalf("goadifset %v: %s", args, stderr)
+	}
+	records := []map[string]string{}
+	for _, record := range testutil.ReadRecords(t, stdout) {
+		records = append(records, testutil.RecordValues(record))
+	}
+	return records
+}
+
+func TestSet(t *testing.T) {
+	input := "<call:8>JA1ABC/P<notes:3>abc<eor>\n" +
+		"<call:5>A1AAA<eor>\n"
+	got := runSet(t, input, "-upper", "notes", "-rename", "notes=comment",
+		"-setempty", "my_rig=IC-705", "-subst", `call=/\/P$//`)
+	want := []map[string]string{
+		{"call": "JA1ABC", "comment": "ABC", "my_rig": "IC-705"},
+		{"call": "A1AAA", "my_rig": "IC-705"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("goadifset = %v, want %v", got, want)
+	}
+
+	got = runSet(t, input, "-e", `call =~ "/P$"`, "-set", "comment={call} portable")
+	want = []map[string]string{
+		{"call": "JA1ABC/P", "notes": "abc", "comment": "JA1ABC/P portable"},
+		{"call": "A1AAA"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("goadifset -e = %v, want %v", got, want)
+	}
+}
+
+func TestSetUserdefTypes(t *testing.T) {
+	input := "log <userdef1:5:T>SHIPT<eoh>\n" +
+		"<call:4>A1AA<shipt:4>0830<eor>\n" +
+		"<call:4>A1AB<shipt:4>0900<eor>\n"
+	// SHIPT is compared as Time
+	got := runSet(t, input, "-e", `shipt == 083000`, "-set", "notes=early")
+	want := []map[string]string{
+		{"call": "A1AA", "shipt": "0830", "notes": "early"},
+		{"call": "A1AB", "shipt": "0900"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("goadifset = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,311 @@
+// goadifset: field operations
+// by Kenji Rikitake, JJ1BDX
+//
+// Operations are applied to each record in the order of the command line:
+//
+//	-set field=template: set the field value
+//	-setempty field=template: set the field value only if empty
+//	-rename old=new: rename the field (overwrite the new field if exists)
+//	-copy src=dst: copy the field value (overwrite the dst field if exists)
+//	-delete field: delete the field
+//	-subst field=/regex/replacement/: substitute the regex matches
+//	  in the field value with the replacement
+//	  (any character can be used as the delimiter instead of "/",
+//	  a backslash-escaped delimiter is the delimiter character itself,
+//	  and $1 or ${1} in the replacement refers to the submatch)
+//	-upper field: convert the field value to uppercase
+//	-lower field: convert the field value to lowercase
+//
+// Templates are strings with {field} placeholders
+// replaced by the field values (e.g., "{contest_id} #{srx}").
+// Spaces around field names and values are removed,
+// and a value enclosed in double quotes is unquoted.
+// Fields set to empty strings are deleted.
+// Field names are case insensitive.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifio"
+)
+
+var ErrInvalidOperation = errors.New("invalid operation")
+
+var ErrNoSuchField = adifparser.ErrNoSuchField
+
+var regPlaceholder = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+var regFieldName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Field operation
+type fieldOp interface {
+	apply(record adifparser.ADIFRecord) error
+}
+
+type setOp struct {
+	field    string
+	template string
+	ifempty  bool
+}
+
+type renameOp struct {
+	from string
+	to   string
+	// Keep the source field for copying
+	keep bool
+}
+
+type deleteOp struct {
+	field string
+}
+
+type substOp struct {
+	field       string
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+type caseOp struct {
+	field string
+	upper bool
+}
+
+// Obtain the field value, empty if the field does not exist
+func fieldValue(record adifparser.ADIFRecord, field string) (string, error) {
+	value, err := record.GetValue(field)
+	if err == ErrNoSuchField {
+		return "", nil
+	}
+	return value, err
+}
+
+// Set the field value, or delete the field if the value is empty
+func setFieldValue(record adifparser.ADIFRecord, field, value string) {
+	if value == "" {
+		record.DeleteField(field)
+		return
+	}
+	record.SetValue(field, value)
+}
+
+// Expand the template with the field values of the record
+func expandTemplate(template string, record adifparser.ADIFRecord) (string, error) {
+	var experr error
+	value := regPlaceholder.ReplaceAllStringFunc(template,
+		func(placeholder string) string {
+			field := strings.ToLower(placeholder[1 : len(placeholder)-1])
+			value, err := fieldValue(record, field)
+			if err != nil {
+				experr = err
+			}
+			return value
+		})
+	return value, experr
+}
+
+func (op setOp) apply(record adifparser.ADIFRecord) error {
+	if op.ifempty {
+		current, err := fieldValue(record, op.field)
+		if err != nil {
+			return err
+		}
+		if current != "" {
+			return nil
+		}
+	}
+	value, err := expandTemplate(op.template, record)
+	if err != nil {
+		return err
+	}
+	setFieldValue(record, op.field, value)
+	return nil
+}
+
+func (op renameOp) apply(record adifparser.ADIFRecord) error {
+	value, err := record.GetValue(op.from)
+	if err == ErrNoSuchField {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !op.keep {
+		record.DeleteField(op.from)
+	}
+	setFieldValue(record, op.to, value)
+	return nil
+}
+
+func (op deleteOp) apply(record adifparser.ADIFRecord) error {
+	record.DeleteField(op.field)
+	return nil
+}
+
+func (op substOp) apply(record adifparser.ADIFRecord) error {
+	value, err := record.GetValue(op.field)
+	if err == ErrNoSuchField {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	setFieldValue(record, op.field,
+		op.pattern.ReplaceAllString(value, op.replacement))
+	return nil
+}
+
+func (op caseOp) apply(record adifparser.ADIFRecord) error {
+	value, err := record.GetValue(op.field)
+	if err == ErrNoSuchField {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if op.upper {
+		record.SetValue(op.field, strings.ToUpper(value))
+	} else {
+		record.SetValue(op.field, strings.ToLower(value))
+	}
+	return nil
+}
+
+// Apply the operations to the record in order
+// Returns the error of the first failed operation
+// without applying the rest
+func applyOps(record adifparser.ADIFRecord, ops []fieldOp) error {
+	for _, op := range ops {
+		if err := op.apply(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update the USERDEF declarations of the header by the operations
+// The declarations of the source fields are kept
+// unless the operations are applied to all records
+// Standard ADIF fields are not declared as the target fields
+func updateUserdefs(header *adifio.Header, ops []fieldOp, all bool) {
+	for _, op := range ops {
+		switch op := op.(type) {
+		case renameOp:
+			if u, ok := header.Userdef(op.from); ok {
+				if !adifio.IsStandardField(op.to) {
+					u.Name = op.to
+					header.AddUserdef(u)
+				}
+				if !op.keep && all {
+					header.RemoveUserdef(op.from)
+				}
+			}
+		case deleteOp:
+			if all {
+				header.RemoveUserdef(op.field)
+			}
+		}
+	}
+}
+
+// Parse a field name
+func parseFieldName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !regFieldName.MatchString(name) {
+		return "", fmt.Errorf("%w: invalid field name %q", ErrInvalidOperation, name)
+	}
+	return name, nil
+}
+
+// Split an argument into a field name and a value by "="
+func splitAssignment(arg string) (string, string, error) {
+	name, value, found := strings.Cut(arg, "=")
+	if !found {
+		return "", "", fmt.Errorf("%w: no \"=\" in %q", ErrInvalidOperation, arg)
+	}
+	field, err := parseFieldName(name)
+	if err != nil {
+		return "", "", err
+	}
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
+		value = value[1 : len(value)-1]
+	}
+	return field, value, nil
+}
+
+func newSetOp(arg string, ifempty bool) (fieldOp, error) {
+	field, template, err := splitAssignment(arg)
+	if err != nil {
+		return nil, err
+	}
+	return setOp{field, template, ifempty}, nil
+}
+
+func newRenameOp(arg string, keep bool) (fieldOp, error) {
+	from, to, err := splitAssignment(arg)
+	if err != nil {
+		return nil, err
+	}
+	to, err = parseFieldName(to)
+	if err != nil {
+		return nil, err
+	}
+	return renameOp{from, to, keep}, nil
+}
+
+func newDeleteOp(arg string) (fieldOp, error) {
+	field, err := parseFieldName(arg)
+	if err != nil {
+		return nil, err
+	}
+	return deleteOp{field}, nil
+}
+
+// Split a string by the delimiter
+// Backslash-escaped delimiters are not split and unescaped
+func splitDelimited(s string, delimiter byte) []string {
+	parts := []string{}
+	var part strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && s[i+1] == delimiter {
+			part.WriteByte(delimiter)
+			i++
+		} else if s[i] == delimiter {
+			parts = append(parts, part.String())
+			part.Reset()
+		} else {
+			part.WriteByte(s[i])
+		}
+	}
+	return append(parts, part.String())
+}
+
+func newSubstOp(arg string) (fieldOp, error) {
+	field, expr, err := splitAssignment(arg)
+	if err != nil {
+		return nil, err
+	}
+	// The first character is the delimiter
+	if len(expr) < 3 {
+		return nil, fmt.Errorf("%w: invalid substitution %q", ErrInvalidOperation, expr)
+	}
+	parts := splitDelimited(expr[1:], expr[0])
+	if len(parts) != 3 || parts[2] != "" {
+		return nil, fmt.Errorf("%w: invalid substitution %q", ErrInvalidOperation, expr)
+	}
+	pattern, err := regexp.Compile(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	return substOp{field, pattern, parts[1]}, nil
+}
+
+func newCaseOp(arg string, upper bool) (fieldOp, error) {
+	field, err := parseFieldName(arg)
+	if err != nil {
+		return nil, err
+	}
+	return caseOp{field, upper}, nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifio"
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+func TestOps(t *testing.T) {
+	type opArg struct {
+		name string
+		arg  string
+	}
+	newOps := map[string]func(string) (fieldOp, error){
+		"set":      func(arg string) (fieldOp, error) { return newSetOp(arg, false) },
+		"setempty": func(arg string) (fieldOp, error) { return newSetOp(arg, true) },
+		"rename":   func(arg string) (fieldOp, error) { return newRenameOp(arg, false) },
+		"copy":     func(arg string) (fieldOp, error) { return newRenameOp(arg, true) },
+		"delete":   newDeleteOp,
+		"subst":    newSubstOp,
+		"upper":    func(arg string) (fieldOp, error) { return newCaseOp(arg, true) },
+		"lower":    func(arg string) (fieldOp, error) { return newCaseOp(arg, false) },
+	}
+	const input = "<call:6>ja1abc<contest_id:8>CQ-WW-CW<srx:3>123<notes:7>QSL via<eor>"
+	tests := []struct {
+		ops  []opArg
+		want map[string]string
+	}{
+		{[]opArg{{"set", `comment={CONTEST_ID} #{srx}`}},
+			map[string]string{"comment": "CQ-WW-CW #123"}},
+		{[]opArg{{"set", `comment = " {call} "`}},
+			map[string]string{"comment": " ja1abc "}},
+		{[]opArg{{"set", `comment={gridsquare}`}}, map[string]string{}},
+		// Setting an empty value deletes the field
+		{[]opArg{{"set", `notes={gridsquare}`}}, map[string]string{"notes": ""}},
+		{[]opArg{{"setempty", `notes=x`}, {"setempty", `my_rig=IC-705`}},
+			map[string]string{"my_rig": "IC-705"}},
+		{[]opArg{{"rename", `notes=comment`}},
+			map[string]string{"notes": "", "comment": "QSL via"}},
+		{[]opArg{{"rename", `srx=notes`}},
+			map[string]string{"srx": "", "notes": "123"}},
+		{[]opArg{{"rename", `gridsquare=notes`}}, map[string]string{}},
+		{[]opArg{{"copy", `srx=app_x_srx`}},
+			map[string]string{"app_x_srx": "123"}},
+		{[]opArg{{"delete", `NOTES`}, {"delete", `gridsquare`}},
+			map[string]string{"notes": ""}},
+		{[]opArg{{"subst", `contest_id=/-(CW|SSB)$//`}},
+			map[string]string{"contest_id": "CQ-WW"}},
+		{[]opArg{{"subst", `contest_id=|CQ-(WW)-(.*)|${2}-$1|`}},
+			map[string]string{"contest_id": "CW-WW"}},
+		{[]opArg{{"subst", `notes=/ /\//`}},
+			map[string]string{"notes": "QSL/via"}},
+		{[]opArg{{"subst", `notes=/.*//`}}, map[string]string{"notes": ""}},
+		{[]opArg{{"upper", `call`}, {"lower", `contest_id`}, {"upper", `gridsquare`}},
+			map[string]string{"call": "JA1ABC", "contest_id": "cq-ww-cw"}},
+		// Operations are applied in order
+		{[]opArg{{"upper", `call`}, {"set", `comment={call}`}, {"lower", `call`}},
+			map[string]string{"call": "ja1abc", "comment": "JA1ABC"}},
+		{[]opArg{{"set", `comment={call}`}, {"upper", `call`}},
+			map[string]string{"call": "JA1ABC", "comment": "ja1abc"}},
+	}
+	for _, tt := range tests {
+		record := testutil.ParseRecord(t, input)
+		want := testutil.RecordValues(record)
+		for field, value := range tt.want {
+			if value == "" {
+				delete(want, field)
+			} else {
+				want[field] = value
+			}
+		}
+		for _, o := range tt.ops {
+			op, err := newOps[o.name](o.arg)
+			if err != nil {
+				t.Fatalf("-%s %s: %v", o.name, o.arg, err)
+			}
+			if err := op.apply(record); err != nil {
+				t.Fatalf("-%s %s: apply: %v", o.name, o.arg, err)
+			}
+		}
+		if got := testutil.RecordValues(record); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v = %v, want %v", tt.ops, got, want)
+		}
+	}
+}
+
+func TestOpErrors(t *testing.T) {
+	tests := []struct {
+		newop func(string) (fieldOp, error)
+		arg   string
+	}{
+		{func(arg string) (fieldOp, error) { return newSetOp(arg, false) }, "comment"},
+		{func(arg string) (fieldOp, error) { return newSetOp(arg, false) }, "=x"},
+		{func(arg string) (fieldOp, error) { return newSetOp(arg, false) }, "my field=x"},
+		{func(arg string) (fieldOp, error) { return newRenameOp(arg, false) }, "notes="},
+		{func(arg string) (fieldOp, error) { return newRenameOp(arg, true) }, "notes=a-b"},
+		{newDeleteOp, "app-x"},
+		{newDeleteOp, ""},
+		{newSubstOp, "notes"},
+		{newSubstOp, "notes=/a/"},
+		{newSubstOp, "notes=/a/b"},
+		{newSubstOp, "notes=/a/b/c/"},
+		{func(arg string) (fieldOp, error) { return newCaseOp(arg, true) }, "a.b"},
+	}
+	for _, tt := range tests {
+		if _, err := tt.newop(tt.arg); !errors.Is(err, ErrInvalidOperation) {
+			t.Errorf("%q: error = %v, want %v", tt.arg, err, ErrInvalidOperation)
+		}
+	}
+	if _, err := newSubstOp("notes=/(/x/"); err == nil {
+		t.Errorf("invalid regex: no error")
+	}
+}
+
+// Operation always failing
+type failOp struct{}
+
+var errFailOp = errors.New("failed")
+
+func (op failOp) apply(record adifparser.ADIFRecord) error {
+	return errFailOp
+}
+
+func TestApplyOps(t *testing.T) {
+	record := testutil.ParseRecord(t, "<call:4>a1aa<notes:4>test<eor>")
+	ops := []fieldOp{caseOp{"call", true}, failOp{}, deleteOp{"notes"}}
+	if err := applyOps(record, ops); err != errFailOp {
+		t.Errorf("applyOps() error = %v, want %v", err, errFailOp)
+	}
+	// The operations after the failed one are not applied
+	want := map[string]string{"call": "A1AA", "notes": "test"}
+	if got := testutil.RecordValues(record); !reflect.DeepEqual(got, want) {
+		t.Errorf("record = %v, want %v", got, want)
+	}
+	if err := applyOps(record, ops[2:]); err != nil {
+		t.Errorf("applyOps() error = %v", err)
+	}
+}
+
+func TestSplitDelimited(t *testing.T) {
+	tests := []struct {
+		s         string
+		delimiter byte
+		want      []string
+	}{
+		{"a/b/", '/', []string{"a", "b", ""}},
+		{`a\/b/c/`, '/', []string{"a/b", "c", ""}},
+		{`a\.b|c|`, '|', []string{`a\.b`, "c", ""}},
+		{"", '/', []string{""}},
+		{`a\`, '/', []string{`a\`}},
+	}
+	for _, tt := range tests {
+		if got := splitDelimited(tt.s, tt.delimiter); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitDelimited(%q, %q) = %q, want %q", tt.s, tt.delimiter, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateUserdefs(t *testing.T) {
+	input := "log <userdef1:3:N>EPC<userdef2:5:S>GUEST<userdef3:4:E>SIZE<eoh>"
+	tests := []struct {
+		ops  []fieldOp
+		all  bool
+		want []string
+	}{
+		{[]fieldOp{renameOp{"epc", "rank", false}}, true,
+			[]string{"guest", "size", "rank"}},
+		{[]fieldOp{renameOp{"epc", "rank", false}}, false,
+			[]string{"epc", "guest", "size", "rank"}},
+		{[]fieldOp{renameOp{"epc", "rank", true}}, true,
+			[]string{"epc", "guest", "size", "rank"}},
+		// Standard ADIF fields are not declared
+		{[]fieldOp{renameOp{"guest", "operator", false}}, true,
+			[]string{"epc", "size"}},
+		{[]fieldOp{renameOp{"guest", "operator", true}}, true,
+			[]string{"epc", "guest", "size"}},
+		{[]fieldOp{renameOp{"notes", "comment", false}}, true,
+			[]string{"epc", "guest", "size"}},
+		{[]fieldOp{deleteOp{"size"}}, true, []string{"epc", "guest"}},
+		{[]fieldOp{deleteOp{"size"}}, false, []string{"epc", "guest", "size"}},
+	}
+	for _, tt := range tests {
+		header, _, err := adifio.ReadHeader(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		updateUserdefs(header, tt.ops, tt.all)
+		if got := header.UserdefNames(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("updateUserdefs(%+v, %v) = %q, want %q", tt.ops, tt.all, got, tt.want)
+		}
+		// Type of the renamed field is kept
+		if u, ok := header.Userdef("rank"); ok && u.Type != "N" {
+			t.Errorf("rank type = %q", u.Type)
+		}
+	}
+}
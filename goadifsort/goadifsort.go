@@ -0,0 +1,174 @@
+// goadifsort: sort ADIF records by multiple keys
+// by Kenji Rikitake, JJ1BDX
+// Usage: goadifsort [-f infile] [-o outfile] key...
+// -nh: do not pass through the input ADIF header
+// key: field[:type][:order]
+//  type: string, number, date, time, band
+//  order: asc (ascending, default), desc (descending)
+// Field "time" is the QSO time by qso_date and time_on
+// See sortkey.go for the default types of the fields
+// Examples:
+//  goadifsort call
+//  goadifsort dxcc call
+//  goadifsort band time
+//  goadifsort qso_date:desc tx_pwr:number:desc
+// Sorting is stable: records with the same keys keep the input order
+// Records without the field or with invalid values are placed last
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifio"
+)
+
+type recordWithKeys struct {
+	keys   []keyValue
+	record adifparser.ADIFRecord
+}
+
+func main() {
+	var infile = flag.String("f", "", "input file (stdout in none)")
+	var outfile = flag.String("o", "", "output file (stdout if none)")
+	var noheader = flag.Bool("nh", false, "do not pass through the input header")
+
+	var fp *os.File
+	var err error
+
+	flag.Usage = func() {
+		execname := os.Args[0]
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"goadifsort: sort ADIF records by multiple keys")
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [-f infile] [-o outfile] key...\n", execname)
+		flag.PrintDefaults()
+		details :=
+			"key: field[:type][:order]\n" +
+				"  type: string, number, date, time, band\n" +
+				"  order: asc (ascending, default), desc (descending)\n" +
+				"Field \"time\" is the QSO time by qso_date and time_on\n" +
+				"Default types:\n" +
+				"  band: band (in the frequency order)\n" +
+				"  time: date\n" +
+				"  ADIF Number, Integer, Date, and Time fields: number, date, and time\n" +
+				"    (e.g., freq, tx_pwr, dxcc, srx: number, qso_date: date, time_on: time)\n" +
+				"  other fields: string (case insensitive)\n" +
+				"Examples:\n" +
+				"  call\n" +
+				"  dxcc call\n" +
+				"  band time\n" +
+				"  qso_date:desc tx_pwr:number:desc\n" +
+				"Sorting is stable: records with the same keys keep the input order\n" +
+				"Records without the field or with invalid values are placed last\n"
+		fmt.Fprint(flag.CommandLine.Output(), details)
+	}
+
+	flag.Parse()
+
+	var keys []sortKey
+	for _, spec := range flag.Args() {
+		key, err := parseSortKey(spec)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		fmt.Fprint(os.Stderr, "Error: no sort key\n")
+		flag.Usage()
+		return
+	}
+
+	if *infile == "" {
+		fp = os.Stdin
+	} else {
+		fp, err = os.Open(*infile)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+			return
+		}
+	}
+
+	header, input, err := adifio.ReadHeader(fp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if *noheader {
+		header = nil
+	}
+
+	var writer adifparser.ADIFWriter
+	var writefp *os.File
+	if *outfile != "" {
+		if _, err := os.Stat(*outfile); os.IsNotExist(err) {
+			// File does not exist: create it
+			writefp, err = os.Create(*outfile)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				return
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: file %s already exists\n", *outfile)
+			return
+		}
+		writer = adifio.NewWriter(writefp, header, "goadifsort")
+	} else {
+		writefp = nil
+		writer = adifio.NewWriter(os.Stdout, header, "goadifsort")
+	}
+
+	if err := writer.SetComment("goadifsort\n"); err != nil {
+		fmt.Fprint(os.Stderr, err)
+		return
+	}
+
+	records := []recordWithKeys{}
+
+	reader := adifparser.NewADIFReader(input)
+	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprint(os.Stderr, err)
+			}
+			break // when io.EOF break the loop!
+		}
+
+		values := make([]keyValue, len(keys))
+		for i, key := range keys {
+			values[i] = key.value(record)
+		}
+		records = append(records, recordWithKeys{values, record})
+	}
+
+	sort.SliceStable(records,
+		func(i, j int) bool {
+			for k, key := range keys {
+				result := key.compare(records[i].keys[k], records[j].keys[k])
+				if result != 0 {
+					return result < 0
+				}
+			}
+			return false
+		})
+
+	for i := range records {
+		if err := writer.WriteRecord(records[i].record); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	// Flush and close output here
+	writer.Flush()
+	if writefp != os.Stdout {
+		writefp.Close()
+	}
+
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+const testMainEnv = "GOADIFSORT_TEST_MAIN"
+
+// Run main() in a child process of the test binary
+func TestMain(m *testing.M) {
+	testutil.Main(m, testMainEnv, main)
+}
+
+// Run goadifsort with the input and the sort keys, and return the calls
+func runSort(t *testing.T, input string, keys ...string) []string {
+	t.Helper()
+	stdout, stderr := testutil.Run(t, testMainEnv, input, keys...)
+	if stderr != "" {
+		t.Fatalf("goadifsort %v: %s", keys, stderr)
+	}
+	return testutil.Calls(t, stdout)
+}
+
+func TestSort(t *testing.T) {
+	input := "<call:4>A1AA<band:3>20m<qso_date:8>20231125<time_on:4>1200<tx_pwr:3>100<eor>\n" +
+		"<call:4>A1AB<band:3>40m<qso_date:8>20231125<time_on:4>1100<tx_pwr:1>5<eor>\n" +
+		"<call:4>a1ac<band:2>6m<qso_date:8>20231124<time_on:4>2300<eor>\n" +
+		"<call:4>A1AD<band:4>160M<qso_date:8>20231125<time_on:4>1100<tx_pwr:2>50<eor>\n" +
+		"<call:4>A1AE<band:3>20m<qso_date:8>20231125<time_on:4>1000<tx_pwr:2>50<eor>\n"
+	tests := []struct {
+		keys  []string
+		calls []string
+	}{
+		{[]string{"call:desc"}, []string{"A1AE", "A1AD", "a1ac", "A1AB", "A1AA"}},
+		{[]string{"band"}, []string{"A1AD", "A1AB", "A1AA", "A1AE", "a1ac"}},
+		{[]string{"band", "time"}, []string{"A1AD", "A1AB", "A1AE", "A1AA", "a1ac"}},
+		{[]string{"band:string"}, []string{"A1AD", "A1AA", "A1AE", "A1AB", "a1ac"}},
+		// Stable: A1AB and A1AD have the same time
+		{[]string{"time"}, []string{"a1ac", "A1AE", "A1AB", "A1AD", "A1AA"}},
+		{[]string{"time:desc"}, []string{"A1AA", "A1AB", "A1AD", "A1AE", "a1ac"}},
+		// Missing values are the last
+		{[]string{"tx_pwr"}, []string{"A1AB", "A1AD", "A1AE", "A1AA", "a1ac"}},
+		{[]string{"tx_pwr:desc", "time_on"}, []string{"A1AA", "A1AE", "A1AD", "A1AB", "a1ac"}},
+		{[]string{"qso_date:desc", "band:desc"}, []string{"A1AA", "A1AE", "A1AB", "A1AD", "a1ac"}},
+	}
+	for _, tt := range tests {
+		if got := runSort(t, input, tt.keys...); !reflect.DeepEqual(got, tt.calls) {
+			t.Errorf("goadifsort %v = %v, want %v", tt.keys, got, tt.calls)
+		}
+	}
+}
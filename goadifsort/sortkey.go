@@ -0,0 +1,211 @@
+// goadifsort: sort keys and typed comparison
+// by Kenji Rikitake, JJ1BDX
+//
+// A sort key is specified as field[:type][:order]
+//  type: string, number, date, time, band
+//  order: asc (ascending, default), desc (descending)
+// Field "time" is the QSO time by qso_date and time_on
+// Default types:
+//  band: band
+//  time (QSO time): date
+//  fields with Number, Date, and Time types in adifio/fields.go
+//  (e.g., freq, dxcc, srx, qso_date, time_on): number, date, and time
+//  other fields: string
+// Strings are compared case-insensitively
+// Bands are compared in the frequency order
+// Records without the field or with invalid values are placed last
+// in both ascending and descending orders
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifband"
+	"github.com/jj1bdx/goadiftools/adifio"
+)
+
+// Key types
+const (
+	keyTypeString = iota
+	keyTypeNumber
+	keyTypeDate
+	keyTypeTime
+	keyTypeBand
+)
+
+var ErrInvalidSortKey = errors.New("invalid sort key")
+
+var keyTypeNames = map[string]int{
+	"string": keyTypeString,
+	"number": keyTypeNumber,
+	"date":   keyTypeDate,
+	"time":   keyTypeTime,
+	"band":   keyTypeBand,
+}
+
+// Obtain the default key type of a field
+func defaultKeyType(field string) int {
+	switch field {
+	case "band":
+		return keyTypeBand
+	case "time":
+		return keyTypeDate
+	}
+	switch adifio.FieldType(field) {
+	case "N":
+		return keyTypeNumber
+	case "D":
+		return keyTypeDate
+	case "T":
+		return keyTypeTime
+	}
+	return keyTypeString
+}
+
+// Sort key
+type sortKey struct {
+	field   string
+	keytype int
+	desc    bool
+}
+
+// Key value of a record
+type keyValue struct {
+	// Missing or invalid value
+	missing bool
+	str     string
+	num     float64
+}
+
+// Parse a sort key in the form of field[:type][:order]
+func parseSortKey(spec string) (sortKey, error) {
+	parts := strings.Split(strings.ToLower(spec), ":")
+	key := sortKey{field: parts[0]}
+	if key.field == "" || len(parts) > 3 {
+		return key, fmt.Errorf("%w: %s", ErrInvalidSortKey, spec)
+	}
+	key.keytype = defaultKeyType(key.field)
+	typeset := false
+	orderset := false
+	for _, p := range parts[1:] {
+		if keytype, exists := keyTypeNames[p]; exists && !typeset {
+			key.keytype = keytype
+			typeset = true
+		} else if (p == "asc" || p == "desc") && !orderset {
+			key.desc = p == "desc"
+			orderset = true
+		} else {
+			return key, fmt.Errorf("%w: %s", ErrInvalidSortKey, spec)
+		}
+	}
+	return key, nil
+}
+
+// Obtain the field value of a record
+// Field "time" is qso_date and time_on in YYYYMMDDHHMMSS
+func fieldValue(record adifparser.ADIFRecord, field string) (string, bool) {
+	if field == "time" {
+		adifdate, err := record.GetValue("qso_date")
+		if err != nil || adifdate == "" {
+			return "", false
+		}
+		adiftime, err := record.GetValue("time_on")
+		if err != nil || adiftime == "" {
+			return "", false
+		}
+		if len(adiftime) == 4 {
+			adiftime = adiftime + "00"
+		}
+		if _, err := time.Parse("20060102150405", adifdate+adiftime); err != nil {
+			return "", false
+		}
+		return adifdate + adiftime, true
+	}
+	value, err := record.GetValue(field)
+	if err != nil || value == "" {
+		return "", false
+	}
+	return value, true
+}
+
+// Obtain the key value of a record
+func (key sortKey) value(record adifparser.ADIFRecord) keyValue {
+	value, exists := fieldValue(record, key.field)
+	if !exists {
+		return keyValue{missing: true}
+	}
+	switch key.keytype {
+	case keyTypeString:
+		return keyValue{str: strings.ToUpper(value)}
+	case keyTypeNumber:
+		num, err := adifio.ParseNumber(value)
+		if err != nil {
+			return keyValue{missing: true}
+		}
+		return keyValue{num: num}
+	case keyTypeDate:
+		// Field "time" is already validated
+		if key.field != "time" {
+			if _, err := time.Parse("20060102", value); err != nil {
+				return keyValue{missing: true}
+			}
+		}
+		num, err := adifio.ParseNumber(value)
+		if err != nil {
+			return keyValue{missing: true}
+		}
+		return keyValue{num: num}
+	case keyTypeTime:
+		if len(value) == 4 {
+			value = value + "00"
+		}
+		if _, err := time.Parse("150405", value); err != nil {
+			return keyValue{missing: true}
+		}
+		num, err := adifio.ParseNumber(value)
+		if err != nil {
+			return keyValue{missing: true}
+		}
+		return keyValue{num: num}
+	case keyTypeBand:
+		index := adifband.Index(value)
+		if index < 0 {
+			return keyValue{missing: true}
+		}
+		return keyValue{num: float64(index)}
+	}
+	return keyValue{missing: true}
+}
+
+// Compare two key values
+// Returns negative if a < b, 0 if a == b, positive if a > b
+// in the order of the key, where missing values are the last
+func (key sortKey) compare(a, b keyValue) int {
+	if a.missing || b.missing {
+		switch {
+		case a.missing && b.missing:
+			return 0
+		case a.missing:
+			return 1
+		default:
+			return -1
+		}
+	}
+	var result int
+	if key.keytype == keyTypeString {
+		result = strings.Compare(a.str, b.str)
+	} else if a.num < b.num {
+		result = -1
+	} else if a.num > b.num {
+		result = 1
+	}
+	if key.desc {
+		return -result
+	}
+	return result
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+func TestParseSortKey(t *testing.T) {
+	tests := []struct {
+		spec string
+		want sortKey
+	}{
+		{"call", sortKey{"call", keyTypeString, false}},
+		{"CALL:desc", sortKey{"call", keyTypeString, true}},
+		{"band", sortKey{"band", keyTypeBand, false}},
+		{"band:string", sortKey{"band", keyTypeString, false}},
+		{"time", sortKey{"time", keyTypeDate, false}},
+		{"qso_date:desc", sortKey{"qso_date", keyTypeDate, true}},
+		{"time_on", sortKey{"time_on", keyTypeTime, false}},
+		{"tx_pwr:number:desc", sortKey{"tx_pwr", keyTypeNumber, true}},
+		{"srx:desc:number", sortKey{"srx", keyTypeNumber, true}},
+		{"dxcc:asc", sortKey{"dxcc", keyTypeNumber, false}},
+		{"app_x_band:band", sortKey{"app_x_band", keyTypeBand, false}},
+		// Field types shared with adifexpr
+		{"stx", sortKey{"stx", keyTypeNumber, false}},
+		{"my_cq_zone", sortKey{"my_cq_zone", keyTypeNumber, false}},
+		{"age:desc", sortKey{"age", keyTypeNumber, true}},
+		{"lotw_qslrdate", sortKey{"lotw_qslrdate", keyTypeDate, false}},
+	}
+	for _, tt := range tests {
+		key, err := parseSortKey(tt.spec)
+		if err != nil || key != tt.want {
+			t.Errorf("parseSortKey(%q) = %+v, %v, want %+v", tt.spec, key, err, tt.want)
+		}
+	}
+	for _, spec := range []string{"", ":desc", "call:up", "call:number:string",
+		"call:asc:desc", "call:number:desc:x"} {
+		if _, err := parseSortKey(spec); !errors.Is(err, ErrInvalidSortKey) {
+			t.Errorf("parseSortKey(%q) error = %v, want %v", spec, err, ErrInvalidSortKey)
+		}
+	}
+}
+
+func TestKeyValue(t *testing.T) {
+	record := testutil.ParseRecord(t, "<call:6>ja1abc<band:3>20M<freq:6>14.074"+
+		"<qso_date:8>20231125<time_on:4>1230<time_off:6>123415"+
+		"<tx_pwr:3>abc<qso_date_off:8>20231325<app_x:0><srx:3>1e3<stx:2>+5<eor>")
+	tests := []struct {
+		spec string
+		want keyValue
+	}{
+		{"call", keyValue{str: "JA1ABC"}},
+		{"band", keyValue{num: 8}},
+		{"freq", keyValue{num: 14.074}},
+		{"qso_date", keyValue{num: 20231125}},
+		{"time", keyValue{num: 20231125123000}},
+		{"time_on", keyValue{num: 123000}},
+		{"time_off", keyValue{num: 123415}},
+		// Missing or invalid values
+		{"tx_pwr", keyValue{missing: true}},
+		{"qso_date_off", keyValue{missing: true}},
+		{"app_x", keyValue{missing: true}},
+		// Not ADIF Numbers
+		{"srx", keyValue{missing: true}},
+		{"stx", keyValue{missing: true}},
+		{"gridsquare", keyValue{missing: true}},
+		{"call:number", keyValue{missing: true}},
+		{"call:band", keyValue{missing: true}},
+		{"band:time", keyValue{missing: true}},
+	}
+	for _, tt := range tests {
+		key, err := parseSortKey(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := key.value(record); got != tt.want {
+			t.Errorf("value(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+
+	// Invalid time of the QSO time
+	record = testutil.ParseRecord(t, "<qso_date:8>20231125<time_on:4>2561<eor>")
+	if got := (sortKey{field: "time", keytype: keyTypeDate}).value(record); !got.missing {
+		t.Errorf("value(time) of invalid time_on = %+v", got)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	asc := sortKey{field: "freq", keytype: keyTypeNumber}
+	desc := sortKey{field: "freq", keytype: keyTypeNumber, desc: true}
+	str := sortKey{field: "call", keytype: keyTypeString}
+	missing := keyValue{missing: true}
+	tests := []struct {
+		key  sortKey
+		a, b keyValue
+		want int
+	}{
+		{asc, keyValue{num: 1}, keyValue{num: 2}, -1},
+		{asc, keyValue{num: 2}, keyValue{num: 1}, 1},
+		{asc, keyValue{num: 2}, keyValue{num: 2}, 0},
+		{desc, keyValue{num: 1}, keyValue{num: 2}, 1},
+		{str, keyValue{str: "A1AA"}, keyValue{str: "A1AB"}, -1},
+		// Missing values are the last in both orders
+		{asc, missing, keyValue{num: 1}, 1},
+		{asc, keyValue{num: 1}, missing, -1},
+		{desc, missing, keyValue{num: 1}, 1},
+		{desc, keyValue{num: 1}, missing, -1},
+		{desc, missing, missing, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.key.compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("compare(%+v, %+v, %+v) = %d, want %d", tt.key, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,475 @@
+// goadifsplit: split ADIF records into multiple files by field values or periods
+// by Kenji Rikitake, JJ1BDX
+// Usage: goadifsplit [-f infile] -t template
+//        goadifsplit [-f infile] [-t template] -l
+// -nh: do not pass through the input ADIF header
+// -t: output file name template with placeholders in braces
+//     e.g., log-{year}-{band}.adi, {station_callsign}/{year}{month}.adi
+// Placeholders:
+//   {year}, {month}, {day}: year (YYYY), month (MM), and day (DD) of qso_date
+//   {date}: qso_date (YYYYMMDD)
+//   {field}: value of the ADIF field (field name is case insensitive)
+//   {location}: station location number with -l
+// Characters other than letters, digits, ".", "+", and "-" in the values
+// are replaced by "_" (e.g., JA1ABC/P -> JA1ABC_P)
+// Empty or non-existing values are replaced by "unknown"
+// Values of the case-insensitive fields are converted to the conventional case:
+//   lowercase: band, band_rx
+//   uppercase: mode, submode, and other enumerations, and callsigns
+//   canonical grid square case (e.g., PM95vq): gridsquare, my_gridsquare
+// Directories in the file names are created if they do not exist
+// Existing files are not overwritten: the command stops with an error,
+// and the files and directories created by the command are removed
+// -maxopen: maximum number of output files kept open (default 64)
+//     The least recently used file is closed and reopened when needed
+// Number of records of each output file is reported to stdout
+//
+// -l: split by station locations for LoTW/TQSL uploads (see location.go)
+//     Default template: {station_callsign}-{my_gridsquare}-{location}.adi
+//     Station locations are reported to stdout as
+//     "location N: field=value ...: number of records"
+//     Missing or inconsistent location data are reported to stderr
+
+package main
+
+import (
+	"bufio"
+	"container/list"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifio"
+)
+
+var ErrNoSuchField = adifparser.ErrNoSuchField
+
+var regPlaceholder = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+
+// Default template for station locations
+const defaultLocationTemplate = "{station_callsign}-{my_gridsquare}-{location}.adi"
+
+var regUnsafeChars = regexp.MustCompile(`[^A-Za-z0-9.+-]`)
+
+// Case-insensitive fields whose values are converted
+// to the conventional case in the file names
+var lowerCaseFields = map[string]bool{
+	"band":    true,
+	"band_rx": true,
+}
+
+var upperCaseFields = map[string]bool{
+	"call":             true,
+	"cont":             true,
+	"contest_id":       true,
+	"mode":             true,
+	"my_state":         true,
+	"operator":         true,
+	"owner_callsign":   true,
+	"prop_mode":        true,
+	"station_callsign": true,
+	"state":            true,
+	"submode":          true,
+}
+
+// Output file of the split records
+type splitOutput struct {
+	filename string
+	// nil if closed
+	fp     *os.File
+	writer adifparser.ADIFWriter
+	count  int
+	// Element in the list of the open files
+	element *list.Element
+}
+
+// Output files of the split records
+// At most maxopen files are kept open
+type splitOutputs struct {
+	header  *adifio.Header
+	maxopen int
+	outputs map[string]*splitOutput
+	// Open files from the most recently used
+	open *list.List
+	// Names of the files created in the order of creation
+	created []string
+	// Names of the directories created in the order of creation
+	createddirs []string
+}
+
+func newSplitOutputs(header *adifio.Header, maxopen int) *splitOutputs {
+	return &splitOutputs{
+		header:  header,
+		maxopen: maxopen,
+		outputs: make(map[string]*splitOutput),
+		open:    list.New(),
+	}
+}
+
+// Convert a value to be safe as a part of a file name
+func safeValue(value string) string {
+	value = regUnsafeChars.ReplaceAllString(strings.TrimSpace(value), "_")
+	if strings.Trim(value, ".") == "" {
+		// Empty, or dots only such as ".."
+		return "unknown"
+	}
+	return value
+}
+
+// Convert the value of the field to the conventional case
+func normalizeCase(field, value string) string {
+	switch {
+	case lowerCaseFields[field]:
+		return strings.ToLower(value)
+	case upperCaseFields[field]:
+		return strings.ToUpper(value)
+	case field == "gridsquare" || field == "my_gridsquare":
+		return canonicalGridSquare(strings.TrimSpace(value))
+	}
+	return value
+}
+
+// Expand the template with the values of the record
+// extra gives the values of the placeholders other than the fields
+func expandTemplate(template string, record adifparser.ADIFRecord,
+	extra map[string]string) (string, error) {
+	var experr error
+	filename := regPlaceholder.ReplaceAllStringFunc(template,
+		func(placeholder string) string {
+			name := strings.ToLower(placeholder[1 : len(placeholder)-1])
+			if value, exists := extra[name]; exists {
+				return safeValue(value)
+			}
+			field := name
+			switch name {
+			case "year", "month", "day", "date":
+				field = "qso_date"
+			}
+			value, err := record.GetValue(field)
+			if err != nil && err != ErrNoSuchField {
+				experr = err
+				return ""
+			}
+			if field == "qso_date" && name != "date" {
+				if len(value) != 8 {
+					return "unknown"
+				}
+				switch name {
+				case "year":
+					value = value[0:4]
+				case "month":
+					value = value[4:6]
+				case "day":
+					value = value[6:8]
+				}
+			}
+			return safeValue(normalizeCase(field, value))
+		})
+	return filename, experr
+}
+
+// Flush and close the output file
+func (o *splitOutput) close() error {
+	if o.fp == nil {
+		return nil
+	}
+	err := o.writer.Flush()
+	if cerr := o.fp.Close(); err == nil {
+		err = cerr
+	}
+	o.fp = nil
+	o.writer = nil
+	return err
+}
+
+// Close the least recently used files to open another file
+func (s *splitOutputs) reserve() error {
+	for s.open.Len() >= s.maxopen {
+		output := s.open.Remove(s.open.Back()).(*splitOutput)
+		output.element = nil
+		if err := output.close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Obtain the output file of the file name
+// A new file is created with the input header passed through,
+// and a closed file is reopened for appending
+// Returns an error of os.ErrExist if a new file already exists
+func (s *splitOutputs) get(filename string) (*splitOutput, error) {
+	output, exists := s.outputs[filename]
+	if exists && output.fp != nil {
+		s.open.MoveToFront(output.element)
+		return output, nil
+	}
+	if err := s.reserve(); err != nil {
+		return nil, err
+	}
+	if exists {
+		fp, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return nil, err
+		}
+		output.fp = fp
+		output.writer = adifio.NewAppendWriter(fp, s.header, "goadifsplit")
+	} else {
+		if err := s.mkdirAll(filepath.Dir(filename)); err != nil {
+			return nil, err
+		}
+		fp, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return nil, err
+		}
+		s.created = append(s.created, filename)
+		writer := adifio.NewWriter(fp, s.header, "goadifsplit")
+		if err := writer.SetComment("goadifsplit\n"); err != nil {
+			fp.Close()
+			return nil, err
+		}
+		output = &splitOutput{filename: filename, fp: fp, writer: writer}
+		s.outputs[filename] = output
+	}
+	output.element = s.open.PushFront(output)
+	return output, nil
+}
+
+// Create the directory and its parents if they do not exist,
+// and record the directories to be created
+func (s *splitOutputs) mkdirAll(dir string) error {
+	missing := []string{}
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		missing = append(missing, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	// Parents first
+	for i := len(missing) - 1; i >= 0; i-- {
+		s.createddirs = append(s.createddirs, missing[i])
+	}
+	return os.MkdirAll(dir, 0755)
+}
+
+// Flush and close all output files
+func (s *splitOutputs) close() error {
+	var err error
+	for s.open.Len() > 0 {
+		output := s.open.Remove(s.open.Front()).(*splitOutput)
+		output.element = nil
+		if cerr := output.close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Close and remove all the files and directories created
+// Directories are removed only if empty
+func (s *splitOutputs) remove() {
+	s.close()
+	for _, filename := range s.created {
+		os.Remove(filename)
+	}
+	for i := len(s.createddirs) - 1; i >= 0; i-- {
+		os.Remove(s.createddirs[i])
+	}
+	s.outputs = make(map[string]*splitOutput)
+	s.created = nil
+	s.createddirs = nil
+}
+
+// Report the number of records of each output file
+func (s *splitOutputs) report(writer io.Writer) {
+	filenames := make([]string, 0, len(s.outputs))
+	for filename := range s.outputs {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	textwriter := bufio.NewWriter(writer)
+	for _, filename := range filenames {
+		fmt.Fprintf(textwriter, "%s: %d\n", filename, s.outputs[filename].count)
+	}
+	textwriter.Flush()
+}
+
+func main() {
+	var infile = flag.String("f", "", "input file (stdout in none)")
+	var template = flag.String("t", "", "output file name template")
+	var noheader = flag.Bool("nh", false, "do not pass through the input header")
+	var locationmode = flag.Bool("l", false, "split by station locations for LoTW/TQSL")
+	var maxopen = flag.Int("maxopen", 64, "maximum number of output files kept open")
+
+	var fp *os.File
+	var err error
+
+	flag.Usage = func() {
+		execname := os.Args[0]
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"goadifsplit: split ADIF records into multiple files by field values or periods")
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [-f infile] -t template\n", execname)
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"       %s [-f infile] [-t template] -l\n", execname)
+		flag.PrintDefaults()
+		details :=
+			"Template examples: log-{year}-{band}.adi, {station_callsign}/{year}{month}.adi\n" +
+				"Placeholders:\n" +
+				"  {year}, {month}, {day}: year (YYYY), month (MM), and day (DD) of qso_date\n" +
+				"  {date}: qso_date (YYYYMMDD)\n" +
+				"  {field}: value of the ADIF field (field name is case insensitive)\n" +
+				"Characters other than letters, digits, \".\", \"+\", and \"-\" in the values\n" +
+				"are replaced by \"_\" (e.g., JA1ABC/P -> JA1ABC_P)\n" +
+				"Empty or non-existing values are replaced by \"unknown\"\n" +
+				"Values of band and band_rx are in lowercase,\n" +
+				"and values of mode, submode, and other enumerations, and callsigns\n" +
+				"are in uppercase (e.g., {band}-{mode}: 20m-FT8)\n" +
+				"Directories in the file names are created if they do not exist\n" +
+				"Existing files are not overwritten: the command stops with an error,\n" +
+				"and the files and directories created by the command are removed\n" +
+				"-maxopen: the least recently used file is closed and reopened when needed\n" +
+				"Number of records of each output file is reported to stdout\n" +
+				"-l: split by station locations for LoTW/TQSL uploads\n" +
+				"    by station_callsign, my_gridsquare, my_cq_zone, my_itu_zone,\n" +
+				"    my_state, my_cnty, and my_dxcc\n" +
+				"    Placeholder {location} is the station location number\n" +
+				"    Default template: " + defaultLocationTemplate + "\n" +
+				"    Station locations are reported to stdout as\n" +
+				"    \"location N: field=value ...: number of records\"\n" +
+				"    Missing or inconsistent location data are reported to stderr\n"
+		fmt.Fprint(flag.CommandLine.Output(), details)
+	}
+
+	flag.Parse()
+
+	if *locationmode && *template == "" {
+		*template = defaultLocationTemplate
+	}
+	if *template == "" || len(flag.Args()) != 0 {
+		fmt.Fprint(os.Stderr, "Error: incorrect arguments\n")
+		flag.Usage()
+		return
+	}
+	if *maxopen <= 0 {
+		fmt.Fprint(os.Stderr, "Error: -maxopen must be positive\n")
+		return
+	}
+
+	if *infile == "" {
+		fp = os.Stdin
+	} else {
+		fp, err = os.Open(*infile)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+			return
+		}
+	}
+
+	header, input, err := adifio.ReadHeader(fp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if *noheader {
+		header = nil
+	}
+
+	outputs := newSplitOutputs(header, *maxopen)
+
+	locations := make(map[string]*stationLocation)
+	locationlist := []*stationLocation{}
+	locationcounts := make(map[int]int)
+	var extra map[string]string
+	recordnumber := 0
+
+	reader := adifparser.NewADIFReader(input)
+	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprint(os.Stderr, err)
+			}
+			break // when io.EOF break the loop!
+		}
+		recordnumber++
+
+		if *locationmode {
+			values, err := locationValues(record)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				outputs.remove()
+				return
+			}
+			for _, problem := range checkLocationValues(values) {
+				fmt.Fprintf(os.Stderr, "Warning: record %d: %s\n",
+					recordnumber, problem)
+			}
+			key := locationKey(values)
+			location, exists := locations[key]
+			if !exists {
+				location = &stationLocation{
+					id:     len(locationlist) + 1,
+					values: values,
+					first:  recordnumber,
+				}
+				locations[key] = location
+				locationlist = append(locationlist, location)
+			}
+			locationcounts[location.id]++
+			// Use the normalized location field values for the file name
+			extra = map[string]string{"location": fmt.Sprint(location.id)}
+			for field, value := range location.values {
+				extra[field] = value
+			}
+		}
+
+		filename, err := expandTemplate(*template, record, extra)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+			outputs.remove()
+			return
+		}
+		output, err := outputs.get(filename)
+		if errors.Is(err, os.ErrExist) {
+			fmt.Fprintf(os.Stderr, "Error: file %s already exists\n", filename)
+			outputs.remove()
+			return
+		} else if err != nil {
+			fmt.Fprint(os.Stderr, err)
+			outputs.remove()
+			return
+		}
+		if err := output.writer.WriteRecord(record); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		output.count++
+	}
+
+	if err := outputs.close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	if *locationmode {
+		for _, problem := range checkLocationConsistency(locationlist) {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", problem)
+		}
+		textwriter := bufio.NewWriter(os.Stdout)
+		for _, location := range locationlist {
+			fmt.Fprintf(textwriter, "location %d: %s: %d\n", location.id,
+				locationString(location.values), locationcounts[location.id])
+		}
+		textwriter.Flush()
+	}
+
+	outputs.report(os.Stdout)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+const testMainEnv = "GOADIFSPLIT_TEST_MAIN"
+
+// Run main() in a child process of the test binary
+func TestMain(m *testing.M) {
+	testutil.Main(m, testMainEnv, main)
+}
+
+// Read the calls of the ADIF file, and check the file has a single header
+func fileCalls(t *testing.T, filename string) []string {
+	t.Helper()
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(strings.ToLower(string(content)), "<eoh>"); n != 1 {
+		t.Errorf("%s: %d headers", filename, n)
+	}
+	return testutil.Calls(t, string(content))
+}
+
+func TestSafeValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"JA1ABC", "JA1ABC"},
+		{"JA1ABC/P", "JA1ABC_P"},
+		{" 1.25m ", "1.25m"},
+		{"a b*c", "a_b_c"},
+		{"", "unknown"},
+		{"..", "unknown"},
+		{"../x", ".._x"},
+	}
+	for _, tt := range tests {
+		if got := safeValue(tt.value); got != tt.want {
+			t.Errorf("safeValue(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	record := testutil.ParseRecord(t,
+		"<call:8>JA1ABC/P<band:3>20M<qso_date:8>20231125<station_callsign:6>JJ1BDX<eor>")
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"log-{year}-{band}.adi", "log-2023-20m.adi"},
+		{"{STATION_CALLSIGN}/{year}{month}{day}.adi", "JJ1BDX/20231125.adi"},
+		{"{date}-{call}.adi", "20231125-JA1ABC_P.adi"},
+		{"{mode}-{location}.adi", "unknown-3.adi"},
+		{"{band}.adi}", "20m.adi}"},
+	}
+	for _, tt := range tests {
+		got, err := expandTemplate(tt.template, record, map[string]string{"location": "3"})
+		if got != tt.want || err != nil {
+			t.Errorf("expandTemplate(%q) = %q, %v, want %q", tt.template, got, err, tt.want)
+		}
+	}
+	record = testutil.ParseRecord(t, "<band:3>20M<mode:3>ft8<gridsquare:6>pm95VQ<comment:3>Abc<eor>")
+	got, _ := expandTemplate("{band}-{mode}-{gridsquare}-{comment}", record, nil)
+	if want := "20m-FT8-PM95vq-Abc"; got != want {
+		t.Errorf("expandTemplate(case) = %q, want %q", got, want)
+	}
+	record = testutil.ParseRecord(t, "<qso_date:6>202311<eor>")
+	if got, _ := expandTemplate("{year}-{date}", record, nil); got != "unknown-202311" {
+		t.Errorf("expandTemplate(invalid date) = %q", got)
+	}
+}
+
+const testLog = "<call:4>A1AA<band:3>20m<qso_date:8>20231125<eor>\n" +
+	"<call:4>A1AB<band:3>40m<qso_date:8>20231125<eor>\n" +
+	"<call:4>A1AC<band:3>15m<qso_date:8>20231126<eor>\n" +
+	"<call:4>A1AD<band:3>20m<qso_date:8>20231126<eor>\n" +
+	"<call:4>A1AE<band:3>40m<qso_date:8>20231126<eor>\n" +
+	"<call:4>A1AF<band:3>15m<qso_date:8>20231126<eor>\n"
+
+func TestSplit(t *testing.T) {
+	for _, maxopen := range []string{"64", "2", "1"} {
+		dir := t.TempDir()
+		template := filepath.Join(dir, "{year}", "log-{band}.adi")
+		stdout, stderr := testutil.Run(t, testMainEnv, testLog, "-maxopen", maxopen, "-t", template)
+		if stderr != "" {
+			t.Errorf("-maxopen %s: stderr %q", maxopen, stderr)
+		}
+		want := map[string][]string{
+			"15m": {"A1AC", "A1AF"},
+			"20m": {"A1AA", "A1AD"},
+			"40m": {"A1AB", "A1AE"},
+		}
+		report := ""
+		for _, band := range []string{"15m", "20m", "40m"} {
+			filename := filepath.Join(dir, "2023", "log-"+band+".adi")
+			report += filename + ": 2\n"
+			if got := fileCalls(t, filename); !reflect.DeepEqual(got, want[band]) {
+				t.Errorf("-maxopen %s: %s = %v, want %v", maxopen, band, got, want[band])
+			}
+		}
+		if stdout != report {
+			t.Errorf("-maxopen %s: report = %q, want %q", maxopen, stdout, report)
+		}
+	}
+}
+
+func TestSplitConflict(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "log-15m.adi")
+	if err := os.WriteFile(existing, []byte("existing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := testutil.Run(t, testMainEnv, testLog, "-maxopen", "1",
+		"-t", filepath.Join(dir, "log-{band}.adi"))
+	if stdout != "" || stderr != "Error: file "+existing+" already exists\n" {
+		t.Errorf("stdout %q, stderr %q", stdout, stderr)
+	}
+	// The files created before the conflict are removed
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "log-15m.adi" {
+		t.Errorf("files left: %v", entries)
+	}
+	if content, _ := os.ReadFile(existing); string(content) != "existing" {
+		t.Errorf("existing file changed: %q", content)
+	}
+}
+
+func TestSplitConflictDirectories(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "20231126", "15m", "log.adi")
+	if err := os.MkdirAll(filepath.Dir(existing), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(existing, []byte("existing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr := testutil.Run(t, testMainEnv, testLog,
+		"-t", filepath.Join(dir, "{date}", "{band}", "log.adi"))
+	if stderr != "Error: file "+existing+" already exists\n" {
+		t.Errorf("stderr %q", stderr)
+	}
+	// The directories created before the conflict are removed
+	left := []string{}
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && path != dir {
+			rel, _ := filepath.Rel(dir, path)
+			left = append(left, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if want := []string{"20231126", "20231126/15m", "20231126/15m/log.adi"}; !reflect.DeepEqual(left, want) {
+		t.Errorf("left = %v, want %v", left, want)
+	}
+}
+
+func TestSplitCase(t *testing.T) {
+	dir := t.TempDir()
+	input := "<call:4>A1AA<band:3>20M<mode:3>ft8<eor>\n" +
+		"<call:4>A1AB<band:3>20m<mode:3>FT8<eor>\n" +
+		"<call:4>A1AC<band:3>40m<mode:2>Cw<eor>\n"
+	stdout, stderr := testutil.Run(t, testMainEnv, input,
+		"-t", filepath.Join(dir, "{band}-{mode}.adi"))
+	if stderr != "" {
+		t.Errorf("stderr %q", stderr)
+	}
+	want := filepath.Join(dir, "20m-FT8.adi") + ": 2\n" +
+		filepath.Join(dir, "40m-CW.adi") + ": 1\n"
+	if stdout != want {
+		t.Errorf("report = %q, want %q", stdout, want)
+	}
+}
+
+func TestSplitInvalidUserdef(t *testing.T) {
+	dir := t.TempDir()
+	input := "log <userdef1:9:N>EPC,{1:9}<eoh>\n" +
+		"<call:4>A1AA<band:3>20m<epc:1>5<eor>\n" +
+		"<call:4>A1AB<band:3>20m<epc:2>10<eor>\n"
+	filename := filepath.Join(dir, "log-20m.adi")
+	stdout, stderr := testutil.Run(t, testMainEnv, input, "-t", filepath.Join(dir, "log-{band}.adi"))
+	// Records with invalid values are written and counted with warnings
+	if !strings.Contains(stderr, `invalid USERDEF field value: epc: "10"`) {
+		t.Errorf("stderr %q", stderr)
+	}
+	if stdout != filename+": 2\n" {
+		t.Errorf("report = %q", stdout)
+	}
+	if got := fileCalls(t, filename); !reflect.DeepEqual(got, []string{"A1AA", "A1AB"}) {
+		t.Errorf("calls = %v", got)
+	}
+}
@@ -0,0 +1,169 @@
+// goadifsplit: station locations for LoTW/TQSL uploads
+// by Kenji Rikitake, JJ1BDX
+//
+// TQSL requires each upload to match a single Station Location.
+// Records are grouped by the following location fields:
+//  station_callsign, my_gridsquare, my_cq_zone, my_itu_zone,
+//  my_state, my_cnty, my_dxcc
+// The values are compared case-insensitively,
+// and grid squares are compared with the canonical case (e.g., PM95vq).
+// Locations are numbered in the order of appearance.
+//
+// The following problems are reported as warnings:
+//  missing station_callsign, my_gridsquare, or my_dxcc
+//  invalid my_gridsquare
+//  my_cnty without my_state
+//  locations with the same station_callsign and the first four characters
+//  of my_gridsquare but different location fields other than my_gridsquare
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jj1bdx/adifparser"
+)
+
+// Location fields in the order of the location key
+var locationFields = []string{
+	"station_callsign",
+	"my_gridsquare",
+	"my_cq_zone",
+	"my_itu_zone",
+	"my_state",
+	"my_cnty",
+	"my_dxcc",
+}
+
+// Location fields required for TQSL uploads
+var requiredLocationFields = []string{
+	"station_callsign",
+	"my_gridsquare",
+	"my_dxcc",
+}
+
+// Grid square of 2, 4, 6, or 8 characters
+var regGridSquare = regexp.MustCompile(`^[A-Ra-r]{2}([0-9]{2}([A-Xa-x]{2}([0-9]{2})?)?)?$`)
+
+// Station location
+type stationLocation struct {
+	id     int
+	values map[string]string
+	// Record number of the first record
+	first int
+}
+
+// Convert a grid square to the canonical case
+func canonicalGridSquare(grid string) string {
+	if len(grid) <= 4 {
+		return strings.ToUpper(grid)
+	}
+	return strings.ToUpper(grid[0:4]) + strings.ToLower(grid[4:])
+}
+
+// Obtain the location field values of a record
+func locationValues(record adifparser.ADIFRecord) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, field := range locationFields {
+		value, err := record.GetValue(field)
+		if err != nil && err != ErrNoSuchField {
+			return nil, err
+		}
+		value = strings.TrimSpace(value)
+		if field == "my_gridsquare" {
+			value = canonicalGridSquare(value)
+		} else {
+			value = strings.ToUpper(value)
+		}
+		values[field] = value
+	}
+	return values, nil
+}
+
+// Location key of the location field values
+func locationKey(values map[string]string) string {
+	parts := make([]string, len(locationFields))
+	for i, field := range locationFields {
+		parts[i] = values[field]
+	}
+	return strings.Join(parts, "\t")
+}
+
+// Describe the location field values
+func locationString(values map[string]string) string {
+	parts := []string{}
+	for _, field := range locationFields {
+		if values[field] != "" {
+			parts = append(parts, field+"="+values[field])
+		}
+	}
+	if len(parts) == 0 {
+		return "(EMPTY)"
+	}
+	return strings.Join(parts, " ")
+}
+
+// Check the location field values of a record
+// Returns the descriptions of the problems
+func checkLocationValues(values map[string]string) []string {
+	problems := []string{}
+	for _, field := range requiredLocationFields {
+		if values[field] == "" {
+			problems = append(problems, "missing "+field)
+		}
+	}
+	grid := values["my_gridsquare"]
+	if grid != "" && !regGridSquare.MatchString(grid) {
+		problems = append(problems, "invalid my_gridsquare "+grid)
+	}
+	if values["my_cnty"] != "" && values["my_state"] == "" {
+		problems = append(problems, "my_cnty without my_state")
+	}
+	return problems
+}
+
+// Find the locations with the same station_callsign and
+// the first four characters of my_gridsquare
+// but different location fields other than my_gridsquare
+// Returns the descriptions of the inconsistencies
+func checkLocationConsistency(locations []*stationLocation) []string {
+	problems := []string{}
+	groups := make(map[string][]*stationLocation)
+	keys := []string{}
+	for _, location := range locations {
+		grid := location.values["my_gridsquare"]
+		if len(grid) > 4 {
+			grid = grid[0:4]
+		}
+		key := location.values["station_callsign"] + " " + grid
+		if _, exists := groups[key]; !exists {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], location)
+	}
+	for _, key := range keys {
+		group := groups[key]
+		differs := false
+		for _, location := range group[1:] {
+			for _, field := range locationFields {
+				if field != "my_gridsquare" &&
+					location.values[field] != group[0].values[field] {
+					differs = true
+				}
+			}
+		}
+		if !differs {
+			continue
+		}
+		ids := make([]string, len(group))
+		for i, location := range group {
+			ids[i] = fmt.Sprint(location.id)
+		}
+		problems = append(problems,
+			fmt.Sprintf("inconsistent locations %s with the same station_callsign and grid %s",
+				strings.Join(ids, ", "), key))
+	}
+	return problems
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+func TestCanonicalGridSquare(t *testing.T) {
+	tests := []struct {
+		grid string
+		want string
+	}{
+		{"pm", "PM"},
+		{"pm95", "PM95"},
+		{"pm95VQ", "PM95vq"},
+		{"Pm95vQ12", "PM95vq12"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := canonicalGridSquare(tt.grid); got != tt.want {
+			t.Errorf("canonicalGridSquare(%q) = %q, want %q", tt.grid, got, tt.want)
+		}
+	}
+}
+
+func TestLocationValues(t *testing.T) {
+	record := testutil.ParseRecord(t, "<station_callsign:6>jj1bdx<my_gridsquare:6>pm95VQ"+
+		"<my_cq_zone:2>25<my_state:3> 13<call:4>A1AA<eor>")
+	values, err := locationValues(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"station_callsign": "JJ1BDX",
+		"my_gridsquare":    "PM95vq",
+		"my_cq_zone":       "25",
+		"my_itu_zone":      "",
+		"my_state":         "13",
+		"my_cnty":          "",
+		"my_dxcc":          "",
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("locationValues = %v, want %v", values, want)
+	}
+	if got := locationKey(values); got != "JJ1BDX\tPM95vq\t25\t\t13\t\t" {
+		t.Errorf("locationKey = %q", got)
+	}
+	if got := locationString(values); got !=
+		"station_callsign=JJ1BDX my_gridsquare=PM95vq my_cq_zone=25 my_state=13" {
+		t.Errorf("locationString = %q", got)
+	}
+	if got := locationString(map[string]string{}); got != "(EMPTY)" {
+		t.Errorf("locationString(empty) = %q", got)
+	}
+}
+
+func TestCheckLocationValues(t *testing.T) {
+	tests := []struct {
+		values map[string]string
+		want   []string
+	}{
+		{map[string]string{"station_callsign": "JJ1BDX", "my_gridsquare": "PM95vq",
+			"my_dxcc": "339"}, []string{}},
+		{map[string]string{}, []string{"missing station_callsign",
+			"missing my_gridsquare", "missing my_dxcc"}},
+		{map[string]string{"station_callsign": "JJ1BDX", "my_gridsquare": "PM9",
+			"my_dxcc": "339", "my_cnty": "X"},
+			[]string{"invalid my_gridsquare PM9", "my_cnty without my_state"}},
+		{map[string]string{"station_callsign": "JJ1BDX", "my_gridsquare": "SM95",
+			"my_dxcc": "339", "my_cnty": "X", "my_state": "Y"},
+			[]string{"invalid my_gridsquare SM95"}},
+	}
+	for _, tt := range tests {
+		if got := checkLocationValues(tt.values); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("checkLocationValues(%v) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestCheckLocationConsistency(t *testing.T) {
+	location := func(id int, call, grid, state string) *stationLocation {
+		return &stationLocation{id: id, values: map[string]string{
+			"station_callsign": call, "my_gridsquare": grid, "my_state": state}}
+	}
+	locations := []*stationLocation{
+		location(1, "JJ1BDX", "PM95vq", "13"),
+		// Same location fields except for the grid
+		location(2, "JJ1BDX", "PM95vr", "13"),
+		location(3, "JJ1BDX", "PM95", "14"),
+		location(4, "JJ1BDX", "PM96", "15"),
+		location(5, "JA1ABC", "PM95", "13"),
+	}
+	want := []string{"inconsistent locations 1, 2, 3 " +
+		"with the same station_callsign and grid JJ1BDX PM95"}
+	if got := checkLocationConsistency(locations); !reflect.DeepEqual(got, want) {
+		t.Errorf("checkLocationConsistency = %q, want %q", got, want)
+	}
+	if got := checkLocationConsistency(locations[:2]); len(got) != 0 {
+		t.Errorf("checkLocationConsistency(consistent) = %q", got)
+	}
+}
+
+func TestSplitLocations(t *testing.T) {
+	input := "<call:4>A1AA<station_callsign:6>JJ1BDX<my_gridsquare:6>PM95vq<my_dxcc:3>339<eor>\n" +
+		"<call:4>A1AB<station_callsign:8>JJ1BDX/1<my_gridsquare:4>PM96<my_dxcc:3>339<eor>\n" +
+		"<call:4>A1AC<station_callsign:6>jj1bdx<my_gridsquare:6>PM95VQ<my_dxcc:3>339<eor>\n" +
+		"<call:4>A1AD<station_callsign:6>JJ1BDX<my_gridsquare:4>PM95<eor>\n"
+	dir := t.TempDir()
+	stdout, stderr := testutil.Run(t, testMainEnv, input, "-l",
+		"-t", filepath.Join(dir, "{station_callsign}-{my_gridsquare}-{location}.adi"))
+	wantfiles := map[string][]string{
+		"JJ1BDX-PM95vq-1.adi": {"A1AA", "A1AC"},
+		"JJ1BDX_1-PM96-2.adi": {"A1AB"},
+		"JJ1BDX-PM95-3.adi":   {"A1AD"},
+	}
+	for name, calls := range wantfiles {
+		if got := fileCalls(t, filepath.Join(dir, name)); !reflect.DeepEqual(got, calls) {
+			t.Errorf("%s = %v, want %v", name, got, calls)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != len(wantfiles) {
+		t.Errorf("%d files, want %d", len(entries), len(wantfiles))
+	}
+	wantstdout := "location 1: station_callsign=JJ1BDX my_gridsquare=PM95vq my_dxcc=339: 2\n" +
+		"location 2: station_callsign=JJ1BDX/1 my_gridsquare=PM96 my_dxcc=339: 1\n" +
+		"location 3: station_callsign=JJ1BDX my_gridsquare=PM95: 1\n" +
+		filepath.Join(dir, "JJ1BDX-PM95-3.adi") + ": 1\n" +
+		filepath.Join(dir, "JJ1BDX-PM95vq-1.adi") + ": 2\n" +
+		filepath.Join(dir, "JJ1BDX_1-PM96-2.adi") + ": 1\n"
+	if stdout != wantstdout {
+		t.Errorf("stdout = %q, want %q", stdout, wantstdout)
+	}
+	wantstderr := "Warning: record 4: missing my_dxcc\n" +
+		"Warning: inconsistent locations 1, 3 with the same station_callsign and grid JJ1BDX PM95\n"
+	if stderr != wantstderr {
+		t.Errorf("stderr = %q, want %q", stderr, wantstderr)
+	}
+}
@@ -1,51 +1,243 @@
 // goadifstat: check statistics of ADIF ADI files
 // by Kenji Rikitake, JJ1BDX
-// Usage: goadifstat [-f infile] [-o outfile] -q query type
-// Valid query types: bands, country, cqz, dxcc, gridsquare, modes, nqso, submodes
+// Usage: goadifstat [-f infile] [-o outfile] [-s sort order] -q query type
+//        goadifstat [-f infile] [-f2 infile2] [-o outfile]
+//        [-start1 time-expr] [-end1 time-expr]
+//        [-start2 time-expr] [-end2 time-expr] -q compare
+// Valid query types: bands, compare, cont, country, cqz, dxcc,
+//                    gridsquare, ituz, modes, nqso, operator, station,
+//                    submodes, wpx
+//
+// dxcc: list DXCC entities with the entity code, main prefix,
+//       continent, QSO count, and entity name,
+//       resolved with the Club Log database (cty.xml) through gocldb
+//       Deleted entities are flagged as (DELETED)
+// Valid sort orders for dxcc: code (default), name, prefix, count
+//
+// wpx: list WPX prefixes derived from the call field
+//      with the CQ WPX Contest prefix rules
+//
+// operator, station: statistics grouped by the operator or
+//                    station_callsign field, showing QSO count,
+//                    operating hours, DXCC entities, bands and modes
+//                    Operating hours are the number of distinct UTC hours
+//                    with at least one QSO by qso_date and time_on
+//
+// compare: compare the statistics of the two periods, and show
+//          new DXCC entities, CQ/ITU zones, and grid squares, and
+//          changes of QSO counts per band and mode
+//          from the first period to the second period
+//   With -f2: the first period is from infile (filtered by -start1/-end1)
+//             and the second period is from infile2 (filtered by -start2/-end2)
+//   Without -f2: the both periods are from infile,
+//                filtered by -start1/-end1 and -start2/-end2 respectively
+//   Time of ADIF record determined by: qso_date and time_on
+//   Time expressions are the same as goadiftime (see adiftime package)
+//   in UTC: -start1/-start2 use the start of the time expression,
+//   and -end1/-end2 use the end of the time expression
+//   (e.g., -start1 "cqww-cw 2022" -end1 "cqww-cw 2022")
 
 package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifband"
+	"github.com/jj1bdx/goadiftools/adiftime"
+	"github.com/jj1bdx/goadiftools/callsign"
+	"github.com/jj1bdx/goadiftools/dxcc"
 	"io"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var ErrNoSuchField = adifparser.ErrNoSuchField
 
-var bandList = []string{
-	"2190m", "630m", "560m", "160m", "80m", "60m",
-	"40m", "30m", "20m", "17m", "15m", "12m",
-	"10m", "6m", "5m", "4m", "2m", "1.25m",
-	"70cm", "33cm", "23cm", "13cm", "9cm", "6cm",
-	"3cm", "1.25cm", "6mm", "4mm", "2.5mm", "2mm",
-	"1mm"}
-
-var mapBand map[string]int
-var mapCountry map[string]int
-var mapCqz map[int]bool
-var mapDxcc map[int]bool
-var mapGrid map[string]bool
-var mapMode map[string]int
-var mapSubmode map[string]int
-
-func initStatMaps() {
-	mapBand = make(map[string]int)
-	mapCountry = make(map[string]int)
-	mapCqz = make(map[int]bool)
-	mapDxcc = make(map[int]bool)
-	mapGrid = make(map[string]bool)
-	mapMode = make(map[string]int)
-	mapSubmode = make(map[string]int)
+// Statistics of a group of records
+// for per-operator and per-station statistics
+type groupStat struct {
+	nqso  int
+	bands map[string]int
+	modes map[string]int
+	dxcc  map[int]bool
+	hours map[string]bool
 }
 
-func updateStatMaps(record adifparser.ADIFRecord) {
+// Statistics maps
+type statMaps struct {
+	band     map[string]int
+	cont     map[string]int
+	country  map[string]int
+	cqz      map[int]bool
+	dxcc     map[int]int
+	grid     map[string]bool
+	ituz     map[int]bool
+	mode     map[string]int
+	submode  map[string]int
+	wpx      map[string]bool
+	operator map[string]*groupStat
+	station  map[string]*groupStat
+}
+
+// Create empty statistics maps
+func newStatMaps() statMaps {
+	return statMaps{
+		band:     make(map[string]int),
+		cont:     make(map[string]int),
+		country:  make(map[string]int),
+		cqz:      make(map[int]bool),
+		dxcc:     make(map[int]int),
+		grid:     make(map[string]bool),
+		ituz:     make(map[int]bool),
+		mode:     make(map[string]int),
+		submode:  make(map[string]int),
+		wpx:      make(map[string]bool),
+		operator: make(map[string]*groupStat),
+		station:  make(map[string]*groupStat),
+	}
+}
+
+// Time window for filtering records
+// Zero start or end means no limit
+type timeWindow struct {
+	start time.Time
+	end   time.Time
+}
+
+// Parse the start and end time of a time window in time expressions
+// (see adiftime package for the syntax)
+// The start of the start expression and the end of the end expression
+// are used, and dates are in UTC
+func parseTimeWindow(start, end string, now time.Time) (timeWindow, error) {
+	var window timeWindow
+	if start != "" {
+		t, _, err := adiftime.ParseTimeExpr(start, now, time.UTC)
+		if err != nil {
+			return window, err
+		}
+		window.start = t.UTC()
+	}
+	if end != "" {
+		_, t, err := adiftime.ParseTimeExpr(end, now, time.UTC)
+		if err != nil {
+			return window, err
+		}
+		window.end = t.UTC()
+	}
+	if !window.start.IsZero() && !window.end.IsZero() &&
+		window.start.After(window.end) {
+		return window, errors.New("start time is after end time")
+	}
+	return window, nil
+}
+
+// Return true if the time window has any limit
+func (w timeWindow) limited() bool {
+	return !w.start.IsZero() || !w.end.IsZero()
+}
+
+// Check if start <= t <= end
+func (w timeWindow) contains(t time.Time) bool {
+	passstart := w.start.IsZero() || !t.Before(w.start)
+	passend := w.end.IsZero() || !t.After(w.end)
+	return passstart && passend
+}
+
+// Update the groupStat of the group named by the value of groupfield
+func updateGroupStat(groups map[string]*groupStat, groupfield string,
+	record adifparser.ADIFRecord) {
+	group, err := record.GetValue(groupfield)
+	if err != nil && err != ErrNoSuchField {
+		fmt.Fprint(os.Stderr, err)
+		return
+	}
+	if group == "" {
+		group = "(UNKNOWN)"
+	} else {
+		// Use uppercase for callsigns
+		group = strings.ToUpper(group)
+	}
+	stat, exists := groups[group]
+	if !exists {
+		stat = &groupStat{
+			bands: make(map[string]int),
+			modes: make(map[string]int),
+			dxcc:  make(map[int]bool),
+			hours: make(map[string]bool),
+		}
+		groups[group] = stat
+	}
+	stat.nqso++
+
+	band, err := record.GetValue("band")
+	if err == nil && band != "" {
+		stat.bands[strings.ToLower(band)]++
+	}
+	mode, err := record.GetValue("mode")
+	if err == nil && mode != "" {
+		stat.modes[strings.ToUpper(mode)]++
+	}
+	dxcc, err := record.GetValue("dxcc")
+	if err == nil && dxcc != "" {
+		keynum, err := strconv.Atoi(dxcc)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+		} else {
+			stat.dxcc[keynum] = true
+		}
+	}
+	// Operating hour: YYYYMMDDHH
+	adifdate, err := record.GetValue("qso_date")
+	if err != nil || len(adifdate) < 8 {
+		return
+	}
+	adiftime, err := record.GetValue("time_on")
+	if err != nil || len(adiftime) < 2 {
+		return
+	}
+	stat.hours[adifdate[0:8]+adiftime[0:2]] = true
+}
+
+// Output groupStat of all groups sorted by the group name
+func groupStatOutput(groups map[string]*groupStat, writer *bufio.Writer) {
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		stat := groups[k]
+		fmt.Fprintf(writer, "%s: qsos %d hours %d entities %d\n",
+			k, stat.nqso, len(stat.hours), len(stat.dxcc))
+		fmt.Fprintf(writer, "  bands: ")
+		for band := range adifband.Bands {
+			num, exists := stat.bands[adifband.Bands[band]]
+			if exists {
+				fmt.Fprintf(writer, "%s %d ", adifband.Bands[band], num)
+			}
+		}
+		fmt.Fprintf(writer, "\n")
+		modes := make([]string, 0, len(stat.modes))
+		for m := range stat.modes {
+			modes = append(modes, m)
+		}
+		sort.Strings(modes)
+		fmt.Fprintf(writer, "  modes: ")
+		for _, m := range modes {
+			fmt.Fprintf(writer, "%s %d ", m, stat.modes[m])
+		}
+		fmt.Fprintf(writer, "\n")
+	}
+}
+
+// Update the statistics maps with the record
+func (m statMaps) update(record adifparser.ADIFRecord) {
 	var err error
 	var exists bool
 	var key string
@@ -58,11 +250,11 @@ func updateStatMaps(record adifparser.ADIFRecord) {
 	} else {
 		// Use *lowercase* for band names
 		key = strings.ToLower(key)
-		_, exists = mapBand[key]
+		_, exists = m.band[key]
 		if exists {
-			mapBand[key]++
+			m.band[key]++
 		} else {
-			mapBand[key] = 1
+			m.band[key] = 1
 		}
 	}
 
@@ -77,11 +269,11 @@ func updateStatMaps(record adifparser.ADIFRecord) {
 			// Use uppercase for country names
 			key = strings.ToUpper(key)
 		}
-		_, exists = mapCountry[key]
+		_, exists = m.country[key]
 		if exists {
-			mapCountry[key]++
+			m.country[key]++
 		} else {
-			mapCountry[key] = 1
+			m.country[key] = 1
 		}
 	}
 
@@ -95,13 +287,63 @@ func updateStatMaps(record adifparser.ADIFRecord) {
 		if err != nil && err != ErrNoSuchField {
 			fmt.Fprint(os.Stderr, err)
 		} else {
-			_, exists = mapCqz[keynum]
+			_, exists = m.cqz[keynum]
+			if !exists {
+				m.cqz[keynum] = true
+			}
+		}
+	}
+
+	// ituz
+	key, err = record.GetValue("ituz")
+	if err != nil && err != ErrNoSuchField {
+		fmt.Fprint(os.Stderr, err)
+	} else if key != "" {
+		// Ituz values are integers
+		keynum, err = strconv.Atoi(key)
+		if err != nil && err != ErrNoSuchField {
+			fmt.Fprint(os.Stderr, err)
+		} else {
+			_, exists = m.ituz[keynum]
 			if !exists {
-				mapCqz[keynum] = true
+				m.ituz[keynum] = true
 			}
 		}
 	}
 
+	// cont
+	key, err = record.GetValue("cont")
+	if err != nil && err != ErrNoSuchField {
+		fmt.Fprint(os.Stderr, err)
+	} else if key != "" {
+		// Use uppercase for continent names
+		key = strings.ToUpper(key)
+		_, exists = m.cont[key]
+		if exists {
+			m.cont[key]++
+		} else {
+			m.cont[key] = 1
+		}
+	}
+
+	// wpx
+	key, err = record.GetValue("call")
+	if err != nil && err != ErrNoSuchField {
+		fmt.Fprint(os.Stderr, err)
+	} else if key != "" {
+		key = callsign.WpxPrefix(key)
+		if key != "" {
+			_, exists = m.wpx[key]
+			if !exists {
+				m.wpx[key] = true
+			}
+		}
+	}
+
+	// operator and station_callsign
+	updateGroupStat(m.operator, "operator", record)
+	updateGroupStat(m.station, "station_callsign", record)
+
 	// dxcc
 	key, err = record.GetValue("dxcc")
 	if err != nil && err != ErrNoSuchField {
@@ -112,9 +354,11 @@ func updateStatMaps(record adifparser.ADIFRecord) {
 		if err != nil && err != ErrNoSuchField {
 			fmt.Fprint(os.Stderr, err)
 		} else {
-			_, exists = mapDxcc[keynum]
-			if !exists {
-				mapDxcc[keynum] = true
+			_, exists = m.dxcc[keynum]
+			if exists {
+				m.dxcc[keynum]++
+			} else {
+				m.dxcc[keynum] = 1
 			}
 		}
 	}
@@ -128,9 +372,9 @@ func updateStatMaps(record adifparser.ADIFRecord) {
 		key = key[0:4]
 		// Grid locator first two letters are uppercase
 		key = strings.ToUpper(key)
-		_, exists = mapGrid[key]
+		_, exists = m.grid[key]
 		if !exists {
-			mapGrid[key] = true
+			m.grid[key] = true
 		}
 	}
 
@@ -140,11 +384,11 @@ func updateStatMaps(record adifparser.ADIFRecord) {
 		fmt.Fprint(os.Stderr, err)
 	} else {
 		key = strings.ToUpper(key)
-		_, exists = mapMode[key]
+		_, exists = m.mode[key]
 		if exists {
-			mapMode[key]++
+			m.mode[key]++
 		} else {
-			mapMode[key] = 1
+			m.mode[key] = 1
 		}
 	}
 
@@ -154,40 +398,91 @@ func updateStatMaps(record adifparser.ADIFRecord) {
 		fmt.Fprint(os.Stderr, err)
 	} else if key != "" {
 		key = strings.ToUpper(key)
-		_, exists = mapSubmode[key]
+		_, exists = m.submode[key]
 		if exists {
-			mapSubmode[key]++
+			m.submode[key]++
 		} else {
-			mapSubmode[key] = 1
+			m.submode[key] = 1
 		}
 	}
 }
 
-func statOutput(query *string, writer *bufio.Writer,
+// DXCC entity info and QSO count for dxcc output
+type dxccEntity struct {
+	dxcc.Entity
+	count int
+}
+
+// Resolve DXCC entity codes of the QSO count map
+func resolveDxccEntities(counts map[int]int) []dxccEntity {
+	entities := make([]dxccEntity, 0, len(counts))
+	for code, count := range counts {
+		entity, _ := dxcc.Lookup(code)
+		entities = append(entities, dxccEntity{entity, count})
+	}
+	return entities
+}
+
+// Sort DXCC entities by the given sort order
+// Ties are sorted by the entity code
+func sortDxccEntities(entities []dxccEntity, order string) {
+	sort.Slice(entities, func(i, j int) bool {
+		a := entities[i]
+		b := entities[j]
+		switch order {
+		case "name":
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+		case "prefix":
+			if a.Prefix != b.Prefix {
+				return a.Prefix < b.Prefix
+			}
+		case "count":
+			// Larger counts first
+			if a.count != b.count {
+				return a.count > b.count
+			}
+		}
+		return a.Code < b.Code
+	})
+}
+
+func statOutput(m statMaps, query *string, sortorder *string, writer *bufio.Writer,
 	reader adifparser.ADIFReader) {
 	// Calculate and output the stats
 	switch {
 	case *query == "bands":
-		for band := range bandList {
-			num, exists := mapBand[bandList[band]]
+		for band := range adifband.Bands {
+			num, exists := m.band[adifband.Bands[band]]
 			if exists {
-				fmt.Fprintf(writer, "%s %d ", bandList[band], num)
+				fmt.Fprintf(writer, "%s %d ", adifband.Bands[band], num)
 			}
 		}
 		fmt.Fprintf(writer, "\n")
+	case *query == "cont":
+		keys := make([]string, 0, len(m.cont))
+		for k := range m.cont {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(writer, "%s %d ", k, m.cont[k])
+		}
+		fmt.Fprintf(writer, "\n")
 	case *query == "country":
-		keys := make([]string, 0, len(mapCountry))
-		for k := range mapCountry {
+		keys := make([]string, 0, len(m.country))
+		for k := range m.country {
 			keys = append(keys, k)
 		}
 		sort.Strings(keys)
 		for _, k := range keys {
-			fmt.Fprintf(writer, "%s: %d\n", k, mapCountry[k])
+			fmt.Fprintf(writer, "%s: %d\n", k, m.country[k])
 		}
 		fmt.Fprintln(writer, "(TOTAL):", reader.RecordCount())
 	case *query == "cqz":
-		keys := make([]int, 0, len(mapCqz))
-		for k := range mapCqz {
+		keys := make([]int, 0, len(m.cqz))
+		for k := range m.cqz {
 			keys = append(keys, k)
 		}
 		sort.Ints(keys)
@@ -196,18 +491,20 @@ func statOutput(query *string, writer *bufio.Writer,
 		}
 		fmt.Fprintf(writer, "\n")
 	case *query == "dxcc":
-		keys := make([]int, 0, len(mapDxcc))
-		for k := range mapDxcc {
-			keys = append(keys, k)
-		}
-		sort.Ints(keys)
-		for _, n := range keys {
-			fmt.Fprintf(writer, "%d ", n)
+		entities := resolveDxccEntities(m.dxcc)
+		sortDxccEntities(entities, *sortorder)
+		for _, e := range entities {
+			deleted := ""
+			if e.Deleted {
+				deleted = " (DELETED)"
+			}
+			fmt.Fprintf(writer, "%3d %-6s %-2s %5d %s%s\n",
+				e.Code, e.Prefix, e.Cont, e.count, e.Name, deleted)
 		}
-		fmt.Fprintf(writer, "\n")
+		fmt.Fprintln(writer, "(ENTITIES):", len(entities))
 	case *query == "gridsquare":
-		keys := make([]string, 0, len(mapGrid))
-		for k := range mapGrid {
+		keys := make([]string, 0, len(m.grid))
+		for k := range m.grid {
 			keys = append(keys, k)
 		}
 		sort.Strings(keys)
@@ -215,24 +512,48 @@ func statOutput(query *string, writer *bufio.Writer,
 			fmt.Fprintf(writer, "%s ", g)
 		}
 		fmt.Fprintf(writer, "\n")
+	case *query == "ituz":
+		keys := make([]int, 0, len(m.ituz))
+		for k := range m.ituz {
+			keys = append(keys, k)
+		}
+		sort.Ints(keys)
+		for _, n := range keys {
+			fmt.Fprintf(writer, "%d ", n)
+		}
+		fmt.Fprintf(writer, "\n")
 	case *query == "modes":
-		keys := make([]string, 0, len(mapMode))
-		for k := range mapMode {
+		keys := make([]string, 0, len(m.mode))
+		for k := range m.mode {
 			keys = append(keys, k)
 		}
 		sort.Strings(keys)
 		for _, k := range keys {
-			fmt.Fprintf(writer, "%s %d ", k, mapMode[k])
+			fmt.Fprintf(writer, "%s %d ", k, m.mode[k])
 		}
 		fmt.Fprintf(writer, "\n")
+	case *query == "operator":
+		groupStatOutput(m.operator, writer)
+	case *query == "station":
+		groupStatOutput(m.station, writer)
 	case *query == "submodes":
-		keys := make([]string, 0, len(mapSubmode))
-		for k := range mapSubmode {
+		keys := make([]string, 0, len(m.submode))
+		for k := range m.submode {
 			keys = append(keys, k)
 		}
 		sort.Strings(keys)
 		for _, k := range keys {
-			fmt.Fprintf(writer, "%s %d ", k, mapSubmode[k])
+			fmt.Fprintf(writer, "%s %d ", k, m.submode[k])
+		}
+		fmt.Fprintf(writer, "\n")
+	case *query == "wpx":
+		keys := make([]string, 0, len(m.wpx))
+		for k := range m.wpx {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, p := range keys {
+			fmt.Fprintf(writer, "%s ", p)
 		}
 		fmt.Fprintf(writer, "\n")
 	case *query == "nqso":
@@ -242,10 +563,143 @@ func statOutput(query *string, writer *bufio.Writer,
 	}
 }
 
+// Total QSO count of the statistics maps
+// Note: m.band counts all records including those without band field
+func statMapsQSOs(m statMaps) int {
+	total := 0
+	for _, n := range m.band {
+		total += n
+	}
+	return total
+}
+
+// Output the keys which exist in next but not in base
+func newIntKeysOutput(title string, base, next map[int]bool,
+	writer *bufio.Writer) {
+	keys := []int{}
+	for k := range next {
+		if !base[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Ints(keys)
+	fmt.Fprintf(writer, "%s (%d): ", title, len(keys))
+	for _, n := range keys {
+		fmt.Fprintf(writer, "%d ", n)
+	}
+	fmt.Fprintf(writer, "\n")
+}
+
+// Output count changes from base to next for all keys in base and next
+func countChangesOutput(title string, keys []string, base, next map[string]int,
+	writer *bufio.Writer) {
+	fmt.Fprintf(writer, "%s:\n", title)
+	for _, k := range keys {
+		b := base[k]
+		n := next[k]
+		if b == 0 && n == 0 {
+			continue
+		}
+		fmt.Fprintf(writer, "  %s %d -> %d (%+d)\n", k, b, n, n-b)
+	}
+}
+
+// Output the comparison result from base to next
+func compareOutput(base, next statMaps, writer *bufio.Writer) {
+	nbase := statMapsQSOs(base)
+	nnext := statMapsQSOs(next)
+	fmt.Fprintf(writer, "QSOs: %d -> %d (%+d)\n", nbase, nnext, nnext-nbase)
+
+	// DXCC entities with the names, one per line
+	entities := []int{}
+	for k := range next.dxcc {
+		if _, exists := base.dxcc[k]; !exists {
+			entities = append(entities, k)
+		}
+	}
+	sort.Ints(entities)
+	fmt.Fprintf(writer, "New DXCC (%d):\n", len(entities))
+	for _, code := range entities {
+		fmt.Fprintf(writer, "  %s\n", dxcc.Describe(code))
+	}
+	newIntKeysOutput("New CQZ", base.cqz, next.cqz, writer)
+	newIntKeysOutput("New ITUZ", base.ituz, next.ituz, writer)
+
+	grids := []string{}
+	for k := range next.grid {
+		if !base.grid[k] {
+			grids = append(grids, k)
+		}
+	}
+	sort.Strings(grids)
+	fmt.Fprintf(writer, "New gridsquare (%d): ", len(grids))
+	for _, g := range grids {
+		fmt.Fprintf(writer, "%s ", g)
+	}
+	fmt.Fprintf(writer, "\n")
+
+	// Bands in the frequency order
+	countChangesOutput("Bands", adifband.Bands, base.band, next.band, writer)
+
+	modeset := make(map[string]bool)
+	for k := range base.mode {
+		modeset[k] = true
+	}
+	for k := range next.mode {
+		modeset[k] = true
+	}
+	modes := make([]string, 0, len(modeset))
+	for k := range modeset {
+		modes = append(modes, k)
+	}
+	sort.Strings(modes)
+	countChangesOutput("Modes", modes, base.mode, next.mode, writer)
+}
+
+// Read all records from the reader, and update the statistics maps
+// of the periods whose time windows contain the record time
+// Records without valid time are counted only in the periods
+// without time windows
+func updatePeriodStatMaps(reader adifparser.ADIFReader,
+	periods []statMaps, windows []timeWindow) {
+	limited := false
+	for _, w := range windows {
+		limited = limited || w.limited()
+	}
+	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprint(os.Stderr, err)
+			}
+			break // when io.EOF break the loop!
+		}
+		var recordtime time.Time
+		var timeerr error
+		if limited {
+			recordtime, timeerr = adiftime.RecordTime(record)
+			if timeerr != nil {
+				fmt.Fprintln(os.Stderr, timeerr)
+			}
+		}
+		for i := range periods {
+			if !windows[i].limited() ||
+				(timeerr == nil && windows[i].contains(recordtime)) {
+				periods[i].update(record)
+			}
+		}
+	}
+}
+
 func main() {
 	var infile = flag.String("f", "", "input file (stdin if none)")
 	var outfile = flag.String("o", "", "output file (stdout if none)")
 	var query = flag.String("q", "", "query type")
+	var sortorder = flag.String("s", "code", "sort order for dxcc")
+	var infile2 = flag.String("f2", "", "second input file for compare")
+	var start1 = flag.String("start1", "", "start time expression of the first period")
+	var end1 = flag.String("end1", "", "end time expression of the first period")
+	var start2 = flag.String("start2", "", "start time expression of the second period")
+	var end2 = flag.String("end2", "", "end time expression of the second period")
 	var fp *os.File
 	var err error
 
@@ -254,14 +708,49 @@ func main() {
 		fmt.Fprintln(flag.CommandLine.Output(),
 			"goadifstat: check statistics of ADIF ADI files")
 		fmt.Fprintf(flag.CommandLine.Output(),
-			"Usage: %s [-f infile] [-o outfile] -q query type\n", execname)
+			"Usage: %s [-f infile] [-o outfile] [-s sort order] -q query type\n", execname)
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"       %s [-f infile] [-f2 infile2] [-o outfile]\n"+
+				"       [-start1 time-expr] [-end1 time-expr]\n"+
+				"       [-start2 time-expr] [-end2 time-expr] -q compare\n", execname)
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"Valid query types: bands, compare, cont, country, cqz, dxcc,\n"+
+				"                   gridsquare, ituz, modes, nqso, operator, station,\n"+
+				"                   submodes, wpx")
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"dxcc: entity code, main prefix, continent, QSO count, and name\n"+
+				"      resolved with cty.xml of Club Log through gocldb")
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"Valid sort orders for dxcc: code (default), name, prefix, count")
 		fmt.Fprintln(flag.CommandLine.Output(),
-			"Valid query types: bands, country, cqz, dxcc, gridsquare, modes, nqso, submodes")
+			"wpx: WPX prefixes derived from call with the CQ WPX Contest rules")
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"operator, station: QSO count, operating hours, DXCC entities,\n"+
+				"                   bands and modes per operator or station_callsign")
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"compare: new DXCC entities, CQ/ITU zones, grid squares, and\n"+
+				"         QSO count changes per band and mode\n"+
+				"         from the first period to the second period\n"+
+				"  With -f2: first period from infile, second period from infile2\n"+
+				"  Without -f2: both periods from infile\n"+
+				"  Each period is filtered by -start1/-end1 and -start2/-end2\n"+
+				"  Time expressions in UTC are the same as goadiftime, e.g.,\n"+
+				"  2022-10-11T12:33:45Z, 2022-10-11, last month, cqww-cw 2022\n"+
+				"  -start1/-start2 use the start of the time expression\n"+
+				"  -end1/-end2 use the end of the time expression")
 		flag.PrintDefaults()
 	}
 
 	flag.Parse()
 
+	switch *sortorder {
+	case "code", "name", "prefix", "count":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown sort order %s\n", *sortorder)
+		flag.Usage()
+		return
+	}
+
 	if *infile == "" {
 		fp = os.Stdin
 	} else {
@@ -292,7 +781,58 @@ func main() {
 		writer = bufio.NewWriter(os.Stdout)
 	}
 
-	initStatMaps()
+	if *query == "dxcc" || *query == "compare" {
+		if err := dxcc.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v: DXCC entity names are not shown\n", err)
+		}
+	}
+
+	if *query == "compare" {
+		now := time.Now()
+		window1, err := parseTimeWindow(*start1, *end1, now)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+			return
+		}
+		window2, err := parseTimeWindow(*start2, *end2, now)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+			return
+		}
+		base := newStatMaps()
+		next := newStatMaps()
+
+		if *infile2 != "" {
+			fp2, err := os.Open(*infile2)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				return
+			}
+			updatePeriodStatMaps(adifparser.NewADIFReader(fp),
+				[]statMaps{base}, []timeWindow{window1})
+			updatePeriodStatMaps(adifparser.NewADIFReader(fp2),
+				[]statMaps{next}, []timeWindow{window2})
+			fp2.Close()
+		} else {
+			if !window1.limited() && !window2.limited() {
+				fmt.Fprint(os.Stderr,
+					"Error: compare requires -f2 or time periods\n")
+				return
+			}
+			updatePeriodStatMaps(adifparser.NewADIFReader(fp),
+				[]statMaps{base, next}, []timeWindow{window1, window2})
+		}
+		compareOutput(base, next, writer)
+
+		// Flush and close output here
+		writer.Flush()
+		if writefp != nil {
+			writefp.Close()
+		}
+		return
+	}
+
+	stat := newStatMaps()
 
 	reader := adifparser.NewADIFReader(fp)
 	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
@@ -302,10 +842,10 @@ func main() {
 			}
 			break // when io.EOF break the loop!
 		}
-		updateStatMaps(record)
+		stat.update(record)
 	}
 
-	statOutput(query, writer, reader)
+	statOutput(stat, query, sortorder, writer, reader)
 
 	// Flush and close output here
 	writer.Flush()
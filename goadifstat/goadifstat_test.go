@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/dxcc"
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+	"github.com/jj1bdx/gocldb"
+)
+
+func TestResolveDxccEntities(t *testing.T) {
+	gocldb.CLDMapEntityByAdif[339] = gocldb.CLDEntityByAdif{
+		Name: "JAPAN", Prefix: "JA", Cont: "AS"}
+	gocldb.CLDMapEntityByAdif[81] = gocldb.CLDEntityByAdif{
+		Name: "GERMANY", Prefix: "DL", Cont: "EU", Deleted: true}
+	entities := resolveDxccEntities(map[int]int{339: 10, 81: 2, 0: 1, 999: 3})
+	sortDxccEntities(entities, "code")
+	want := []dxccEntity{
+		{dxcc.Entity{Code: 0, Prefix: "?", Name: "(UNKNOWN)", Cont: "?"}, 1},
+		{dxcc.Entity{Code: 81, Prefix: "DL", Name: "GERMANY", Cont: "EU", Deleted: true}, 2},
+		{dxcc.Entity{Code: 339, Prefix: "JA", Name: "JAPAN", Cont: "AS"}, 10},
+		{dxcc.Entity{Code: 999, Prefix: "?", Name: "(UNKNOWN)", Cont: "?"}, 3},
+	}
+	if !reflect.DeepEqual(entities, want) {
+		t.Errorf("resolveDxccEntities() = %+v, want %+v", entities, want)
+	}
+}
+
+func TestSortDxccEntities(t *testing.T) {
+	entities := []dxccEntity{
+		{dxcc.Entity{Code: 339, Prefix: "JA", Name: "JAPAN"}, 10},
+		{dxcc.Entity{Code: 291, Prefix: "K", Name: "UNITED STATES OF AMERICA"}, 10},
+		{dxcc.Entity{Code: 1, Prefix: "VE", Name: "CANADA"}, 5},
+		{dxcc.Entity{Code: 110, Prefix: "KH6", Name: "HAWAII"}, 20},
+	}
+	tests := []struct {
+		order string
+		codes []int
+	}{
+		{"code", []int{1, 110, 291, 339}},
+		{"name", []int{1, 110, 339, 291}},
+		{"prefix", []int{339, 291, 110, 1}},
+		// Ties sorted by the code
+		{"count", []int{110, 291, 339, 1}},
+	}
+	for _, tt := range tests {
+		sortDxccEntities(entities, tt.order)
+		codes := []int{}
+		for _, e := range entities {
+			codes = append(codes, e.Code)
+		}
+		if !reflect.DeepEqual(codes, tt.codes) {
+			t.Errorf("sortDxccEntities(%q) = %v, want %v", tt.order, codes, tt.codes)
+		}
+	}
+}
+
+func TestParseTimeWindow(t *testing.T) {
+	now := time.Date(2023, 12, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		start, end string
+		want       timeWindow
+	}{
+		{"", "", timeWindow{}},
+		{"2022-10-11T12:33:45Z", "", timeWindow{
+			start: time.Date(2022, 10, 11, 12, 33, 45, 0, time.UTC)}},
+		{"2023-01-01", "2023-01-31", timeWindow{
+			start: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(2023, 1, 31, 23, 59, 59, 0, time.UTC)}},
+		{"last month", "last month", timeWindow{
+			start: time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(2023, 11, 30, 23, 59, 59, 0, time.UTC)}},
+		{"cqww-cw 2022", "cqww-cw 2022", timeWindow{
+			start: time.Date(2022, 11, 26, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(2022, 11, 27, 23, 59, 59, 0, time.UTC)}},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeWindow(tt.start, tt.end, now)
+		if err != nil {
+			t.Errorf("parseTimeWindow(%q, %q): %v", tt.start, tt.end, err)
+			continue
+		}
+		if !got.start.Equal(tt.want.start) || !got.end.Equal(tt.want.end) {
+			t.Errorf("parseTimeWindow(%q, %q) = %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+	}
+	for _, tt := range [][2]string{{"2023-02-01", "2023-01-01"}, {"someday", ""}, {"", "2023-13-01"}} {
+		if _, err := parseTimeWindow(tt[0], tt[1], now); err == nil {
+			t.Errorf("parseTimeWindow(%q, %q): no error", tt[0], tt[1])
+		}
+	}
+}
+
+func TestUpdatePeriodStatMaps(t *testing.T) {
+	input := "<call:5>A1AAA<qso_date:8>20220101<time_on:4>0000<dxcc:3>339<band:3>20m<eor>\n" +
+		"<call:5>A1AAB<qso_date:8>20230101<time_on:4>0000<dxcc:3>291<band:3>40m<eor>\n" +
+		// No valid time: counted only in the unlimited period
+		"<call:5>A1AAC<qso_date:8>20230101<time_on:2>00<dxcc:1>1<band:3>40m<eor>\n"
+	window1, _ := parseTimeWindow("2022-01-01", "2022-12-31", time.Now())
+	window2, _ := parseTimeWindow("2023-01-01", "", time.Now())
+	periods := []statMaps{newStatMaps(), newStatMaps(), newStatMaps()}
+	updatePeriodStatMaps(adifparser.NewADIFReader(strings.NewReader(input)),
+		periods, []timeWindow{window1, {}, window2})
+	want := []map[int]int{{339: 1}, {339: 1, 291: 1, 1: 1}, {291: 1}}
+	for i := range periods {
+		if !reflect.DeepEqual(periods[i].dxcc, want[i]) {
+			t.Errorf("period %d: dxcc = %v, want %v", i, periods[i].dxcc, want[i])
+		}
+	}
+}
+
+func TestCompareOutput(t *testing.T) {
+	gocldb.CLDMapEntityByAdif[291] = gocldb.CLDEntityByAdif{
+		Name: "UNITED STATES OF AMERICA", Prefix: "K", Cont: "NA"}
+	defer delete(gocldb.CLDMapEntityByAdif, 291)
+	base := newStatMaps()
+	next := newStatMaps()
+	for _, r := range testutil.ReadRecords(t,
+		"<dxcc:3>339<cqz:2>25<ituz:2>45<gridsquare:6>PM95vq<band:3>20m<mode:2>CW<eor>\n"+
+			"<dxcc:3>339<cqz:2>25<ituz:2>45<band:3>40m<mode:2>CW<eor>\n") {
+		base.update(r)
+	}
+	for _, r := range testutil.ReadRecords(t,
+		"<dxcc:3>339<cqz:2>25<ituz:2>45<gridsquare:4>PM96<band:3>20m<mode:3>FT8<eor>\n"+
+			"<dxcc:3>291<cqz:1>5<ituz:1>8<gridsquare:4>FN31<band:3>20m<mode:3>FT8<eor>\n"+
+			"<dxcc:3>110<cqz:2>31<ituz:2>61<band:2>8m<mode:3>FT8<eor>\n") {
+		next.update(r)
+	}
+	var b strings.Builder
+	writer := bufio.NewWriter(&b)
+	compareOutput(base, next, writer)
+	writer.Flush()
+	want := "QSOs: 2 -> 3 (+1)\n" +
+		"New DXCC (2):\n" +
+		"  110\n" +
+		"  291 K UNITED STATES OF AMERICA\n" +
+		"New CQZ (2): 5 31 \n" +
+		"New ITUZ (2): 8 61 \n" +
+		"New gridsquare (2): FN31 PM96 \n" +
+		"Bands:\n" +
+		"  40m 1 -> 0 (-1)\n" +
+		"  20m 1 -> 2 (+1)\n" +
+		"  8m 0 -> 1 (+1)\n" +
+		"Modes:\n" +
+		"  CW 2 -> 0 (-2)\n" +
+		"  FT8 0 -> 3 (+3)\n"
+	if b.String() != want {
+		t.Errorf("compareOutput() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestUpdateZonesContWpx(t *testing.T) {
+	m := newStatMaps()
+	for _, r := range testutil.ReadRecords(t,
+		"<call:6>JJ1BDX<cqz:2>25<ituz:2>45<cont:2>as<eor>\n"+
+			"<call:8>JA1ABC/3<cqz:2>25<ituz:2>45<cont:2>AS<eor>\n"+
+			"<call:5>K1ABC<cqz:1>5<ituz:1>8<cont:2>NA<eor>\n"+
+			"<call:0><cqz:0><ituz:0><eor>\n") {
+		m.update(r)
+	}
+	if want := map[int]bool{5: true, 25: true}; !reflect.DeepEqual(m.cqz, want) {
+		t.Errorf("cqz = %v, want %v", m.cqz, want)
+	}
+	if want := map[int]bool{8: true, 45: true}; !reflect.DeepEqual(m.ituz, want) {
+		t.Errorf("ituz = %v, want %v", m.ituz, want)
+	}
+	if want := map[string]int{"AS": 2, "NA": 1}; !reflect.DeepEqual(m.cont, want) {
+		t.Errorf("cont = %v, want %v", m.cont, want)
+	}
+	if want := map[string]bool{"JJ1": true, "JA3": true, "K1": true}; !reflect.DeepEqual(m.wpx, want) {
+		t.Errorf("wpx = %v, want %v", m.wpx, want)
+	}
+}
+
+func TestGroupStat(t *testing.T) {
+	m := newStatMaps()
+	for _, r := range testutil.ReadRecords(t,
+		"<operator:6>jj1bdx<station_callsign:6>JA1ZZZ<qso_date:8>20231125<time_on:4>0830<band:3>20m<mode:2>cw<dxcc:3>339<eor>\n"+
+			"<operator:6>JJ1BDX<station_callsign:6>JA1ZZZ<qso_date:8>20231125<time_on:4>0859<band:3>40m<mode:2>CW<dxcc:3>291<eor>\n"+
+			"<operator:6>JJ1BDX<station_callsign:6>JA1ZZZ<qso_date:8>20231125<time_on:4>0900<band:3>20m<mode:3>FT8<dxcc:3>339<eor>\n"+
+			"<station_callsign:6>JA1ZZZ<qso_date:8>20231126<time_on:4>0100<band:3>20m<mode:3>FT8<eor>\n") {
+		m.update(r)
+	}
+	op := m.operator["JJ1BDX"]
+	if op == nil || op.nqso != 3 || len(op.hours) != 2 || len(op.dxcc) != 2 {
+		t.Fatalf("operator JJ1BDX = %+v", op)
+	}
+	if want := map[string]int{"20m": 2, "40m": 1}; !reflect.DeepEqual(op.bands, want) {
+		t.Errorf("operator bands = %v, want %v", op.bands, want)
+	}
+	if want := map[string]int{"CW": 2, "FT8": 1}; !reflect.DeepEqual(op.modes, want) {
+		t.Errorf("operator modes = %v, want %v", op.modes, want)
+	}
+	if unknown := m.operator["(UNKNOWN)"]; unknown == nil || unknown.nqso != 1 {
+		t.Errorf("operator (UNKNOWN) = %+v", unknown)
+	}
+	if st := m.station["JA1ZZZ"]; st == nil || st.nqso != 4 || len(st.hours) != 3 {
+		t.Errorf("station JA1ZZZ = %+v", st)
+	}
+
+	var b strings.Builder
+	writer := bufio.NewWriter(&b)
+	groupStatOutput(m.operator, writer)
+	writer.Flush()
+	want := "(UNKNOWN): qsos 1 hours 1 entities 0\n" +
+		"  bands: 20m 1 \n" +
+		"  modes: FT8 1 \n" +
+		"JJ1BDX: qsos 3 hours 2 entities 2\n" +
+		"  bands: 40m 1 20m 2 \n" +
+		"  modes: CW 2 FT8 1 \n"
+	if b.String() != want {
+		t.Errorf("groupStatOutput() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,174 @@
+// goadifstation: fill in station fields from station profiles
+// by Kenji Rikitake, JJ1BDX
+// Usage: goadifstation [-f infile] [-o outfile] [-w] -c config -p profile
+//        goadifstation [-f infile] [-o outfile] [-w] -c config -a
+// -nh: do not pass through the input ADIF header
+// -c: station profile file in JSON (see profile.go for the format)
+// -p: apply the named profile to all records
+// -a: apply the first profile matching each record
+//     by station_callsign and qso_date
+//     Number of records without matching profile is reported to stderr
+// -w: overwrite existing fields
+// Only empty fields are filled in unless -w is specified
+// station_callsign of the profile is also filled in
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifio"
+)
+
+func main() {
+	var infile = flag.String("f", "", "input file (stdout in none)")
+	var outfile = flag.String("o", "", "output file (stdout if none)")
+	var noheader = flag.Bool("nh", false, "do not pass through the input header")
+	var configfile = flag.String("c", "", "station profile file in JSON")
+	var profilename = flag.String("p", "", "name of the profile to apply to all records")
+	var automatch = flag.Bool("a", false, "apply the first profile matching each record")
+	var overwrite = flag.Bool("w", false, "overwrite existing fields")
+
+	var fp *os.File
+	var err error
+
+	flag.Usage = func() {
+		execname := os.Args[0]
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"goadifstation: fill in station fields from station profiles")
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [-f infile] [-o outfile] [-w] -c config -p profile\n", execname)
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"       %s [-f infile] [-o outfile] [-w] -c config -a\n", execname)
+		flag.PrintDefaults()
+		details :=
+			"Station profile file example:\n" +
+				"  [{\"name\": \"home\", \"station_callsign\": \"JJ1BDX\",\n" +
+				"    \"start\": \"2020-01-01\", \"end\": \"2023-12-31\",\n" +
+				"    \"fields\": {\"my_gridsquare\": \"PM95vq\", \"my_cq_zone\": \"25\",\n" +
+				"               \"my_itu_zone\": \"45\", \"my_dxcc\": \"339\",\n" +
+				"               \"my_rig\": \"IC-7300\", \"tx_pwr\": \"50\"}}]\n" +
+				"  station_callsign, start, and end are optional\n" +
+				"  fields: standard MY_* fields, station_callsign, operator,\n" +
+				"          owner_callsign, and tx_pwr (case insensitive)\n" +
+				"-a: a profile matches a record when station_callsign of the profile\n" +
+				"    is empty or equal to station_callsign of the record,\n" +
+				"    and qso_date of the record is in the date range of the profile\n" +
+				"Only empty fields are filled in unless -w is specified\n" +
+				"station_callsign of the profile is also filled in\n"
+		fmt.Fprint(flag.CommandLine.Output(), details)
+	}
+
+	flag.Parse()
+
+	if *configfile == "" || (*profilename == "") == !*automatch ||
+		len(flag.Args()) != 0 {
+		fmt.Fprint(os.Stderr, "Error: incorrect arguments\n")
+		flag.Usage()
+		return
+	}
+
+	profiles, err := readProfiles(*configfile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	var profile *stationProfile
+	if *profilename != "" {
+		profile = findProfile(profiles, *profilename)
+		if profile == nil {
+			fmt.Fprintf(os.Stderr, "Error: no such profile %s\n", *profilename)
+			return
+		}
+	}
+
+	if *infile == "" {
+		fp = os.Stdin
+	} else {
+		fp, err = os.Open(*infile)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+			return
+		}
+	}
+
+	header, input, err := adifio.ReadHeader(fp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if *noheader {
+		header = nil
+	}
+
+	var writer adifparser.ADIFWriter
+	var writefp *os.File
+	if *outfile != "" {
+		if _, err := os.Stat(*outfile); os.IsNotExist(err) {
+			// File does not exist: create it
+			writefp, err = os.Create(*outfile)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				return
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: file %s already exists\n", *outfile)
+			return
+		}
+		writer = adifio.NewWriter(writefp, header, "goadifstation")
+	} else {
+		writefp = nil
+		writer = adifio.NewWriter(os.Stdout, header, "goadifstation")
+	}
+
+	if err := writer.SetComment("goadifstation\n"); err != nil {
+		fmt.Fprint(os.Stderr, err)
+		return
+	}
+
+	unmatched := 0
+
+	reader := adifparser.NewADIFReader(input)
+	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprint(os.Stderr, err)
+			}
+			break // when io.EOF break the loop!
+		}
+
+		if *automatch {
+			profile = nil
+			for _, p := range profiles {
+				if p.matches(record) {
+					profile = p
+					break
+				}
+			}
+		}
+		if profile != nil {
+			profile.apply(record, *overwrite)
+		} else {
+			unmatched++
+		}
+
+		if err := writer.WriteRecord(record); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	if unmatched > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d records without matching profile\n", unmatched)
+	}
+
+	// Flush and close output here
+	writer.Flush()
+	if writefp != os.Stdout {
+		writefp.Close()
+	}
+
+}
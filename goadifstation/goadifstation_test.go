@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+const testMainEnv = "GOADIFSTATION_TEST_MAIN"
+
+// Run main() in a child process of the test binary
+func TestMain(m *testing.M) {
+	testutil.Main(m, testMainEnv, main)
+}
+
+// Run goadifstation with the input and the arguments,
+// and return the values of the field of the output records and stderr
+func runStation(t *testing.T, input, field string, args ...string) ([]string, string) {
+	t.Helper()
+	stdout, stderr := testutil.Run(t, testMainEnv, input, args...)
+	return testutil.FieldValues(testutil.ReadRecords(t, stdout), field), stderr
+}
+
+func TestStation(t *testing.T) {
+	config := writeProfiles(t, testProfiles)
+	input := "<call:4>A1AA<station_callsign:6>JJ1BDX<qso_date:8>20210101<eor>\n" +
+		"<call:4>A1AB<station_callsign:8>JJ1BDX/1<qso_date:8>20210101<tx_pwr:2>10<eor>\n" +
+		"<call:4>A1AC<station_callsign:6>JJ1BDX<qso_date:8>20191231<eor>\n"
+
+	values, stderr := runStation(t, input, "tx_pwr", "-c", config, "-a")
+	if want := []string{"50", "10", ""}; !reflect.DeepEqual(values, want) {
+		t.Errorf("-a: tx_pwr = %v, want %v", values, want)
+	}
+	if stderr != "Warning: 1 records without matching profile\n" {
+		t.Errorf("-a: stderr = %q", stderr)
+	}
+
+	values, _ = runStation(t, input, "tx_pwr", "-c", config, "-a", "-w")
+	if want := []string{"50", "5", ""}; !reflect.DeepEqual(values, want) {
+		t.Errorf("-a -w: tx_pwr = %v, want %v", values, want)
+	}
+
+	values, stderr = runStation(t, input, "my_gridsquare", "-c", config, "-p", "portable")
+	if want := []string{"PM96", "PM96", "PM96"}; !reflect.DeepEqual(values, want) || stderr != "" {
+		t.Errorf("-p: my_gridsquare = %v, stderr %q, want %v", values, stderr, want)
+	}
+
+	values, stderr = runStation(t, input, "call", "-c", config, "-p", "none")
+	if len(values) != 0 || stderr != "Error: no such profile none\n" {
+		t.Errorf("-p none: %v, stderr %q", values, stderr)
+	}
+}
@@ -0,0 +1,200 @@
+// goadifstation: station profiles
+// by Kenji Rikitake, JJ1BDX
+//
+// Station profiles are read from a JSON file of an array of profiles.
+// Each profile has the following members:
+//
+//	name: profile name (required, unique)
+//	station_callsign: station callsign (optional)
+//	start, end: date range of the profile in YYYY-MM-DD or YYYYMMDD
+//	  (optional, both ends included)
+//	fields: station field names and values to fill in
+//	  (field names are case insensitive)
+//
+// Station fields are the standard MY_* fields,
+// station_callsign, operator, owner_callsign, and tx_pwr.
+//
+// Example:
+//
+//	[
+//	  {
+//	    "name": "home",
+//	    "station_callsign": "JJ1BDX",
+//	    "start": "2020-01-01",
+//	    "fields": {
+//	      "my_gridsquare": "PM95vq",
+//	      "my_cq_zone": "25",
+//	      "my_itu_zone": "45",
+//	      "my_dxcc": "339",
+//	      "my_rig": "IC-7300",
+//	      "my_antenna": "Dipole",
+//	      "tx_pwr": "50"
+//	    }
+//	  }
+//	]
+//
+// A profile matches a record when station_callsign of the profile
+// is empty or equal to station_callsign of the record (case insensitive),
+// and qso_date of the record is in the date range of the profile.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifio"
+)
+
+var ErrInvalidProfile = errors.New("invalid station profile")
+
+// Station fields other than the MY_* fields
+var stationFields = map[string]bool{
+	"operator":         true,
+	"owner_callsign":   true,
+	"station_callsign": true,
+	"tx_pwr":           true,
+}
+
+// Station profile
+type stationProfile struct {
+	Name            string            `json:"name"`
+	StationCallsign string            `json:"station_callsign"`
+	Start           string            `json:"start"`
+	End             string            `json:"end"`
+	Fields          map[string]string `json:"fields"`
+	// Date range in YYYYMMDD, empty if not specified
+	startDate string
+	endDate   string
+}
+
+// Convert a date in YYYY-MM-DD or YYYYMMDD to YYYYMMDD
+func profileDate(date string) (string, error) {
+	if date == "" {
+		return "", nil
+	}
+	date = strings.ReplaceAll(date, "-", "")
+	if _, err := time.Parse("20060102", date); err != nil {
+		return "", err
+	}
+	return date, nil
+}
+
+// Check if the field is a station field
+func isStationField(field string) bool {
+	if strings.HasPrefix(field, "my_") {
+		return adifio.IsStandardField(field)
+	}
+	return stationFields[field]
+}
+
+// Read the station profiles from a JSON file
+func readProfiles(filename string) ([]*stationProfile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var profiles []*stationProfile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for i, profile := range profiles {
+		if profile == nil {
+			return nil, fmt.Errorf("%w: profile %d is null", ErrInvalidProfile, i+1)
+		}
+		if profile.Name == "" {
+			return nil, fmt.Errorf("%w: profile %d has no name", ErrInvalidProfile, i+1)
+		}
+		if names[profile.Name] {
+			return nil, fmt.Errorf("%w: duplicate name %s", ErrInvalidProfile, profile.Name)
+		}
+		names[profile.Name] = true
+		if profile.startDate, err = profileDate(profile.Start); err != nil {
+			return nil, fmt.Errorf("%w: %s: start: %v", ErrInvalidProfile, profile.Name, err)
+		}
+		if profile.endDate, err = profileDate(profile.End); err != nil {
+			return nil, fmt.Errorf("%w: %s: end: %v", ErrInvalidProfile, profile.Name, err)
+		}
+		// Use lowercase field names
+		fields := make(map[string]string)
+		for field, value := range profile.Fields {
+			field = strings.ToLower(field)
+			if !isStationField(field) {
+				return nil, fmt.Errorf("%w: %s: %s is not a station field",
+					ErrInvalidProfile, profile.Name, field)
+			}
+			fields[field] = value
+		}
+		if profile.StationCallsign != "" {
+			fields["station_callsign"] = profile.StationCallsign
+		}
+		profile.Fields = fields
+	}
+	return profiles, nil
+}
+
+// Find a profile by the name
+func findProfile(profiles []*stationProfile, name string) *stationProfile {
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return profile
+		}
+	}
+	return nil
+}
+
+// Check if the profile matches the record
+func (p *stationProfile) matches(record adifparser.ADIFRecord) bool {
+	if p.StationCallsign != "" {
+		call, _ := record.GetValue("station_callsign")
+		if !strings.EqualFold(strings.TrimSpace(call), p.StationCallsign) {
+			return false
+		}
+	}
+	if p.startDate != "" || p.endDate != "" {
+		// YYYYMMDD can be compared as strings
+		date, _ := record.GetValue("qso_date")
+		if len(date) != 8 {
+			return false
+		}
+		if p.startDate != "" && date < p.startDate {
+			return false
+		}
+		if p.endDate != "" && date > p.endDate {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply the profile to the record
+// Only empty fields are filled in unless overwrite is true
+// Returns the number of fields changed
+func (p *stationProfile) apply(record adifparser.ADIFRecord, overwrite bool) int {
+	changed := 0
+	// Apply in the sorted field name order for the stable output
+	fields := make([]string, 0, len(p.Fields))
+	for field := range p.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		value := p.Fields[field]
+		current, _ := record.GetValue(field)
+		if current != "" && !overwrite {
+			continue
+		}
+		if current != value {
+			record.SetValue(field, value)
+			changed++
+		}
+	}
+	return changed
+}
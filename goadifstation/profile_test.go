@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+// Write the profile file and return the file name
+func writeProfiles(t *testing.T, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "profiles.json")
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+const testProfiles = `[
+  {
+    "name": "home",
+    "station_callsign": "JJ1BDX",
+    "start": "2020-01-01",
+    "end": "20221231",
+    "fields": {"MY_GRIDSQUARE": "PM95vq", "my_dxcc": "339", "tx_pwr": "50"}
+  },
+  {
+    "name": "portable",
+    "station_callsign": "jj1bdx/1",
+    "fields": {"my_gridsquare": "PM96", "tx_pwr": "5"}
+  },
+  {
+    "name": "any",
+    "start": "2023-01-01",
+    "fields": {"my_rig": "IC-705"}
+  }
+]`
+
+func TestReadProfiles(t *testing.T) {
+	profiles, err := readProfiles(writeProfiles(t, testProfiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 3 {
+		t.Fatalf("%d profiles", len(profiles))
+	}
+	home := findProfile(profiles, "home")
+	if home == nil || home.startDate != "20200101" || home.endDate != "20221231" {
+		t.Fatalf("home = %+v", home)
+	}
+	if home.Fields["my_gridsquare"] != "PM95vq" || home.Fields["station_callsign"] != "JJ1BDX" {
+		t.Errorf("home fields = %v", home.Fields)
+	}
+	if findProfile(profiles, "none") != nil {
+		t.Errorf("findProfile(none) found a profile")
+	}
+
+	for _, content := range []string{
+		`[{"fields": {}}]`,
+		`[{"name": "a"}, {"name": "a"}]`,
+		`[{"name": "a", "start": "2023-13-01"}]`,
+		`[{"name": "a", "end": "tomorrow"}]`,
+		`[{"name": "a"}, null]`,
+		`[null]`,
+		`[{"name": "a", "fields": {"call": "A1AA"}}]`,
+		`[{"name": "a", "fields": {"MY_GRID": "PM95"}}]`,
+		`[{"name": "a", "fields": {"my gridsquare": "PM95"}}]`,
+		`[{"name": "a", "fields": {"app_x_rig": "IC-705"}}]`,
+	} {
+		if _, err := readProfiles(writeProfiles(t, content)); !errors.Is(err, ErrInvalidProfile) {
+			t.Errorf("readProfiles(%s) error = %v, want %v", content, err, ErrInvalidProfile)
+		}
+	}
+	if _, err := readProfiles(writeProfiles(t, `{"name": "a"}`)); err == nil {
+		t.Errorf("readProfiles(not an array): no error")
+	}
+	if _, err := readProfiles(filepath.Join(t.TempDir(), "none.json")); err == nil {
+		t.Errorf("readProfiles(no file): no error")
+	}
+}
+
+func TestIsStationField(t *testing.T) {
+	for _, field := range []string{"my_gridsquare", "my_rig", "my_pota_ref",
+		"station_callsign", "operator", "owner_callsign", "tx_pwr"} {
+		if !isStationField(field) {
+			t.Errorf("isStationField(%q) = false", field)
+		}
+	}
+	for _, field := range []string{"call", "gridsquare", "my_grid", "my_", "rx_pwr", ""} {
+		if isStationField(field) {
+			t.Errorf("isStationField(%q) = true", field)
+		}
+	}
+}
+
+func TestProfileMatches(t *testing.T) {
+	profiles, err := readProfiles(writeProfiles(t, testProfiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		record  string
+		profile string
+	}{
+		{"<station_callsign:6>jj1bdx<qso_date:8>20200101<eor>", "home"},
+		{"<station_callsign:6>JJ1BDX<qso_date:8>20221231<eor>", "home"},
+		{"<station_callsign:6>JJ1BDX<qso_date:8>20191231<eor>", ""},
+		{"<station_callsign:6>JJ1BDX<qso_date:8>20230101<eor>", "any"},
+		{"<station_callsign:6>JJ1BDX<eor>", ""},
+		{"<station_callsign:8>JJ1BDX/1<qso_date:8>20191231<eor>", "portable"},
+		{"<station_callsign:8>JJ1BDX/1<eor>", "portable"},
+		{"<station_callsign:6>JA1ABC<qso_date:8>20230101<eor>", "any"},
+		{"<qso_date:8>20221231<eor>", ""},
+	}
+	for _, tt := range tests {
+		record := testutil.ParseRecord(t, tt.record)
+		matched := ""
+		for _, profile := range profiles {
+			if profile.matches(record) {
+				matched = profile.Name
+				break
+			}
+		}
+		if matched != tt.profile {
+			t.Errorf("%s matches %q, want %q", tt.record, matched, tt.profile)
+		}
+	}
+}
+
+func TestProfileApply(t *testing.T) {
+	profiles, err := readProfiles(writeProfiles(t, testProfiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	home := findProfile(profiles, "home")
+
+	record := testutil.ParseRecord(t, "<call:4>A1AA<tx_pwr:3>100<my_dxcc:0><eor>")
+	if changed := home.apply(record, false); changed != 3 {
+		t.Errorf("apply: %d fields changed, want 3", changed)
+	}
+	for field, want := range map[string]string{"tx_pwr": "100", "my_dxcc": "339",
+		"my_gridsquare": "PM95vq", "station_callsign": "JJ1BDX", "call": "A1AA"} {
+		if value, _ := record.GetValue(field); value != want {
+			t.Errorf("apply: %s = %q, want %q", field, value, want)
+		}
+	}
+	// Applying again changes nothing
+	if changed := home.apply(record, false); changed != 0 {
+		t.Errorf("apply again: %d fields changed", changed)
+	}
+	// Overwrite
+	if changed := home.apply(record, true); changed != 1 {
+		t.Errorf("apply with overwrite: %d fields changed, want 1", changed)
+	}
+	if value, _ := record.GetValue("tx_pwr"); value != "50" {
+		t.Errorf("apply with overwrite: tx_pwr = %q", value)
+	}
+}
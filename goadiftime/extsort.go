@@ -0,0 +1,296 @@
+// goadiftime: external merge sort of ADIF records by time
+// by Kenji Rikitake, JJ1BDX
+//
+// Records are kept in memory until the estimated size exceeds
+// the memory budget, then sorted and spilled to a temporary file
+// as a sorted run. The runs are merged with a k-way merge
+// of at most mergeFanIn runs at a time to bound the number of open files:
+// if there are more runs, groups of the runs are merged into longer runs
+// until the remaining runs can be merged at once.
+// Sorting is stable: records with the same time keep the input order,
+// so the output is identical with and without the memory budget.
+// The record size is estimated from the length of the ADIF string.
+
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adiftime"
+)
+
+// Estimated memory overhead per record in bytes
+const recordOverhead = 256
+
+// Maximum number of runs merged at a time
+const mergeFanIn = 64
+
+// Compare record times for sorting
+func recordLess(a, b recordWithTime, reverse bool) bool {
+	if reverse {
+		return a.date.After(b.date)
+	}
+	return a.date.Before(b.date)
+}
+
+// Sorted run in a temporary file
+type sortRun struct {
+	fp      *os.File
+	reader  adifparser.ADIFReader
+	current recordWithTime
+	// Order of the run for stable merging
+	index int
+}
+
+// Read the next record of the run
+// Returns io.EOF at the end of the run
+func (r *sortRun) next() error {
+	record, err := r.reader.ReadRecord()
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return io.EOF
+	}
+	recordtime, err := adiftime.RecordTime(record)
+	if err != nil {
+		return err
+	}
+	r.current = recordWithTime{recordtime, record}
+	return nil
+}
+
+// Heap of the current records of the runs
+type runHeap struct {
+	runs    []*sortRun
+	reverse bool
+}
+
+func (h runHeap) Len() int { return len(h.runs) }
+
+func (h runHeap) Less(i, j int) bool {
+	a := h.runs[i]
+	b := h.runs[j]
+	if recordLess(a.current, b.current, h.reverse) {
+		return true
+	}
+	if recordLess(b.current, a.current, h.reverse) {
+		return false
+	}
+	// Same time: earlier run first
+	return a.index < b.index
+}
+
+func (h runHeap) Swap(i, j int) { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+
+func (h *runHeap) Push(x any) { h.runs = append(h.runs, x.(*sortRun)) }
+
+func (h *runHeap) Pop() any {
+	n := len(h.runs)
+	run := h.runs[n-1]
+	h.runs = h.runs[:n-1]
+	return run
+}
+
+// Sorter of records with a memory budget
+type recordSorter struct {
+	// Memory budget in bytes (0 for no limit)
+	budget  int64
+	size    int64
+	reverse bool
+	records []recordWithTime
+	// Temporary file names of the runs in the input order
+	runs []string
+	// Maximum number of runs merged at a time
+	fanin int
+}
+
+func newRecordSorter(budget int64, reverse bool) *recordSorter {
+	return &recordSorter{budget: budget, reverse: reverse, fanin: mergeFanIn}
+}
+
+// Add a record, and spill the records to a run if over the budget
+func (s *recordSorter) add(r recordWithTime) error {
+	s.records = append(s.records, r)
+	if s.budget == 0 {
+		return nil
+	}
+	s.size += int64(len(r.record.ToString())) + recordOverhead
+	if s.size >= s.budget {
+		return s.spill()
+	}
+	return nil
+}
+
+// Sort the records in memory
+func (s *recordSorter) sortRecords() {
+	sort.SliceStable(s.records,
+		func(i, j int) bool {
+			return recordLess(s.records[i], s.records[j], s.reverse)
+		})
+}
+
+// Sort the records in memory and write them to a temporary file as a run
+func (s *recordSorter) spill() error {
+	if len(s.records) == 0 {
+		return nil
+	}
+	s.sortRecords()
+	fp, err := s.createRun()
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	writer := adifparser.NewADIFWriter(fp)
+	for i := range s.records {
+		err = writer.WriteRecord(s.records[i].record)
+		if err != nil {
+			return err
+		}
+	}
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+	s.records = nil
+	s.size = 0
+	return nil
+}
+
+// Create a temporary file for a new run
+func (s *recordSorter) createRun() (*os.File, error) {
+	fp, err := os.CreateTemp("", "goadiftime-run-*.adi")
+	if err != nil {
+		return nil, err
+	}
+	s.runs = append(s.runs, fp.Name())
+	return fp, nil
+}
+
+// Merge the runs in the order of the run names
+// and write the records with the write function
+func (s *recordSorter) mergeRuns(names []string,
+	write func(adifparser.ADIFRecord) error) error {
+	h := &runHeap{reverse: s.reverse}
+	defer func() {
+		for _, run := range h.runs {
+			run.fp.Close()
+		}
+	}()
+	for i, name := range names {
+		fp, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		run := &sortRun{fp: fp, reader: adifparser.NewADIFReader(fp), index: i}
+		err = run.next()
+		if err == io.EOF {
+			fp.Close()
+			continue
+		} else if err != nil {
+			fp.Close()
+			return err
+		}
+		h.runs = append(h.runs, run)
+	}
+	heap.Init(h)
+	for h.Len() > 0 {
+		run := h.runs[0]
+		if err := write(run.current.record); err != nil {
+			return err
+		}
+		err := run.next()
+		if err == io.EOF {
+			heap.Pop(h)
+			run.fp.Close()
+		} else if err != nil {
+			return err
+		} else {
+			heap.Fix(h, 0)
+		}
+	}
+	return nil
+}
+
+// Merge the group of the runs into a new run
+func (s *recordSorter) mergeGroup(group []string) error {
+	fp, err := s.createRun()
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	writer := adifparser.NewADIFWriter(fp)
+	err = s.mergeRuns(group, writer.WriteRecord)
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// Merge groups of the runs into longer runs
+// until the number of the runs is within the fan-in
+// The merged runs keep the order of the groups for stable sorting
+func (s *recordSorter) reduceRuns() error {
+	for len(s.runs) > s.fanin {
+		runs := s.runs
+		s.runs = nil
+		for i := 0; i < len(runs); i += s.fanin {
+			end := i + s.fanin
+			if end > len(runs) {
+				end = len(runs)
+			}
+			if err := s.mergeGroup(runs[i:end]); err != nil {
+				// Keep the remaining runs for cleanup
+				s.runs = append(s.runs, runs[i:]...)
+				return err
+			}
+			for _, name := range runs[i:end] {
+				os.Remove(name)
+			}
+		}
+	}
+	return nil
+}
+
+// Write all the records in the sorted order
+// Errors of writing records are reported to stderr
+func (s *recordSorter) output(writer adifparser.ADIFWriter) error {
+	if len(s.runs) == 0 {
+		// All records are in memory
+		s.sortRecords()
+		for i := range s.records {
+			if err := writer.WriteRecord(s.records[i].record); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+		return nil
+	}
+
+	err := s.spill()
+	if err != nil {
+		return err
+	}
+	err = s.reduceRuns()
+	if err != nil {
+		return err
+	}
+	return s.mergeRuns(s.runs, func(record adifparser.ADIFRecord) error {
+		if err := writer.WriteRecord(record); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return nil
+	})
+}
+
+// Remove the temporary files of the runs
+func (s *recordSorter) cleanup() {
+	for _, name := range s.runs {
+		os.Remove(name)
+	}
+	s.runs = nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adiftime"
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+// Generate records with many duplicate times
+func sortTestRecords(t *testing.T, n int) []recordWithTime {
+	t.Helper()
+	rng := rand.New(rand.NewSource(1))
+	var input strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&input, "<call:6>A%05d<qso_date:8>202311%02d<time_on:4>%02d%02d<eor>\n",
+			i, 20+rng.Intn(3), rng.Intn(3), rng.Intn(2)*30)
+	}
+	records := []recordWithTime{}
+	for _, record := range testutil.ReadRecords(t, input.String()) {
+		recordtime, err := adiftime.RecordTime(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, recordWithTime{recordtime, record})
+	}
+	return records
+}
+
+// Sort the records and return the output
+func sortOutput(t *testing.T, records []recordWithTime,
+	budget int64, fanin int, reverse bool) (string, int) {
+	t.Helper()
+	sorter := newRecordSorter(budget, reverse)
+	sorter.fanin = fanin
+	defer sorter.cleanup()
+	for _, r := range records {
+		if err := sorter.add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runs := len(sorter.runs)
+	var output bytes.Buffer
+	writer := adifparser.NewADIFWriter(&output)
+	if err := sorter.output(writer); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return output.String(), runs
+}
+
+func TestExternalSort(t *testing.T) {
+	tmpdir := t.TempDir()
+	t.Setenv("TMPDIR", tmpdir)
+	records := sortTestRecords(t, 500)
+
+	for _, reverse := range []bool{false, true} {
+		want, _ := sortOutput(t, records, 0, mergeFanIn, reverse)
+		if n := len(testutil.ReadRecords(t, want)); n != len(records) {
+			t.Fatalf("in-memory sort: %d records, want %d", n, len(records))
+		}
+		tests := []struct {
+			budget int64
+			fanin  int
+		}{
+			// One record per run with multiple merge passes
+			{1, 2},
+			{1, 3},
+			{1, mergeFanIn},
+			// Several records per run
+			{4096, 2},
+			{4096, mergeFanIn},
+			// All records in memory
+			{1 << 30, 2},
+		}
+		for _, tt := range tests {
+			got, runs := sortOutput(t, records, tt.budget, tt.fanin, reverse)
+			if got != want {
+				t.Errorf("budget %d, fan-in %d, reverse %v (%d runs): "+
+					"output differs from in-memory sort",
+					tt.budget, tt.fanin, reverse, runs)
+			}
+		}
+	}
+
+	// Temporary files are removed
+	entries, err := os.ReadDir(tmpdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("temporary files left: %v", entries)
+	}
+}
+
+func TestReduceRuns(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	sorter := newRecordSorter(1, false)
+	sorter.fanin = 3
+	defer sorter.cleanup()
+	for _, r := range sortTestRecords(t, 10) {
+		if err := sorter.add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(sorter.runs) != 10 {
+		t.Fatalf("%d runs, want 10", len(sorter.runs))
+	}
+	// 10 runs -> 4 runs -> 2 runs
+	if err := sorter.reduceRuns(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sorter.runs) != 2 {
+		t.Errorf("%d runs after reduceRuns, want 2", len(sorter.runs))
+	}
+}
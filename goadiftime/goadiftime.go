@@ -1,10 +1,41 @@
 // goadiftime: sort and filter ADIF file by time
 // by Kenji Rikitake, JJ1BDX
 // Usage: goadiftime [-f infile] [-o outfile] [-r]
-//        [-starttime RFC3339-time] [-endtime RFC3339-time]
+//        [-starttime time-expr] [-endtime time-expr] [-period time-expr]
+//        [-tz zone] [-logtz zone] [-shift offset [-dryrun]] [-mem MiB]
+// -nh: do not pass through the input ADIF header
 // RFC3339-time example: 2022-10-11T12:33:45Z
 // Time of ADIF record determined by: qso_date and time_on
 //
+// Time expressions (see adiftime package for the details):
+//   RFC3339 time, date only (2023-11-25),
+//   today, yesterday, this/last week, this/last month, this/last year,
+//   last N[hdw] (e.g., last 7d), and
+//   named contest weekends with optional year (e.g., cqww-cw 2023)
+// -starttime uses the start of the time expression
+// -endtime uses the end of the time expression
+//   (e.g., -endtime 2023-11-26 means 2023-11-26T23:59:59Z)
+// -period sets both starttime and endtime from the time expression
+//
+// Time zones (IANA time zone names, e.g., Asia/Tokyo):
+// -tz: time zone of the dates in time expressions (default: UTC)
+//   (e.g., -tz Asia/Tokyo -period 2023-11-25 means
+//    from 2023-11-24T15:00:00Z to 2023-11-25T14:59:59Z)
+// -logtz: time zone of qso_date, time_on, qso_date_off, and time_off
+//   in the input records (default: UTC)
+//   These fields are rewritten to UTC in the output records
+//   before time filtering
+//
+// Clock-offset correction:
+// -shift: shift qso_date, time_on, qso_date_off, and time_off
+//   of the records in the time window by the offset
+//   (Go duration, e.g., 2m13s, -1h30m)
+//   All records are output, and the records outside the window
+//   are not changed
+// -dryrun: with -shift, list the affected records as
+//   "record number:call:time before:time after"
+//   instead of ADIF records
+//
 // Time filtering conditions:
 // if starttime and endtime both are specified:
 // the condition is: starttime <= record time <= endtime
@@ -21,18 +52,25 @@
 //   the output is sorted by time increasing order
 //   when with -r option or -r=true:
 //   the output is sorted by time decreasing order
+// Sorting is stable: records with the same time keep the input order
+// -mem: memory budget for sorting in MiB (0 for no limit, default)
+//   Sorted runs over the budget are written to temporary files
+//   and merged (see extsort.go)
+//   The output is identical to the one without the budget
 
 package main
 
 import (
+	"bufio"
 	"errors"
 	"flag"
 	"fmt"
 	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adifio"
+	"github.com/jj1bdx/goadiftools/adiftime"
 	"io"
 	"os"
-	"sort"
-	"strconv"
+	"strings"
 	"time"
 )
 
@@ -44,12 +82,19 @@ type recordWithTime struct {
 func main() {
 	var infile = flag.String("f", "", "input file (stdout in none)")
 	var outfile = flag.String("o", "", "output file (stdout if none)")
+	var noheader = flag.Bool("nh", false, "do not pass through the input header")
 	var reverse bool
 	flag.BoolVar(&reverse, "r", false, "reverse sort (new to old)")
 	var nosorting bool
 	flag.BoolVar(&nosorting, "n", false, "no sorting with this flag")
-	var starttime = flag.String("starttime", "", "start time in RFC3339")
-	var endtime = flag.String("endtime", "", "end time in RFC3339")
+	var starttime = flag.String("starttime", "", "start time expression")
+	var endtime = flag.String("endtime", "", "end time expression")
+	var period = flag.String("period", "", "time expression for both start and end time")
+	var tz = flag.String("tz", "", "time zone of time expressions (UTC if none)")
+	var logtz = flag.String("logtz", "", "time zone of input records (UTC if none)")
+	var shift = flag.String("shift", "", "clock offset to shift the records in the time window")
+	var dryrun = flag.Bool("dryrun", false, "list the records to be shifted only")
+	var membudget = flag.Int64("mem", 0, "memory budget for sorting in MiB (0 for no limit)")
 
 	var fp *os.File
 	var err error
@@ -60,13 +105,44 @@ func main() {
 			"goadiftime: sort and filter ADIF file by time")
 		fmt.Fprintf(flag.CommandLine.Output(),
 			"Usage: %s  [-f infile] [-o outfile] [-r] "+
-				"[-starttime RFC3339-time] [-endtime RFC3339-time]\n",
+				"[-starttime time-expr] [-endtime time-expr] [-period time-expr] "+
+				"[-tz zone] [-logtz zone] [-shift offset [-dryrun]] [-mem MiB]\n",
 			execname)
 		flag.PrintDefaults()
 		details :=
 			"RFC3339-time example: 2022-10-11T12:33:45Z\n" +
 				"Time of ADIF record determined by: qso_date and time_on\n" +
 				"\n" +
+				"Time expressions:\n" +
+				"  RFC3339 time, date only (2023-11-25),\n" +
+				"  today, yesterday, this/last week, this/last month, this/last year,\n" +
+				"  last N[hdw] (e.g., last 7d), and\n" +
+				"  named contest weekends with optional year (e.g., cqww-cw 2023):\n" +
+				"  " + strings.Join(adiftime.ContestNames(), ", ") + "\n" +
+				"-starttime uses the start of the time expression\n" +
+				"-endtime uses the end of the time expression\n" +
+				"  (e.g., -endtime 2023-11-26 means 2023-11-26T23:59:59Z)\n" +
+				"-period sets both starttime and endtime from the time expression\n" +
+				"\n" +
+				"Time zones (IANA time zone names, e.g., Asia/Tokyo):\n" +
+				"-tz: time zone of the dates in time expressions (default: UTC)\n" +
+				"  (e.g., -tz Asia/Tokyo -period 2023-11-25 means\n" +
+				"   from 2023-11-24T15:00:00Z to 2023-11-25T14:59:59Z)\n" +
+				"-logtz: time zone of qso_date, time_on, qso_date_off, and time_off\n" +
+				"  in the input records (default: UTC)\n" +
+				"  These fields are rewritten to UTC in the output records\n" +
+				"  before time filtering\n" +
+				"\n" +
+				"Clock-offset correction:\n" +
+				"-shift: shift qso_date, time_on, qso_date_off, and time_off\n" +
+				"  of the records in the time window by the offset\n" +
+				"  (Go duration, e.g., 2m13s, -1h30m)\n" +
+				"  All records are output, and the records outside the window\n" +
+				"  are not changed\n" +
+				"-dryrun: with -shift, list the affected records as\n" +
+				"  \"record number:call:time before:time after\"\n" +
+				"  instead of ADIF records\n" +
+				"\n" +
 				"Time filtering conditions:\n" +
 				"if starttime and endtime both are specified:\n" +
 				"the condition is: starttime <= record time <= endtime\n" +
@@ -82,12 +158,15 @@ func main() {
 				"  when without -r option or -r=false (default):\n" +
 				"  the output is sorted by time increasing order\n" +
 				"  when with -r option or -r=true:\n" +
-				"  the output is sorted by time decreasing order\n"
+				"  the output is sorted by time decreasing order\n" +
+				"Sorting is stable: records with the same time keep the input order\n" +
+				"-mem: memory budget for sorting in MiB (0 for no limit, default)\n" +
+				"  Sorted runs over the budget are written to temporary files\n" +
+				"  and merged\n" +
+				"  The output is identical to the one without the budget\n"
 		fmt.Fprint(flag.CommandLine.Output(), details)
 	}
 
-	records := []recordWithTime{}
-
 	flag.Parse()
 
 	if *infile == "" {
@@ -100,7 +179,17 @@ func main() {
 		}
 	}
 
+	header, input, err := adifio.ReadHeader(fp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if *noheader {
+		header = nil
+	}
+
 	var writer adifparser.ADIFWriter
+	var textwriter *bufio.Writer
 	var writefp *os.File
 	if *outfile != "" {
 		if _, err := os.Stat(*outfile); os.IsNotExist(err) {
@@ -114,32 +203,68 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error: file %s already exists\n", *outfile)
 			return
 		}
-		writer = adifparser.NewADIFWriter(writefp)
+		writer = adifio.NewWriter(writefp, header, "goadiftime")
+		textwriter = bufio.NewWriter(writefp)
 	} else {
 		writefp = nil
-		writer = adifparser.NewADIFWriter(os.Stdout)
+		writer = adifio.NewWriter(os.Stdout, header, "goadiftime")
+		textwriter = bufio.NewWriter(os.Stdout)
+	}
+
+	var offset time.Duration
+	shiftmode := *shift != ""
+	if shiftmode {
+		offset, err = time.ParseDuration(*shift)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+			return
+		}
+	} else if *dryrun {
+		fmt.Fprint(os.Stderr, "Error: -dryrun requires -shift\n")
+		return
+	}
+
+	exprloc, err := loadLocation(*tz)
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		return
+	}
+	logloc, err := loadLocation(*logtz)
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		return
 	}
 
 	var startTime time.Time
 	var endTime time.Time
+	now := time.Now()
+	if *period != "" {
+		if *starttime != "" || *endtime != "" {
+			fmt.Fprint(os.Stderr,
+				"Error: -period cannot be used with -starttime or -endtime\n")
+			return
+		}
+		*starttime = *period
+		*endtime = *period
+	}
 	starttimeexists := *starttime != ""
 	if starttimeexists {
-		parsedStartTime, err := time.Parse(time.RFC3339, *starttime)
+		parsedStartTime, _, err := adiftime.ParseTimeExpr(*starttime, now, exprloc)
 		if err != nil {
 			fmt.Fprint(os.Stderr, err)
 			return
 		}
-		startTime = parsedStartTime.UTC()
+		startTime = parsedStartTime
 	}
 
 	endtimeexists := *endtime != ""
 	if endtimeexists {
-		parsedEndTime, err := time.Parse(time.RFC3339, *endtime)
+		_, parsedEndTime, err := adiftime.ParseTimeExpr(*endtime, now, exprloc)
 		if err != nil {
 			fmt.Fprint(os.Stderr, err)
 			return
 		}
-		endTime = parsedEndTime.UTC()
+		endTime = parsedEndTime
 	}
 	if starttimeexists && endtimeexists &&
 		startTime.After(endTime) {
@@ -147,12 +272,34 @@ func main() {
 		return
 	}
 
-	if writer.SetComment("goadiftime\n") != nil {
+	if err := writer.SetComment("goadiftime\n"); err != nil {
 		fmt.Fprint(os.Stderr, err)
 		return
 	}
 
-	reader := adifparser.NewADIFReader(fp)
+	if *membudget < 0 {
+		fmt.Fprint(os.Stderr, "Error: negative memory budget\n")
+		return
+	}
+	sorter := newRecordSorter(*membudget*1024*1024, reverse)
+	defer sorter.cleanup()
+	// Add a record to the output
+	// Unsorted records are written immediately
+	addRecord := func(r recordWithTime) error {
+		if *dryrun {
+			return nil
+		}
+		if nosorting {
+			if err := writer.WriteRecord(r.record); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			return nil
+		}
+		return sorter.add(r)
+	}
+
+	recordnumber := 0
+	reader := adifparser.NewADIFReader(input)
 	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
 		if err != nil {
 			if err != io.EOF {
@@ -160,78 +307,73 @@ func main() {
 			}
 			break // when io.EOF break the loop!
 		}
+		recordnumber++
 
-		adifdate, err := record.GetValue("qso_date")
-		if err != nil {
-			fmt.Fprint(os.Stderr, err)
-			return
-		}
-		adiftime, err := record.GetValue("time_on")
-		if err != nil {
-			fmt.Fprint(os.Stderr, err)
-			return
+		if *logtz != "" {
+			err = rewriteRecordToUTC(record, logloc)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				return
+			}
 		}
 
-		adifyear, err := strconv.Atoi(adifdate[0:4])
-		if err != nil {
-			fmt.Fprint(os.Stderr, err)
-			return
-		}
-		adifmonth, err := strconv.Atoi(adifdate[4:6])
-		if err != nil {
-			fmt.Fprint(os.Stderr, err)
-			return
-		}
-		adifday, err := strconv.Atoi(adifdate[6:8])
-		if err != nil {
-			fmt.Fprint(os.Stderr, err)
-			return
-		}
-		adifhour, err := strconv.Atoi(adiftime[0:2])
-		if err != nil {
-			fmt.Fprint(os.Stderr, err)
-			return
-		}
-		adifminute, err := strconv.Atoi(adiftime[2:4])
+		recordtime, err := adiftime.RecordTime(record)
 		if err != nil {
 			fmt.Fprint(os.Stderr, err)
 			return
 		}
-		adifsecond := 0
-		if len(adiftime) > 4 {
-			adifsecond, err = strconv.Atoi(adiftime[4:6])
-		}
-		recordtime := time.Date(
-			adifyear, time.Month(adifmonth), adifday,
-			adifhour, adifminute, adifsecond,
-			0, time.UTC)
 
 		passstart := !starttimeexists ||
 			(recordtime.After(startTime) || recordtime.Equal(startTime))
 		passend := !endtimeexists ||
 			(recordtime.Before(endTime) || recordtime.Equal(endTime))
-		if passstart && passend {
+		if shiftmode {
+			// Shift the records in the time window and keep all records
+			if passstart && passend {
+				shiftedtime := recordtime.Add(offset)
+				if *dryrun {
+					call, _ := record.GetValue("call")
+					fmt.Fprintf(textwriter, "%d:%s:%s:%s\n", recordnumber, call,
+						recordtime.Format(time.RFC3339),
+						shiftedtime.Format(time.RFC3339))
+					continue
+				}
+				err = rewriteRecordTime(record, time.UTC, offset)
+				if err != nil {
+					fmt.Fprint(os.Stderr, err)
+					return
+				}
+				recordtime = shiftedtime
+			}
+			err = addRecord(recordWithTime{recordtime, record})
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				return
+			}
+		} else if passstart && passend {
 			recordandtime := recordWithTime{recordtime, record}
-			records = append(records, recordandtime)
+			err = addRecord(recordandtime)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err)
+				return
+			}
 		}
 	}
 
-	if !nosorting {
-		if reverse {
-			sort.Slice(records,
-				func(i, j int) bool {
-					return records[i].date.After(records[j].date)
-				})
-		} else {
-			sort.Slice(records,
-				func(i, j int) bool {
-					return records[i].date.Before(records[j].date)
-				})
+	if *dryrun {
+		textwriter.Flush()
+		if writefp != os.Stdout {
+			writefp.Close()
 		}
+		return
 	}
 
-	for i := range records {
-		writer.WriteRecord(records[i].record)
+	if !nosorting {
+		err = sorter.output(writer)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err)
+			return
+		}
 	}
 
 	// Flush and close output here
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+const testMainEnv = "GOADIFTIME_TEST_MAIN"
+
+// Run main() in a child process of the test binary
+func TestMain(m *testing.M) {
+	testutil.Main(m, testMainEnv, main)
+}
+
+// Run goadiftime with the input and the arguments, and return stdout
+func runTime(t *testing.T, input string, args ...string) string {
+	t.Helper()
+	stdout, stderr := testutil.Run(t, testMainEnv, input, args...)
+	if stderr != "" {
+		t.Errorf("goadiftime %v: stderr: %s", args, stderr)
+	}
+	return stdout
+}
+
+const testLog = "<call:4>A1AA<qso_date:8>20231126<time_on:4>0010<eor>\n" +
+	"<call:4>A1AB<qso_date:8>20231124<time_on:4>2359<eor>\n" +
+	"<call:4>A1AC<qso_date:8>20231125<time_on:6>000000<eor>\n" +
+	"<call:4>A1AD<qso_date:8>20231126<time_on:6>235959<eor>\n" +
+	"<call:4>A1AE<qso_date:8>20231127<time_on:4>0000<eor>\n" +
+	"<call:4>A1AF<qso_date:8>20231125<time_on:4>0000<eor>\n"
+
+func TestTimeExpressions(t *testing.T) {
+	tests := []struct {
+		args  []string
+		calls []string
+	}{
+		{[]string{}, []string{"A1AB", "A1AC", "A1AF", "A1AA", "A1AD", "A1AE"}},
+		{[]string{"-r"}, []string{"A1AE", "A1AD", "A1AA", "A1AC", "A1AF", "A1AB"}},
+		{[]string{"-n", "-starttime", "2023-11-26"}, []string{"A1AA", "A1AD", "A1AE"}},
+		{[]string{"-endtime", "2023-11-25"}, []string{"A1AB", "A1AC", "A1AF"}},
+		{[]string{"-starttime", "2023-11-25", "-endtime", "2023-11-26"},
+			[]string{"A1AC", "A1AF", "A1AA", "A1AD"}},
+		{[]string{"-period", "cqww-cw 2023"}, []string{"A1AC", "A1AF", "A1AA", "A1AD"}},
+		{[]string{"-period", "2023-11-26"}, []string{"A1AA", "A1AD"}},
+		{[]string{"-starttime", "2023-11-26T00:10:00Z", "-endtime", "2023-11-27T00:00:00Z"},
+			[]string{"A1AA", "A1AD", "A1AE"}},
+		{[]string{"-period", "2023-11-28"}, []string{}},
+	}
+	for _, tt := range tests {
+		got := testutil.FieldValues(testutil.ReadRecords(t, runTime(t, testLog, tt.args...)), "call")
+		if !reflect.DeepEqual(got, tt.calls) {
+			t.Errorf("goadiftime %v = %v, want %v", tt.args, got, tt.calls)
+		}
+	}
+}
+
+func TestTimeExpressionErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"-starttime", "next week"},
+		{"-period", "today", "-starttime", "today"},
+		{"-starttime", "2023-11-27", "-endtime", "2023-11-26"},
+	} {
+		stdout, stderr := testutil.Run(t, testMainEnv, testLog, args...)
+		if stderr == "" || len(testutil.ReadRecords(t, stdout)) != 0 {
+			t.Errorf("goadiftime %v: stderr %q, stdout %q", args, stderr, stdout)
+		}
+	}
+}
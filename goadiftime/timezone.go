@@ -0,0 +1,93 @@
+// goadiftime: time zone handling for logs recorded in local time
+// by Kenji Rikitake, JJ1BDX
+//
+// Some logging software records QSO_DATE, TIME_ON, and TIME_OFF
+// in the local time (e.g., JST) instead of UTC.
+// The fields are converted from the named IANA time zone
+// (e.g., Asia/Tokyo) to UTC, including the date rollover.
+// QSO_DATE_OFF is also converted if it exists,
+// or set if the UTC date of TIME_OFF differs from QSO_DATE.
+// The same rewriting is used for shifting the time by a clock offset.
+
+package main
+
+import (
+	"time"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/adiftime"
+)
+
+// Load the time location of the IANA time zone name
+// Empty name is treated as UTC
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// Format the time as ADIF time
+// withSeconds chooses HHMMSS instead of HHMM
+func formatADIFTime(t time.Time, withSeconds bool) string {
+	if withSeconds {
+		return t.Format("150405")
+	}
+	return t.Format("1504")
+}
+
+// Rewrite QSO_DATE, TIME_ON, QSO_DATE_OFF, and TIME_OFF
+// of the record from the location to UTC
+func rewriteRecordToUTC(record adifparser.ADIFRecord, loc *time.Location) error {
+	return rewriteRecordTime(record, loc, 0)
+}
+
+// Rewrite QSO_DATE, TIME_ON, QSO_DATE_OFF, and TIME_OFF
+// of the record from the location to UTC, shifted by the offset
+// Times are written in HHMMSS if the offset has seconds
+func rewriteRecordTime(record adifparser.ADIFRecord, loc *time.Location, offset time.Duration) error {
+	withSeconds := offset%time.Minute != 0
+	adifdate, err := record.GetValue("qso_date")
+	if err != nil {
+		return err
+	}
+	adiftimeon, err := record.GetValue("time_on")
+	if err != nil {
+		return err
+	}
+	timeon, err := adiftime.ParseDateTime(adifdate, adiftimeon, loc)
+	if err != nil {
+		return err
+	}
+	timeonutc := timeon.Add(offset).UTC()
+	record.SetValue("qso_date", timeonutc.Format("20060102"))
+	record.SetValue("time_on",
+		formatADIFTime(timeonutc, withSeconds || len(adiftimeon) == 6))
+
+	adiftimeoff, err := record.GetValue("time_off")
+	if err != nil || adiftimeoff == "" {
+		// No TIME_OFF: nothing more to rewrite
+		return nil
+	}
+	adifdateoff, err := record.GetValue("qso_date_off")
+	dateoffexists := err == nil && adifdateoff != ""
+	if !dateoffexists {
+		adifdateoff = adifdate
+	}
+	timeoff, err := adiftime.ParseDateTime(adifdateoff, adiftimeoff, loc)
+	if err != nil {
+		return err
+	}
+	// TIME_OFF before TIME_ON without QSO_DATE_OFF means the next day
+	if !dateoffexists && timeoff.Before(timeon) {
+		timeoff = timeoff.AddDate(0, 0, 1)
+	}
+	timeoffutc := timeoff.Add(offset).UTC()
+	record.SetValue("time_off",
+		formatADIFTime(timeoffutc, withSeconds || len(adiftimeoff) == 6))
+	dateoffutc := timeoffutc.Format("20060102")
+	if dateoffexists || dateoffutc != timeonutc.Format("20060102") {
+		record.SetValue("qso_date_off", dateoffutc)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jj1bdx/adifparser"
+	"github.com/jj1bdx/goadiftools/internal/testutil"
+)
+
+// Obtain qso_date, time_on, qso_date_off, and time_off of the record
+func recordTimeFields(record adifparser.ADIFRecord) []string {
+	values := []string{}
+	for _, field := range []string{"qso_date", "time_on", "qso_date_off", "time_off"} {
+		value, _ := record.GetValue(field)
+		values = append(values, value)
+	}
+	return values
+}
+
+func loadTokyo(t *testing.T) *time.Location {
+	t.Helper()
+	loc, err := loadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip(err)
+	}
+	return loc
+}
+
+func TestLoadLocation(t *testing.T) {
+	if loc, err := loadLocation(""); loc != time.UTC || err != nil {
+		t.Errorf("loadLocation(\"\") = %v, %v", loc, err)
+	}
+	if _, err := loadLocation("Nowhere/Nothing"); err == nil {
+		t.Errorf("loadLocation(invalid): no error")
+	}
+}
+
+func TestRewriteRecordToUTC(t *testing.T) {
+	tokyo := loadTokyo(t)
+	tests := []struct {
+		record string
+		want   []string
+	}{
+		// Date rollover to the previous day
+		{"<qso_date:8>20231125<time_on:4>0830<eor>",
+			[]string{"20231124", "2330", "", ""}},
+		{"<qso_date:8>20231125<time_on:6>093015<eor>",
+			[]string{"20231125", "003015", "", ""}},
+		// Year rollover
+		{"<qso_date:8>20240101<time_on:4>0000<time_off:4>0010<eor>",
+			[]string{"20231231", "1500", "", "1510"}},
+		// TIME_OFF crosses the UTC date without QSO_DATE_OFF
+		{"<qso_date:8>20231125<time_on:4>0850<time_off:4>0910<eor>",
+			[]string{"20231124", "2350", "20231125", "0010"}},
+		// TIME_OFF before TIME_ON without QSO_DATE_OFF is the next day
+		{"<qso_date:8>20231125<time_on:4>2350<time_off:4>0010<eor>",
+			[]string{"20231125", "1450", "", "1510"}},
+		// QSO_DATE_OFF is always rewritten if it exists
+		{"<qso_date:8>20231125<time_on:4>1000<qso_date_off:8>20231125<time_off:4>1100<eor>",
+			[]string{"20231125", "0100", "20231125", "0200"}},
+		{"<qso_date:8>20231125<time_on:4>2350<qso_date_off:8>20231126<time_off:4>0850<eor>",
+			[]string{"20231125", "1450", "20231125", "2350"}},
+	}
+	for _, tt := range tests {
+		record := testutil.ParseRecord(t, tt.record)
+		if err := rewriteRecordToUTC(record, tokyo); err != nil {
+			t.Errorf("rewriteRecordToUTC(%s): %v", tt.record, err)
+			continue
+		}
+		if got := recordTimeFields(record); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("rewriteRecordToUTC(%s) = %v, want %v", tt.record, got, tt.want)
+		}
+	}
+
+	for _, s := range []string{"<call:4>A1AA<eor>",
+		"<qso_date:8>20231125<eor>",
+		"<qso_date:8>20231325<time_on:4>0830<eor>",
+		"<qso_date:8>20231125<time_on:4>0830<time_off:2>xx<eor>"} {
+		if err := rewriteRecordToUTC(testutil.ParseRecord(t, s), tokyo); err == nil {
+			t.Errorf("rewriteRecordToUTC(%s): no error", s)
+		}
+	}
+}
+
+func TestLocalTimeOptions(t *testing.T) {
+	loadTokyo(t)
+	input := "<call:4>A1AA<qso_date:8>20231125<time_on:4>0830<eor>\n" +
+		"<call:4>A1AB<qso_date:8>20231125<time_on:4>0930<eor>\n" +
+		"<call:4>A1AC<qso_date:8>20231126<time_on:4>2330<eor>\n"
+	tests := []struct {
+		args  []string
+		calls []string
+		dates []string
+	}{
+		// -logtz rewrites the records before filtering
+		{[]string{"-logtz", "Asia/Tokyo", "-period", "2023-11-25"},
+			[]string{"A1AB"}, []string{"20231125"}},
+		{[]string{"-logtz", "Asia/Tokyo"}, []string{"A1AA", "A1AB", "A1AC"},
+			[]string{"20231124", "20231125", "20231126"}},
+		// -tz applies to the time expressions only
+		{[]string{"-tz", "Asia/Tokyo", "-period", "2023-11-25"},
+			[]string{"A1AA", "A1AB"}, []string{"20231125", "20231125"}},
+		{[]string{"-tz", "Asia/Tokyo", "-period", "2023-11-27"},
+			[]string{"A1AC"}, []string{"20231126"}},
+		{[]string{"-tz", "Asia/Tokyo", "-logtz", "Asia/Tokyo", "-period", "2023-11-25"},
+			[]string{"A1AA", "A1AB"}, []string{"20231124", "20231125"}},
+	}
+	for _, tt := range tests {
+		records := testutil.ReadRecords(t, runTime(t, input, tt.args...))
+		calls := testutil.FieldValues(records, "call")
+		dates := testutil.FieldValues(records, "qso_date")
+		if !reflect.DeepEqual(calls, tt.calls) || !reflect.DeepEqual(dates, tt.dates) {
+			t.Errorf("goadiftime %v = %v %v, want %v %v",
+				tt.args, calls, dates, tt.calls, tt.dates)
+		}
+	}
+}
+
+func TestRewriteRecordTime(t *testing.T) {
+	tests := []struct {
+		record string
+		offset time.Duration
+		want   []string
+	}{
+		// Minute offsets keep HHMM
+		{"<qso_date:8>20231125<time_on:4>2359<eor>", 2 * time.Minute,
+			[]string{"20231126", "0001", "", ""}},
+		// Second offsets write HHMMSS
+		{"<qso_date:8>20231125<time_on:4>1200<time_off:4>1205<eor>", 2*time.Minute + 13*time.Second,
+			[]string{"20231125", "120213", "", "120713"}},
+		{"<qso_date:8>20231125<time_on:6>000010<eor>", -time.Minute,
+			[]string{"20231124", "235910", "", ""}},
+		// QSO_DATE_OFF is set when TIME_OFF moves to another date
+		{"<qso_date:8>20231125<time_on:4>2350<time_off:4>2358<eor>", 5 * time.Minute,
+			[]string{"20231125", "2355", "20231126", "0003"}},
+		{"<qso_date:8>20231125<time_on:4>2350<qso_date_off:8>20231126<time_off:4>0010<eor>",
+			-time.Hour, []string{"20231125", "2250", "20231125", "2310"}},
+	}
+	for _, tt := range tests {
+		record := testutil.ParseRecord(t, tt.record)
+		if err := rewriteRecordTime(record, time.UTC, tt.offset); err != nil {
+			t.Errorf("rewriteRecordTime(%s, %v): %v", tt.record, tt.offset, err)
+			continue
+		}
+		if got := recordTimeFields(record); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("rewriteRecordTime(%s, %v) = %v, want %v",
+				tt.record, tt.offset, got, tt.want)
+		}
+	}
+}
+
+func TestShift(t *testing.T) {
+	input := "<call:4>A1AA<qso_date:8>20231125<time_on:4>1000<eor>\n" +
+		"<call:4>A1AB<qso_date:8>20231125<time_on:4>1200<eor>\n" +
+		"<call:4>A1AC<qso_date:8>20231125<time_on:4>1400<eor>\n"
+
+	// Only the records in the window are shifted, and all records are output
+	records := testutil.ReadRecords(t, runTime(t, input, "-shift", "2m13s",
+		"-starttime", "2023-11-25T11:00:00Z", "-endtime", "2023-11-25T13:00:00Z"))
+	if got := testutil.FieldValues(records, "time_on"); !reflect.DeepEqual(got,
+		[]string{"1000", "120213", "1400"}) {
+		t.Errorf("shift: time_on = %v", got)
+	}
+
+	// Shifted records are sorted again
+	records = testutil.ReadRecords(t, runTime(t, input, "-shift", "-3h",
+		"-starttime", "2023-11-25T13:00:00Z"))
+	if got := testutil.FieldValues(records, "call"); !reflect.DeepEqual(got,
+		[]string{"A1AA", "A1AC", "A1AB"}) {
+		t.Errorf("shift and sort: call = %v", got)
+	}
+
+	// Dry run lists the affected records only
+	output := runTime(t, input, "-shift", "-1m", "-dryrun", "-period", "2023-11-25")
+	want := "1:A1AA:2023-11-25T10:00:00Z:2023-11-25T09:59:00Z\n" +
+		"2:A1AB:2023-11-25T12:00:00Z:2023-11-25T11:59:00Z\n" +
+		"3:A1AC:2023-11-25T14:00:00Z:2023-11-25T13:59:00Z\n"
+	if output != want {
+		t.Errorf("dry run = %q, want %q", output, want)
+	}
+}
@@ -0,0 +1,115 @@
+// testutil: helpers for the tests of goadiftools
+// by Kenji Rikitake, JJ1BDX
+//
+// The command tests run main() in a child process of the test binary:
+//
+//	func TestMain(m *testing.M) {
+//		testutil.Main(m, testMainEnv, main)
+//	}
+//
+// and Run executes the test binary with testMainEnv set.
+
+package testutil
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jj1bdx/adifparser"
+)
+
+// Run main instead of the tests if the environment variable env is "1",
+// otherwise run the tests
+func Main(m *testing.M, env string, main func()) {
+	if os.Getenv(env) == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// Run main in a child process of the test binary
+// with the input as stdin and the arguments, and return stdout and stderr
+// The test fails if the child process fails
+func Run(t testing.TB, env string, input string, args ...string) (string, string) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), env+"=1")
+	cmd.Stdin = strings.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("%v: %v: %s", args, err, stderr.String())
+	}
+	return stdout.String(), stderr.String()
+}
+
+// Parse a single ADIF record
+func ParseRecord(t testing.TB, s string) adifparser.ADIFRecord {
+	t.Helper()
+	record, err := adifparser.NewADIFReader(strings.NewReader(s)).ReadRecord()
+	if err != nil {
+		t.Fatalf("parsing %q: %v", s, err)
+	}
+	return record
+}
+
+// Read all ADIF records from ADIF text
+func ReadRecords(t testing.TB, s string) []adifparser.ADIFRecord {
+	t.Helper()
+	records := []adifparser.ADIFRecord{}
+	reader := adifparser.NewADIFReader(strings.NewReader(s))
+	for {
+		record, err := reader.ReadRecord()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+}
+
+// Obtain the values of the field of the records,
+// or empty strings for the records without the field
+func FieldValues(records []adifparser.ADIFRecord, field string) []string {
+	values := []string{}
+	for _, record := range records {
+		value, _ := record.GetValue(field)
+		values = append(values, value)
+	}
+	return values
+}
+
+// Obtain the calls of the ADIF records in ADIF text
+func Calls(t testing.TB, s string) []string {
+	t.Helper()
+	return FieldValues(ReadRecords(t, s), "call")
+}
+
+// Obtain the field values of the record as a map with lowercase names
+func RecordValues(record adifparser.ADIFRecord) map[string]string {
+	values := make(map[string]string)
+	for _, field := range record.GetFields() {
+		value, _ := record.GetValue(field)
+		values[strings.ToLower(field)] = value
+	}
+	return values
+}
+
+// Obtain the sorted lowercase field names of the record
+func FieldNames(record adifparser.ADIFRecord) []string {
+	fields := []string{}
+	for _, field := range record.GetFields() {
+		fields = append(fields, strings.ToLower(field))
+	}
+	sort.Strings(fields)
+	return fields
+}